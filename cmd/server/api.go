@@ -6,13 +6,17 @@ import (
 	_ "embed"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"log"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/bsv-blockchain/go-chaintracks/pkg/chaintracks"
 	"github.com/bsv-blockchain/go-sdk/chainhash"
+	"github.com/bsv-blockchain/go-sdk/transaction"
 	"github.com/gofiber/fiber/v2"
 	"github.com/valyala/fasthttp"
 )
@@ -25,6 +29,8 @@ type Server struct {
 	cm           *chaintracks.ChainManager
 	sseClients   map[int64]*bufio.Writer
 	sseClientsMu sync.RWMutex
+	cdnStore     *chaintracks.FileStore // Header files served under /cdn (nil if disabled)
+	cdnRootURL   string                 // Public URL of /cdn, derived from requests if empty
 }
 
 // NewServer creates a new API server
@@ -35,18 +41,31 @@ func NewServer(cm *chaintracks.ChainManager) *Server {
 	}
 }
 
-// StartBroadcasting listens to ChainManager tip changes and broadcasts to all SSE clients
-func (s *Server) StartBroadcasting(ctx context.Context, tipChan <-chan *chaintracks.BlockHeader) {
+// sseEventBufferSize is the number of chain events queued for SSE broadcasting
+const sseEventBufferSize = 256
+
+// StartBroadcasting subscribes to ChainManager tip changes and reorgs and broadcasts them to all SSE clients
+func (s *Server) StartBroadcasting(ctx context.Context) {
 	go func() {
-		for {
-			select {
-			case <-ctx.Done():
-				return
-			case tip := <-tipChan:
-				if tip == nil {
-					continue
+		for ctx.Err() == nil {
+			events, unsubscribe := s.cm.Subscribe(ctx, chaintracks.SubscribeOptions{
+				Policy:     chaintracks.PolicyLossless,
+				BufferSize: sseEventBufferSize,
+			})
+
+			for event := range events {
+				switch event.Type {
+				case chaintracks.EventTip:
+					s.broadcastTip(event.Tip)
+				case chaintracks.EventReorg:
+					s.broadcastReorg(event.Reorg)
 				}
-				s.broadcastTip(tip)
+			}
+			unsubscribe()
+
+			// The subscription closes on overflow; resubscribe so SSE clients keep receiving updates
+			if ctx.Err() == nil {
+				log.Printf("SSE event subscription closed, resubscribing")
 			}
 		}
 	}()
@@ -59,8 +78,21 @@ func (s *Server) broadcastTip(tip *chaintracks.BlockHeader) {
 		return
 	}
 
-	sseMessage := fmt.Sprintf("data: %s\n\n", string(data))
+	s.broadcast(fmt.Sprintf("data: %s\n\n", string(data)))
+}
 
+// broadcastReorg sends a reorg event to all connected SSE clients
+func (s *Server) broadcastReorg(reorg *chaintracks.ReorgEvent) {
+	data, err := json.Marshal(reorg)
+	if err != nil {
+		return
+	}
+
+	s.broadcast(fmt.Sprintf("event: reorg\ndata: %s\n\n", string(data)))
+}
+
+// broadcast writes a raw SSE message to all connected SSE clients
+func (s *Server) broadcast(sseMessage string) {
 	s.sseClientsMu.RLock()
 	clientsCopy := make(map[int64]*bufio.Writer, len(s.sseClients))
 	for id, writer := range s.sseClients {
@@ -225,6 +257,15 @@ func (s *Server) HandleGetTipHeader(c *fiber.Ctx) error {
 	})
 }
 
+// HandleGetSyncStatus returns how fresh the chain tip is: when it last changed and how far it is behind
+func (s *Server) HandleGetSyncStatus(c *fiber.Ctx) error {
+	c.Set("Cache-Control", "no-cache")
+	return c.JSON(Response{
+		Status: "success",
+		Value:  s.cm.GetSyncStatus(),
+	})
+}
+
 // HandleGetHeaderByHeight returns a header by height
 func (s *Server) HandleGetHeaderByHeight(c *fiber.Ctx) error {
 	heightStr := c.Params("height")
@@ -307,7 +348,276 @@ func (s *Server) HandleGetHeaderByHash(c *fiber.Ctx) error {
 	})
 }
 
-// HandleGetHeaders returns multiple headers as concatenated hex
+// HandleGetHeaderByMerkleRoot returns the main chain header with a merkle root
+func (s *Server) HandleGetHeaderByMerkleRoot(c *fiber.Ctx) error {
+	root, err := chainhash.NewHashFromHex(c.Params("root"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(Response{
+			Status:      "error",
+			Code:        "ERR_INVALID_PARAMS",
+			Description: "Invalid merkle root parameter",
+		})
+	}
+
+	header, err := s.cm.GetHeaderByMerkleRoot(root)
+	if err != nil {
+		c.Set("Cache-Control", "no-cache")
+		return c.JSON(Response{
+			Status: "success",
+			Value:  nil,
+		})
+	}
+
+	tip := s.cm.GetHeight()
+	if header.Height < tip-100 {
+		c.Set("Cache-Control", "public, max-age=3600")
+	} else {
+		c.Set("Cache-Control", "no-cache")
+	}
+
+	return c.JSON(Response{
+		Status: "success",
+		Value:  header,
+	})
+}
+
+// HandleGetMedianTimePast returns the median time past for a height
+func (s *Server) HandleGetMedianTimePast(c *fiber.Ctx) error {
+	height, err := strconv.ParseUint(c.Params("height"), 10, 32)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(Response{
+			Status:      "error",
+			Code:        "ERR_INVALID_PARAMS",
+			Description: "Invalid height parameter",
+		})
+	}
+
+	tip := s.cm.GetHeight()
+	if uint32(height) < tip-100 {
+		c.Set("Cache-Control", "public, max-age=3600")
+	} else {
+		c.Set("Cache-Control", "no-cache")
+	}
+
+	mtp, err := s.cm.GetMedianTimePast(uint32(height))
+	if err != nil {
+		return c.JSON(Response{
+			Status: "success",
+			Value:  nil,
+		})
+	}
+
+	return c.JSON(Response{
+		Status: "success",
+		Value:  mtp,
+	})
+}
+
+// maxVerifyRoots is the most merkle roots accepted in one verify request
+const maxVerifyRoots = 1000
+
+// HandleVerifyMerkleRoots validates a JSON list of {height, merkleRoot} pairs against the main chain,
+// returning valid, invalid or unknown (height beyond the tip) for each in request order
+func (s *Server) HandleVerifyMerkleRoots(c *fiber.Ctx) error {
+	var checks []chaintracks.RootCheck
+	if err := json.Unmarshal(c.Body(), &checks); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(Response{
+			Status:      "error",
+			Code:        "ERR_INVALID_PARAMS",
+			Description: "Body must be a JSON array of {height, merkleRoot} objects",
+		})
+	}
+	if len(checks) > maxVerifyRoots {
+		return c.Status(fiber.StatusBadRequest).JSON(Response{
+			Status:      "error",
+			Code:        "ERR_INVALID_PARAMS",
+			Description: fmt.Sprintf("At most %d merkle roots per request", maxVerifyRoots),
+		})
+	}
+
+	results, err := s.cm.IsValidRootsForHeights(c.UserContext(), checks)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(Response{
+			Status:      "error",
+			Code:        "ERR_INTERNAL",
+			Description: err.Error(),
+		})
+	}
+
+	c.Set("Cache-Control", "no-cache")
+	return c.JSON(Response{
+		Status: "success",
+		Value:  results,
+	})
+}
+
+// HandleValidateRoot reports whether a merkle root at a height is on the main chain and how deeply confirmed,
+// matches a known fork header instead, or is beyond the tip
+func (s *Server) HandleValidateRoot(c *fiber.Ctx) error {
+	root, err := chainhash.NewHashFromHex(c.Params("root"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(Response{
+			Status:      "error",
+			Code:        "ERR_INVALID_PARAMS",
+			Description: "Invalid merkle root parameter",
+		})
+	}
+
+	height, err := strconv.ParseUint(c.Params("height"), 10, 32)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(Response{
+			Status:      "error",
+			Code:        "ERR_INVALID_PARAMS",
+			Description: "Invalid height parameter",
+		})
+	}
+
+	status, err := s.cm.ValidateRoot(c.UserContext(), root, uint32(height))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(Response{
+			Status:      "error",
+			Code:        "ERR_INTERNAL",
+			Description: err.Error(),
+		})
+	}
+
+	c.Set("Cache-Control", "no-cache")
+	return c.JSON(Response{
+		Status: "success",
+		Value:  status,
+	})
+}
+
+// verifyMerklePathRequest is the body of POST /v2/verify/merklepath
+type verifyMerklePathRequest struct {
+	MerklePath string `json:"merklePath"` // BUMP (BRC-74) hex
+	TxID       string `json:"txid"`
+}
+
+// HandleVerifyMerklePath computes the merkle root a BUMP proves for a txid and checks it against the main chain,
+// returning the confirmation depth of the transaction
+func (s *Server) HandleVerifyMerklePath(c *fiber.Ctx) error {
+	var req verifyMerklePathRequest
+	if err := json.Unmarshal(c.Body(), &req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(Response{
+			Status:      "error",
+			Code:        "ERR_INVALID_PARAMS",
+			Description: "Body must be a JSON object with merklePath and txid",
+		})
+	}
+
+	txid, err := chainhash.NewHashFromHex(req.TxID)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(Response{
+			Status:      "error",
+			Code:        "ERR_INVALID_PARAMS",
+			Description: "Invalid txid",
+		})
+	}
+	path, err := transaction.NewMerklePathFromHex(req.MerklePath)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(Response{
+			Status:      "error",
+			Code:        "ERR_INVALID_PROOF",
+			Description: fmt.Sprintf("Invalid merkle path: %v", err),
+		})
+	}
+
+	result, err := s.cm.VerifyMerklePath(c.UserContext(), path, txid)
+	if err != nil {
+		return proofError(c, err)
+	}
+
+	c.Set("Cache-Control", "no-cache")
+	return c.JSON(Response{
+		Status: "success",
+		Value:  result,
+	})
+}
+
+// beefVerification is the outcome of POST /v2/verify/beef
+type beefVerification struct {
+	Valid  bool                      `json:"valid"` // Every proof matches the main chain
+	Proofs []chaintracks.ProofResult `json:"proofs"`
+}
+
+// HandleVerifyBeef checks every merkle proof in a BEEF against the main chain, returning the confirmation depth
+// of each proven transaction. The BEEF is the raw body with Content-Type application/octet-stream, or hex in a
+// JSON object's beef field.
+func (s *Server) HandleVerifyBeef(c *fiber.Ctx) error {
+	beef := c.Body()
+	if !strings.HasPrefix(c.Get(fiber.HeaderContentType), fiber.MIMEOctetStream) {
+		var req struct {
+			Beef string `json:"beef"`
+		}
+		var err error
+		if err = json.Unmarshal(c.Body(), &req); err == nil {
+			beef, err = hex.DecodeString(req.Beef)
+		}
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(Response{
+				Status:      "error",
+				Code:        "ERR_INVALID_PARAMS",
+				Description: "Body must be raw BEEF bytes or a JSON object with beef hex",
+			})
+		}
+	}
+
+	proofs, err := s.cm.VerifyBeef(c.UserContext(), beef)
+	if err != nil {
+		return proofError(c, err)
+	}
+
+	valid := true
+	for _, proof := range proofs {
+		valid = valid && proof.Result == chaintracks.RootValid
+	}
+
+	c.Set("Cache-Control", "no-cache")
+	return c.JSON(Response{
+		Status: "success",
+		Value:  beefVerification{Valid: valid, Proofs: proofs},
+	})
+}
+
+// proofError responds to a failed proof verification, as a bad request if the proof itself is at fault
+func proofError(c *fiber.Ctx, err error) error {
+	if errors.Is(err, chaintracks.ErrInvalidProof) {
+		return c.Status(fiber.StatusBadRequest).JSON(Response{
+			Status:      "error",
+			Code:        "ERR_INVALID_PROOF",
+			Description: err.Error(),
+		})
+	}
+	return c.Status(fiber.StatusInternalServerError).JSON(Response{
+		Status:      "error",
+		Code:        "ERR_INTERNAL",
+		Description: err.Error(),
+	})
+}
+
+// maxHeadersCount is the most headers returned by one /v2/headers request; clients page with X-Next-Height
+const maxHeadersCount = 10000
+
+// Media types HandleGetHeaders can respond with, besides the default JSON envelope
+const (
+	mimeOctetStream = "application/octet-stream"
+	mimeJSONLines   = "application/x-ndjson"
+)
+
+// headerLine is one line of a JSON-lines /v2/headers response
+type headerLine struct {
+	Height    uint32         `json:"height"`
+	Hash      chainhash.Hash `json:"hash"`
+	ChainWork string         `json:"chainWork,omitempty"` // Cumulative chainwork as 64 hex digits
+	Header    string         `json:"header"`              // The 80-byte header (hex)
+}
+
+// HandleGetHeaders returns up to maxHeadersCount consecutive headers from the in-memory chain index, all from one
+// view of the chain. By default they are concatenated hex in a JSON envelope; Accept: application/octet-stream
+// streams the raw 80-byte headers and Accept: application/x-ndjson streams one JSON object per header with its
+// height, hash and chainwork. If fewer headers are returned than requested and the chain has more, X-Next-Height
+// gives the height to request next.
 func (s *Server) HandleGetHeaders(c *fiber.Ctx) error {
 	heightStr := c.Query("height")
 	countStr := c.Query("count")
@@ -345,21 +655,51 @@ func (s *Server) HandleGetHeaders(c *fiber.Ctx) error {
 		c.Set("Cache-Control", "no-cache")
 	}
 
-	var hexData string
-	for i := uint32(0); i < uint32(count); i++ {
-		h := uint32(height) + i
-		header, err := s.cm.GetHeaderByHeight(h)
-		if err != nil {
-			break
-		}
+	headers := s.cm.GetHeadersByHeight(uint32(height), uint32(min(count, maxHeadersCount)))
+	if next := height + uint64(len(headers)); uint64(len(headers)) < count && next <= uint64(tip) {
+		c.Set("X-Next-Height", strconv.FormatUint(next, 10))
+	}
+
+	c.Vary(fiber.HeaderAccept)
+	switch c.Accepts(fiber.MIMEApplicationJSON, mimeOctetStream, mimeJSONLines) {
+	case mimeOctetStream:
+		c.Set(fiber.HeaderContentType, mimeOctetStream)
+		c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+			for _, header := range headers {
+				if _, err := w.Write(header.Header.Bytes()); err != nil {
+					return
+				}
+			}
+			w.Flush()
+		})
+		return nil
+
+	case mimeJSONLines:
+		c.Set(fiber.HeaderContentType, mimeJSONLines)
+		c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+			enc := json.NewEncoder(w)
+			for _, header := range headers {
+				line := headerLine{Height: header.Height, Hash: header.Hash, Header: hex.EncodeToString(header.Header.Bytes())}
+				if header.ChainWork != nil {
+					line.ChainWork = chaintracks.ChainWorkToHex(header.ChainWork)
+				}
+				if err := enc.Encode(line); err != nil {
+					return
+				}
+			}
+			w.Flush()
+		})
+		return nil
+	}
 
-		headerBytes := header.Header.Bytes()
-		hexData += hex.EncodeToString(headerBytes)
+	hexData := make([]byte, 0, hex.EncodedLen(len(headers)*80))
+	for _, header := range headers {
+		hexData = hex.AppendEncode(hexData, header.Header.Bytes())
 	}
 
 	return c.JSON(Response{
 		Status: "success",
-		Value:  hexData,
+		Value:  string(hexData),
 	})
 }
 
@@ -407,6 +747,7 @@ func (s *Server) SetupRoutes(app *fiber.App, dashboard *DashboardHandler) {
 	app.Get("/robots.txt", s.HandleRobots)
 	app.Get("/docs", s.HandleSwaggerUI)
 	app.Get("/openapi.yaml", s.HandleOpenAPISpec)
+	app.Get(cdnPath+"/:file", s.HandleCDNFile)
 
 	v2 := app.Group("/v2")
 	v2.Get("/network", s.HandleGetNetwork)
@@ -414,7 +755,14 @@ func (s *Server) SetupRoutes(app *fiber.App, dashboard *DashboardHandler) {
 	v2.Get("/tip/hash", s.HandleGetTipHash)
 	v2.Get("/tip/header", s.HandleGetTipHeader)
 	v2.Get("/tip/stream", s.HandleTipStream)
+	v2.Get("/sync/status", s.HandleGetSyncStatus)
 	v2.Get("/header/height/:height", s.HandleGetHeaderByHeight)
 	v2.Get("/header/hash/:hash", s.HandleGetHeaderByHash)
+	v2.Get("/header/merkleroot/:root", s.HandleGetHeaderByMerkleRoot)
+	v2.Get("/mediantimepast/:height", s.HandleGetMedianTimePast)
 	v2.Get("/headers", s.HandleGetHeaders)
+	v2.Post("/merkleroots/verify", s.HandleVerifyMerkleRoots)
+	v2.Get("/merkleroot/:root/height/:height", s.HandleValidateRoot)
+	v2.Post("/verify/merklepath", s.HandleVerifyMerklePath)
+	v2.Post("/verify/beef", s.HandleVerifyBeef)
 }
@@ -1,18 +1,21 @@
 package main
 
 import (
+	"bytes"
 	"encoding/json"
+	"fmt"
 	"io"
 	"net/http/httptest"
 	"testing"
 
 	"github.com/bsv-blockchain/go-chaintracks/pkg/chaintracks"
 	"github.com/bsv-blockchain/go-sdk/chainhash"
+	"github.com/bsv-blockchain/go-sdk/transaction"
 	"github.com/gofiber/fiber/v2"
 )
 
 func setupTestApp(t *testing.T) (*fiber.App, *Server, *chaintracks.ChainManager) {
-	cm, err := chaintracks.NewChainManager("main", "../../data/headers", "")
+	cm, err := chaintracks.NewChainManager("main", "../../data/headers")
 	if err != nil {
 		t.Fatalf("Failed to create chain manager: %v", err)
 	}
@@ -129,6 +132,36 @@ func TestHandleGetTipHash(t *testing.T) {
 	}
 }
 
+func TestHandleGetSyncStatus(t *testing.T) {
+	app, _, cm := setupTestApp(t)
+
+	req := httptest.NewRequest("GET", "/v2/sync/status", nil)
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("Failed to make request: %v", err)
+	}
+
+	if resp.StatusCode != 200 {
+		t.Errorf("Expected status 200, got %d", resp.StatusCode)
+	}
+
+	body, _ := io.ReadAll(resp.Body)
+	var response struct {
+		Status string                 `json:"status"`
+		Value  chaintracks.SyncStatus `json:"value"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+
+	if response.Value.TipHeight != cm.GetHeight() {
+		t.Errorf("Expected tip height %d, got %d", cm.GetHeight(), response.Value.TipHeight)
+	}
+	if response.Value.SecondsBehind <= 0 {
+		t.Errorf("Expected a positive secondsBehind, got %d", response.Value.SecondsBehind)
+	}
+}
+
 func TestHandleGetTipHeader(t *testing.T) {
 	app, _, cm := setupTestApp(t)
 
@@ -318,6 +351,45 @@ func TestHandleGetHeaderByHash_NotFound(t *testing.T) {
 	}
 }
 
+func TestHandleGetHeaderByMerkleRoot(t *testing.T) {
+	app, _, cm := setupTestApp(t)
+
+	tip := cm.GetTip()
+
+	req := httptest.NewRequest("GET", "/v2/header/merkleroot/"+tip.MerkleRoot.String(), nil)
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("Failed to make request: %v", err)
+	}
+
+	if resp.StatusCode != 200 {
+		t.Errorf("Expected status 200, got %d", resp.StatusCode)
+	}
+
+	body, _ := io.ReadAll(resp.Body)
+	var response struct {
+		Status string                   `json:"status"`
+		Value  *chaintracks.BlockHeader `json:"value"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+
+	if response.Value == nil || response.Value.Hash != tip.Hash {
+		t.Errorf("Expected tip %s, got %+v", tip.Hash, response.Value)
+	}
+
+	req = httptest.NewRequest("GET", "/v2/header/merkleroot/invalid", nil)
+	resp, err = app.Test(req)
+	if err != nil {
+		t.Fatalf("Failed to make request: %v", err)
+	}
+
+	if resp.StatusCode != 400 {
+		t.Errorf("Expected status 400, got %d", resp.StatusCode)
+	}
+}
+
 func TestHandleGetHeaders(t *testing.T) {
 	app, _, cm := setupTestApp(t)
 
@@ -335,6 +407,10 @@ func TestHandleGetHeaders(t *testing.T) {
 	if resp.StatusCode != 200 {
 		t.Errorf("Expected status 200, got %d", resp.StatusCode)
 	}
+	// The format depends on Accept, so caches must key on it
+	if vary := resp.Header.Get("Vary"); vary != "Accept" {
+		t.Errorf("Expected Vary: Accept, got %q", vary)
+	}
 
 	body, _ := io.ReadAll(resp.Body)
 	var response struct {
@@ -378,3 +454,333 @@ func TestHandleGetHeaders_MissingParams(t *testing.T) {
 		t.Errorf("Expected status 'error', got '%s'", response.Status)
 	}
 }
+
+func TestHandleGetHeaders_Binary(t *testing.T) {
+	app, _, cm := setupTestApp(t)
+
+	if cm.GetHeight() <= maxHeadersCount {
+		t.Skip("Not enough headers to test")
+	}
+
+	// More than the maximum is cut short with a pointer to the next page
+	req := httptest.NewRequest("GET", fmt.Sprintf("/v2/headers?height=0&count=%d", maxHeadersCount+5), nil)
+	req.Header.Set("Accept", "application/octet-stream")
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("Failed to make request: %v", err)
+	}
+
+	if resp.StatusCode != 200 {
+		t.Errorf("Expected status 200, got %d", resp.StatusCode)
+	}
+	if ct := resp.Header.Get("Content-Type"); ct != "application/octet-stream" {
+		t.Errorf("Expected Content-Type application/octet-stream, got %q", ct)
+	}
+	if next := resp.Header.Get("X-Next-Height"); next != fmt.Sprint(maxHeadersCount) {
+		t.Errorf("Expected X-Next-Height %d, got %q", maxHeadersCount, next)
+	}
+
+	body, _ := io.ReadAll(resp.Body)
+	if len(body) != maxHeadersCount*80 {
+		t.Fatalf("Expected %d bytes, got %d", maxHeadersCount*80, len(body))
+	}
+
+	header, _ := cm.GetHeaderByHeight(maxHeadersCount - 1)
+	if !bytes.Equal(body[len(body)-80:], header.Header.Bytes()) {
+		t.Errorf("Last header does not match height %d", maxHeadersCount-1)
+	}
+}
+
+func TestHandleGetHeaders_JSONLines(t *testing.T) {
+	app, _, cm := setupTestApp(t)
+
+	tip := cm.GetTip()
+
+	// A range past the tip stops at the tip without a next page
+	req := httptest.NewRequest("GET", fmt.Sprintf("/v2/headers?height=%d&count=10", tip.Height-1), nil)
+	req.Header.Set("Accept", "application/x-ndjson")
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("Failed to make request: %v", err)
+	}
+
+	if resp.StatusCode != 200 {
+		t.Errorf("Expected status 200, got %d", resp.StatusCode)
+	}
+	if next := resp.Header.Get("X-Next-Height"); next != "" {
+		t.Errorf("Expected no X-Next-Height at the tip, got %q", next)
+	}
+	if vary := resp.Header.Get("Vary"); vary != "Accept" {
+		t.Errorf("Expected Vary: Accept, got %q", vary)
+	}
+
+	var lines []headerLine
+	dec := json.NewDecoder(resp.Body)
+	for dec.More() {
+		var line headerLine
+		if err := dec.Decode(&line); err != nil {
+			t.Fatalf("Failed to decode line: %v", err)
+		}
+		lines = append(lines, line)
+	}
+
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 lines, got %d", len(lines))
+	}
+	last := lines[1]
+	if last.Height != tip.Height || last.Hash != tip.Hash || last.ChainWork != chaintracks.ChainWorkToHex(tip.ChainWork) {
+		t.Errorf("Expected the tip at height %d, got %+v", tip.Height, last)
+	}
+}
+
+func TestHandleGetMedianTimePast(t *testing.T) {
+	app, _, cm := setupTestApp(t)
+
+	tip := cm.GetTip()
+	expected, err := cm.GetMedianTimePast(tip.Height)
+	if err != nil {
+		t.Fatalf("Failed to get median time past: %v", err)
+	}
+
+	req := httptest.NewRequest("GET", fmt.Sprintf("/v2/mediantimepast/%d", tip.Height), nil)
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("Failed to make request: %v", err)
+	}
+
+	if resp.StatusCode != 200 {
+		t.Errorf("Expected status 200, got %d", resp.StatusCode)
+	}
+
+	body, _ := io.ReadAll(resp.Body)
+	var response struct {
+		Status string `json:"status"`
+		Value  uint32 `json:"value"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+
+	if response.Status != "success" {
+		t.Errorf("Expected status 'success', got '%s'", response.Status)
+	}
+
+	if response.Value != expected {
+		t.Errorf("Expected median time past %d, got %d", expected, response.Value)
+	}
+}
+
+func TestHandleVerifyMerkleRoots(t *testing.T) {
+	app, _, cm := setupTestApp(t)
+
+	tip := cm.GetTip()
+	checks := []chaintracks.RootCheck{
+		{Height: tip.Height, MerkleRoot: tip.MerkleRoot},
+		{Height: tip.Height, MerkleRoot: tip.Hash},
+		{Height: tip.Height + 1, MerkleRoot: tip.MerkleRoot},
+	}
+	body, _ := json.Marshal(checks)
+
+	req := httptest.NewRequest("POST", "/v2/merkleroots/verify", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("Failed to make request: %v", err)
+	}
+
+	if resp.StatusCode != 200 {
+		t.Errorf("Expected status 200, got %d", resp.StatusCode)
+	}
+
+	respBody, _ := io.ReadAll(resp.Body)
+	var response struct {
+		Status string                   `json:"status"`
+		Value  []chaintracks.RootResult `json:"value"`
+	}
+	if err := json.Unmarshal(respBody, &response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+
+	want := []chaintracks.RootValidity{chaintracks.RootValid, chaintracks.RootInvalid, chaintracks.RootUnknown}
+	if len(response.Value) != len(want) {
+		t.Fatalf("Expected %d results, got %d", len(want), len(response.Value))
+	}
+	for i, result := range response.Value {
+		if result.Result != want[i] || result.Height != checks[i].Height || result.MerkleRoot != checks[i].MerkleRoot {
+			t.Errorf("Result %d = %+v, want %s for %+v", i, result, want[i], checks[i])
+		}
+	}
+}
+
+func TestHandleVerifyMerkleRoots_InvalidBody(t *testing.T) {
+	app, _, _ := setupTestApp(t)
+
+	tooMany, _ := json.Marshal(make([]chaintracks.RootCheck, maxVerifyRoots+1))
+	for _, body := range [][]byte{[]byte(`{"height": 1}`), tooMany} {
+		req := httptest.NewRequest("POST", "/v2/merkleroots/verify", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("Failed to make request: %v", err)
+		}
+
+		if resp.StatusCode != 400 {
+			t.Errorf("Expected status 400, got %d", resp.StatusCode)
+		}
+	}
+}
+
+func TestHandleVerifyMerklePath(t *testing.T) {
+	app, _, cm := setupTestApp(t)
+
+	// A block with a single transaction has that transaction's ID as its merkle root
+	genesis, err := cm.GetHeaderByHeight(0)
+	if err != nil {
+		t.Fatalf("Failed to get genesis header: %v", err)
+	}
+	isTxid := true
+	path := transaction.NewMerklePath(0, [][]*transaction.PathElement{{{Offset: 0, Hash: &genesis.MerkleRoot, Txid: &isTxid}}})
+
+	body, _ := json.Marshal(map[string]string{"merklePath": path.Hex(), "txid": genesis.MerkleRoot.String()})
+	req := httptest.NewRequest("POST", "/v2/verify/merklepath", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("Failed to make request: %v", err)
+	}
+
+	if resp.StatusCode != 200 {
+		t.Errorf("Expected status 200, got %d", resp.StatusCode)
+	}
+
+	respBody, _ := io.ReadAll(resp.Body)
+	var response struct {
+		Status string                  `json:"status"`
+		Value  chaintracks.ProofResult `json:"value"`
+	}
+	if err := json.Unmarshal(respBody, &response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+
+	if response.Value.Result != chaintracks.RootValid || response.Value.Confirmations != cm.GetHeight()+1 {
+		t.Errorf("Expected valid with %d confirmations, got %+v", cm.GetHeight()+1, response.Value)
+	}
+
+	// A txid the path does not contain is a bad proof
+	body, _ = json.Marshal(map[string]string{"merklePath": path.Hex(), "txid": chainhash.Hash{1}.String()})
+	req = httptest.NewRequest("POST", "/v2/verify/merklepath", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err = app.Test(req)
+	if err != nil {
+		t.Fatalf("Failed to make request: %v", err)
+	}
+
+	if resp.StatusCode != 400 {
+		t.Errorf("Expected status 400, got %d", resp.StatusCode)
+	}
+}
+
+func TestHandleVerifyBeef(t *testing.T) {
+	app, _, cm := setupTestApp(t)
+
+	tx := transaction.NewTransaction()
+	isTxid := true
+	tx.MerklePath = transaction.NewMerklePath(cm.GetHeight()+10, [][]*transaction.PathElement{{{Offset: 0, Hash: tx.TxID(), Txid: &isTxid}}})
+	beef, err := tx.BEEF()
+	if err != nil {
+		t.Fatalf("Failed to build BEEF: %v", err)
+	}
+
+	req := httptest.NewRequest("POST", "/v2/verify/beef", bytes.NewReader(beef))
+	req.Header.Set("Content-Type", "application/octet-stream")
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("Failed to make request: %v", err)
+	}
+
+	if resp.StatusCode != 200 {
+		t.Errorf("Expected status 200, got %d", resp.StatusCode)
+	}
+
+	body, _ := io.ReadAll(resp.Body)
+	var response struct {
+		Status string `json:"status"`
+		Value  struct {
+			Valid  bool                      `json:"valid"`
+			Proofs []chaintracks.ProofResult `json:"proofs"`
+		} `json:"value"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+
+	// The proof is for a block beyond the tip, so it cannot be confirmed yet
+	if response.Value.Valid || len(response.Value.Proofs) != 1 || response.Value.Proofs[0].Result != chaintracks.RootUnknown {
+		t.Errorf("Expected one unknown proof, got %+v", response.Value)
+	}
+
+	req = httptest.NewRequest("POST", "/v2/verify/beef", bytes.NewReader([]byte(`{"beef": "00"}`)))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err = app.Test(req)
+	if err != nil {
+		t.Fatalf("Failed to make request: %v", err)
+	}
+
+	if resp.StatusCode != 400 {
+		t.Errorf("Expected status 400, got %d", resp.StatusCode)
+	}
+}
+
+func TestHandleValidateRoot(t *testing.T) {
+	app, _, cm := setupTestApp(t)
+
+	tip := cm.GetTip()
+
+	req := httptest.NewRequest("GET", fmt.Sprintf("/v2/merkleroot/%s/height/%d", tip.MerkleRoot, tip.Height), nil)
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("Failed to make request: %v", err)
+	}
+
+	if resp.StatusCode != 200 {
+		t.Errorf("Expected status 200, got %d", resp.StatusCode)
+	}
+
+	body, _ := io.ReadAll(resp.Body)
+	var response struct {
+		Status string                 `json:"status"`
+		Value  chaintracks.RootStatus `json:"value"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+
+	if !response.Value.OnMainChain || response.Value.Confirmations != 1 || response.Value.BeyondTip {
+		t.Errorf("Expected the tip root on the main chain with 1 confirmation, got %+v", response.Value)
+	}
+
+	req = httptest.NewRequest("GET", fmt.Sprintf("/v2/merkleroot/%s/height/%d", tip.MerkleRoot, tip.Height+1), nil)
+	resp, err = app.Test(req)
+	if err != nil {
+		t.Fatalf("Failed to make request: %v", err)
+	}
+
+	body, _ = io.ReadAll(resp.Body)
+	if err := json.Unmarshal(body, &response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+
+	if response.Value.OnMainChain || !response.Value.BeyondTip {
+		t.Errorf("Expected a height beyond the tip, got %+v", response.Value)
+	}
+
+	req = httptest.NewRequest("GET", "/v2/merkleroot/invalid/height/1", nil)
+	resp, err = app.Test(req)
+	if err != nil {
+		t.Fatalf("Failed to make request: %v", err)
+	}
+
+	if resp.StatusCode != 400 {
+		t.Errorf("Expected status 400, got %d", resp.StatusCode)
+	}
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/bsv-blockchain/go-chaintracks/pkg/chaintracks"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/adaptor"
+)
+
+// cdnPath is the route prefix the header files are served under
+const cdnPath = "/cdn"
+
+// ServeCDN exposes store's metadata and .headers files under /cdn so other instances can bootstrap from it.
+// rootURL is the public URL of /cdn written to the manifest's rootFolder and sourceUrl fields;
+// if empty it is derived from each request.
+func (s *Server) ServeCDN(store *chaintracks.FileStore, rootURL string) {
+	s.cdnStore = store
+	s.cdnRootURL = strings.TrimSuffix(rootURL, "/")
+}
+
+// HandleCDNFile serves the CDN manifest or a .headers file with strong ETags and range support
+func (s *Server) HandleCDNFile(c *fiber.Ctx) error {
+	if s.cdnStore == nil {
+		return c.Status(fiber.StatusNotFound).JSON(Response{
+			Status:      "error",
+			Code:        "ERR_CDN_DISABLED",
+			Description: "CDN serving is only available with the file header store",
+		})
+	}
+
+	network, _ := s.cm.GetNetwork()
+	fileName := c.Params("file")
+
+	var (
+		data        []byte
+		etag        string
+		contentType string
+	)
+
+	if fileName == network+"NetBlockHeaders.json" {
+		metadata, err := s.cdnStore.CDNMetadata()
+		if err != nil || metadata == nil {
+			return c.Status(fiber.StatusServiceUnavailable).JSON(Response{
+				Status:      "error",
+				Code:        "ERR_NO_HEADERS",
+				Description: "No headers available",
+			})
+		}
+
+		rootURL := s.cdnRootURL
+		if rootURL == "" {
+			rootURL = c.BaseURL() + cdnPath
+		}
+		metadata.RootFolder = rootURL
+		for i := range metadata.Files {
+			metadata.Files[i].SourceURL = rootURL
+		}
+
+		if data, err = json.MarshalIndent(metadata, "", "  "); err != nil {
+			return err
+		}
+		sum := sha256.Sum256(data)
+		etag = base64.StdEncoding.EncodeToString(sum[:])
+		contentType = "application/json"
+	} else {
+		entry, fileData, err := s.cdnStore.ReadCDNFile(fileName)
+		if errors.Is(err, chaintracks.ErrHeaderNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(Response{
+				Status:      "error",
+				Code:        "ERR_NOT_FOUND",
+				Description: "Header file not found",
+			})
+		}
+		if err != nil {
+			return err
+		}
+
+		data = fileData
+		etag = entry.FileHash
+		contentType = "application/octet-stream"
+	}
+
+	// Any file can change on a reorg, so clients revalidate against the strong ETag.
+	// http.ServeContent handles Range, If-Range, If-None-Match and HEAD for us.
+	return adaptor.HTTPHandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", contentType)
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("ETag", `"`+etag+`"`)
+		http.ServeContent(w, r, fileName, time.Time{}, bytes.NewReader(data))
+	})(c)
+}
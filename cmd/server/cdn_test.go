@@ -0,0 +1,163 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/bsv-blockchain/go-chaintracks/pkg/chaintracks"
+	"github.com/bsv-blockchain/go-sdk/block"
+	"github.com/gofiber/fiber/v2"
+)
+
+// setupCDNTestApp serves a regtest FileStore holding count linked headers
+func setupCDNTestApp(t *testing.T, count int) (*fiber.App, []byte) {
+	t.Helper()
+
+	dir := t.TempDir()
+	store := chaintracks.NewFileStore(dir, "regtest")
+
+	var (
+		headers []*chaintracks.BlockHeader
+		data    []byte
+		parent  *chaintracks.BlockHeader
+	)
+	for i := 0; i < count; i++ {
+		header := &block.Header{Version: 1, Timestamp: uint32(1700000000 + i*600), Bits: 0x207fffff, Nonce: uint32(i)}
+		chainWork := chaintracks.CalculateWork(header.Bits)
+		if parent != nil {
+			header.PrevHash = parent.Hash
+			chainWork = chaintracks.AddWork(parent.ChainWork, header.Bits)
+		}
+		parent = &chaintracks.BlockHeader{Header: header, Height: uint32(i), Hash: header.Hash(), ChainWork: chainWork}
+		headers = append(headers, parent)
+		data = append(data, header.Bytes()...)
+	}
+	if err := store.PutBranch(headers); err != nil {
+		t.Fatalf("PutBranch() error = %v", err)
+	}
+
+	cm, err := chaintracks.NewChainManager("regtest", dir)
+	if err != nil {
+		t.Fatalf("Failed to create chain manager: %v", err)
+	}
+
+	server := NewServer(cm)
+	server.ServeCDN(store, "")
+	app := fiber.New()
+	server.SetupRoutes(app, NewDashboardHandler(server))
+	return app, data
+}
+
+func TestHandleCDNManifest(t *testing.T) {
+	app, data := setupCDNTestApp(t, 10)
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/cdn/regtestNetBlockHeaders.json", nil))
+	if err != nil {
+		t.Fatalf("Failed to make request: %v", err)
+	}
+	if resp.StatusCode != 200 {
+		t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+	}
+	if resp.Header.Get("ETag") == "" {
+		t.Error("Expected an ETag on the manifest")
+	}
+
+	var metadata chaintracks.CDNMetadata
+	if err := json.NewDecoder(resp.Body).Decode(&metadata); err != nil {
+		t.Fatalf("Failed to decode manifest: %v", err)
+	}
+
+	if metadata.RootFolder != "http://example.com/cdn" {
+		t.Errorf("Expected rootFolder http://example.com/cdn, got %q", metadata.RootFolder)
+	}
+	if len(metadata.Files) != 1 {
+		t.Fatalf("Expected 1 file, got %d", len(metadata.Files))
+	}
+
+	sum := sha256.Sum256(data)
+	entry := metadata.Files[0]
+	if entry.FileHash != base64.StdEncoding.EncodeToString(sum[:]) {
+		t.Errorf("Manifest fileHash %q does not match the file contents", entry.FileHash)
+	}
+	if entry.SourceURL != metadata.RootFolder || entry.Count != 10 {
+		t.Errorf("Unexpected file entry: %+v", entry)
+	}
+}
+
+func TestHandleCDNHeaderFile(t *testing.T) {
+	app, data := setupCDNTestApp(t, 10)
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/cdn/regtestNet_0.headers", nil))
+	if err != nil {
+		t.Fatalf("Failed to make request: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != 200 || string(body) != string(data) {
+		t.Fatalf("Expected status 200 with the file contents, got %d with %d bytes", resp.StatusCode, len(body))
+	}
+
+	sum := sha256.Sum256(data)
+	etag := `"` + base64.StdEncoding.EncodeToString(sum[:]) + `"`
+	if resp.Header.Get("ETag") != etag {
+		t.Errorf("Expected ETag %s, got %s", etag, resp.Header.Get("ETag"))
+	}
+	if resp.Header.Get("Accept-Ranges") != "bytes" {
+		t.Errorf("Expected Accept-Ranges: bytes, got %q", resp.Header.Get("Accept-Ranges"))
+	}
+
+	// Conditional request with the current ETag
+	req := httptest.NewRequest("GET", "/cdn/regtestNet_0.headers", nil)
+	req.Header.Set("If-None-Match", etag)
+	resp, err = app.Test(req)
+	if err != nil {
+		t.Fatalf("Failed to make request: %v", err)
+	}
+	if resp.StatusCode != 304 {
+		t.Errorf("Expected status 304 for a matching If-None-Match, got %d", resp.StatusCode)
+	}
+
+	// Range request for the second and third headers
+	req = httptest.NewRequest("GET", "/cdn/regtestNet_0.headers", nil)
+	req.Header.Set("Range", "bytes=80-239")
+	resp, err = app.Test(req)
+	if err != nil {
+		t.Fatalf("Failed to make request: %v", err)
+	}
+	body, _ = io.ReadAll(resp.Body)
+	if resp.StatusCode != 206 {
+		t.Fatalf("Expected status 206, got %d", resp.StatusCode)
+	}
+	if string(body) != string(data[80:240]) {
+		t.Errorf("Range body does not match bytes 80-239")
+	}
+	if resp.Header.Get("Content-Range") != "bytes 80-239/800" {
+		t.Errorf("Expected Content-Range bytes 80-239/800, got %q", resp.Header.Get("Content-Range"))
+	}
+
+	// Unsatisfiable range
+	req = httptest.NewRequest("GET", "/cdn/regtestNet_0.headers", nil)
+	req.Header.Set("Range", "bytes=800-")
+	resp, err = app.Test(req)
+	if err != nil {
+		t.Fatalf("Failed to make request: %v", err)
+	}
+	if resp.StatusCode != 416 {
+		t.Errorf("Expected status 416, got %d", resp.StatusCode)
+	}
+}
+
+func TestHandleCDNFileNotFound(t *testing.T) {
+	app, _ := setupCDNTestApp(t, 10)
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/cdn/regtestNet_7.headers", nil))
+	if err != nil {
+		t.Fatalf("Failed to make request: %v", err)
+	}
+	if resp.StatusCode != 404 {
+		t.Errorf("Expected status 404, got %d", resp.StatusCode)
+	}
+}
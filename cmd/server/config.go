@@ -1,9 +1,14 @@
 package main
 
 import (
+	"log"
 	"os"
 	"path/filepath"
 	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bsv-blockchain/go-chaintracks/pkg/chaintracks"
 )
 
 // Config holds the server configuration
@@ -11,7 +16,24 @@ type Config struct {
 	Port         int
 	Network      string
 	StoragePath  string
+	HeaderStore  string // "file" (default) or "bolt"
+	VerifyMode   chaintracks.VerifyMode
 	BootstrapURL string
+	BootstrapCDN string
+	CDNUnhashed  bool                          // Accept CDN manifest entries without a fileHash
+	Sources      []chaintracks.BootstrapSource // Extra bootstrap sources, cross-checked against each other
+	SyncWindow   int                           // Parallel batch requests for forward sync from a Chaintracks bootstrap node (0 walks back)
+	CDNPublicURL string                        // Public URL of this server's /cdn route for the served manifest
+	NodePeers    []string                      // SV Nodes followed over the legacy Bitcoin P2P protocol
+	Resync       time.Duration                 // How often upstreams are checked for missed headers (0 disables)
+	DataHub      chaintracks.DataHubPolicy     // Which peer-announced DataHub URLs crawl-back may fetch from
+	BanThreshold int                           // Penalty total at which a peer's announcements are ignored (0 disables)
+	BanDuration  time.Duration                 // How long a peer stays banned
+	CrawlWorkers int                           // Crawl-backs for P2P announcements that run at once
+	HTTPTimeout  time.Duration                 // Per-request timeout for bootstrap sources
+	Retry        chaintracks.RetryPolicy       // Retries for outbound sync requests
+	StartupWait  time.Duration                 // Deadline for the startup bootstrap (0 waits as long as it takes)
+	Checkpoints  []chaintracks.Checkpoint
 }
 
 // LoadConfig loads configuration from environment variables with defaults
@@ -33,14 +55,181 @@ func LoadConfig() *Config {
 		storagePath = path
 	}
 
+	headerStore := "file"
+	if store := os.Getenv("HEADER_STORE"); store != "" {
+		headerStore = store
+	}
+	if headerStore != "file" && headerStore != "bolt" {
+		log.Fatalf("Invalid HEADER_STORE %q: expected file or bolt", headerStore)
+	}
+
+	verifyMode := chaintracks.VerifyOff
+	if mode := os.Getenv("VERIFY_STORE"); mode != "" {
+		parsed, err := chaintracks.ParseVerifyMode(mode)
+		if err != nil {
+			log.Fatalf("Invalid VERIFY_STORE: %v", err)
+		}
+		verifyMode = parsed
+	}
+
 	bootstrapURL := os.Getenv("BOOTSTRAP_URL")
+	bootstrapCDN := os.Getenv("BOOTSTRAP_CDN")
+	cdnPublicURL := os.Getenv("CDN_PUBLIC_URL")
+
+	cdnUnhashed := false
+	if unhashedStr := os.Getenv("CDN_ALLOW_UNHASHED"); unhashedStr != "" {
+		allow, err := strconv.ParseBool(unhashedStr)
+		if err != nil {
+			log.Fatalf("Invalid CDN_ALLOW_UNHASHED %q: expected true or false", unhashedStr)
+		}
+		cdnUnhashed = allow
+	}
+
+	var sources []chaintracks.BootstrapSource
+	if srcStr := os.Getenv("BOOTSTRAP_SOURCES"); srcStr != "" {
+		parsed, err := chaintracks.ParseBootstrapSources(srcStr)
+		if err != nil {
+			log.Fatalf("Invalid BOOTSTRAP_SOURCES: %v", err)
+		}
+		sources = parsed
+	}
+
+	syncWindow := 0
+	if windowStr := os.Getenv("SYNC_WINDOW"); windowStr != "" {
+		w, err := strconv.Atoi(windowStr)
+		if err != nil || w < 0 {
+			log.Fatalf("Invalid SYNC_WINDOW %q: expected a non-negative number of requests", windowStr)
+		}
+		syncWindow = w
+	}
+
+	resync := chaintracks.DefaultResyncInterval
+	if resyncStr := os.Getenv("RESYNC_INTERVAL"); resyncStr != "" {
+		d, err := time.ParseDuration(resyncStr)
+		if err != nil || d < 0 {
+			log.Fatalf("Invalid RESYNC_INTERVAL %q: expected a duration such as 1m, or 0 to disable", resyncStr)
+		}
+		resync = d
+	}
+
+	dataHub := chaintracks.DefaultDataHubPolicy()
+	dataHub.AllowedSchemes = splitList(os.Getenv("DATAHUB_SCHEMES"))
+	dataHub.AllowedHosts = splitList(os.Getenv("DATAHUB_ALLOWED_HOSTS"))
+	dataHub.DeniedHosts = splitList(os.Getenv("DATAHUB_DENIED_HOSTS"))
+	if privStr := os.Getenv("DATAHUB_ALLOW_PRIVATE"); privStr != "" {
+		allow, err := strconv.ParseBool(privStr)
+		if err != nil {
+			log.Fatalf("Invalid DATAHUB_ALLOW_PRIVATE %q: expected true or false", privStr)
+		}
+		dataHub.AllowPrivate = allow
+	}
+	if timeoutStr := os.Getenv("DATAHUB_TIMEOUT"); timeoutStr != "" {
+		d, err := time.ParseDuration(timeoutStr)
+		if err != nil || d <= 0 {
+			log.Fatalf("Invalid DATAHUB_TIMEOUT %q: expected a positive duration such as 30s", timeoutStr)
+		}
+		dataHub.Timeout = d
+	}
+
+	banThreshold := chaintracks.DefaultPeerBanThreshold
+	if thresholdStr := os.Getenv("PEER_BAN_THRESHOLD"); thresholdStr != "" {
+		n, err := strconv.Atoi(thresholdStr)
+		if err != nil || n < 0 {
+			log.Fatalf("Invalid PEER_BAN_THRESHOLD %q: expected a non-negative number, or 0 to disable banning", thresholdStr)
+		}
+		banThreshold = n
+	}
+
+	banDuration := chaintracks.DefaultPeerBanDuration
+	if durationStr := os.Getenv("PEER_BAN_DURATION"); durationStr != "" {
+		d, err := time.ParseDuration(durationStr)
+		if err != nil || d <= 0 {
+			log.Fatalf("Invalid PEER_BAN_DURATION %q: expected a positive duration such as 1h", durationStr)
+		}
+		banDuration = d
+	}
+
+	crawlWorkers := chaintracks.DefaultCrawlWorkers
+	if workersStr := os.Getenv("CRAWL_WORKERS"); workersStr != "" {
+		n, err := strconv.Atoi(workersStr)
+		if err != nil || n <= 0 {
+			log.Fatalf("Invalid CRAWL_WORKERS %q: expected a positive number", workersStr)
+		}
+		crawlWorkers = n
+	}
+
+	httpTimeout := chaintracks.DefaultHTTPTimeout
+	if timeoutStr := os.Getenv("HTTP_TIMEOUT"); timeoutStr != "" {
+		d, err := time.ParseDuration(timeoutStr)
+		if err != nil || d <= 0 {
+			log.Fatalf("Invalid HTTP_TIMEOUT %q: expected a positive duration such as 30s", timeoutStr)
+		}
+		httpTimeout = d
+	}
+
+	retry := chaintracks.DefaultRetryPolicy()
+	if retriesStr := os.Getenv("HTTP_RETRIES"); retriesStr != "" {
+		n, err := strconv.Atoi(retriesStr)
+		if err != nil || n < 0 {
+			log.Fatalf("Invalid HTTP_RETRIES %q: expected a non-negative number", retriesStr)
+		}
+		retry.MaxAttempts = n + 1
+	}
+
+	var startupWait time.Duration
+	if startupStr := os.Getenv("STARTUP_TIMEOUT"); startupStr != "" {
+		d, err := time.ParseDuration(startupStr)
+		if err != nil || d < 0 {
+			log.Fatalf("Invalid STARTUP_TIMEOUT %q: expected a duration such as 10m, or 0 to wait for the bootstrap", startupStr)
+		}
+		startupWait = d
+	}
+
+	nodePeers := splitList(os.Getenv("NODE_PEERS"))
+
+	var checkpoints []chaintracks.Checkpoint
+	if cpStr := os.Getenv("CHECKPOINTS"); cpStr != "" {
+		parsed, err := chaintracks.ParseCheckpoints(cpStr)
+		if err != nil {
+			log.Fatalf("Invalid CHECKPOINTS: %v", err)
+		}
+		checkpoints = parsed
+	}
 
 	return &Config{
 		Port:         port,
 		Network:      network,
 		StoragePath:  storagePath,
+		HeaderStore:  headerStore,
+		VerifyMode:   verifyMode,
 		BootstrapURL: bootstrapURL,
+		BootstrapCDN: bootstrapCDN,
+		CDNUnhashed:  cdnUnhashed,
+		Sources:      sources,
+		SyncWindow:   syncWindow,
+		CDNPublicURL: cdnPublicURL,
+		NodePeers:    nodePeers,
+		Resync:       resync,
+		DataHub:      dataHub,
+		BanThreshold: banThreshold,
+		BanDuration:  banDuration,
+		CrawlWorkers: crawlWorkers,
+		HTTPTimeout:  httpTimeout,
+		Retry:        retry,
+		StartupWait:  startupWait,
+		Checkpoints:  checkpoints,
+	}
+}
+
+// splitList splits a comma-separated list, dropping empty entries
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
 	}
+	return items
 }
 
 // getDefaultStoragePath returns ~/.chaintracks as the default storage path
@@ -2,6 +2,8 @@ package main
 
 import (
 	"fmt"
+	"html"
+	"sort"
 	"time"
 
 	"github.com/bsv-blockchain/go-chaintracks/pkg/chaintracks"
@@ -134,8 +136,12 @@ func (h *DashboardHandler) HandleStatus(c *fiber.Ctx) error {
             <div><span class="label">Current Height:</span><span class="value">%d</span></div>
             <div><span class="label">Tip Hash:</span><span class="value hash">%s</span></div>
             <div><span class="label">Chainwork:</span><span class="value">%s</span></div>
+            %s
+            %s
         </div>
 
+        %s
+
         <div class="section">
             <h2>P2P Network</h2>
             <div><span class="label">Connected Peers:</span><span class="value">%d</span></div>
@@ -144,6 +150,8 @@ func (h *DashboardHandler) HandleStatus(c *fiber.Ctx) error {
             </div>
         </div>
 
+        %s
+
         <div class="timestamp">
             Last updated: %s (auto-refresh every 10s)
         </div>
@@ -154,8 +162,12 @@ func (h *DashboardHandler) HandleStatus(c *fiber.Ctx) error {
 		height,
 		tipHash,
 		tipChainwork,
+		h.renderFreshness(),
+		h.renderSyncProgress(),
+		h.renderBootstrapSources(),
 		peerCount,
 		h.renderPeerList(peers),
+		h.renderPeerScores(),
 		time.Now().Format("2006-01-02 15:04:05 MST"),
 	)
 
@@ -163,6 +175,72 @@ func (h *DashboardHandler) HandleStatus(c *fiber.Ctx) error {
 	return c.SendString(html)
 }
 
+// renderFreshness generates HTML for when the tip last changed and how far it is behind
+func (h *DashboardHandler) renderFreshness() string {
+	status := h.server.cm.GetSyncStatus()
+
+	lastHeader := "not since startup"
+	if !status.LastHeaderAt.IsZero() {
+		lastHeader = fmt.Sprintf("%s (%s ago)", status.LastHeaderAt.Format("2006-01-02 15:04:05 MST"), time.Since(status.LastHeaderAt).Round(time.Second))
+	}
+	behind := fmt.Sprintf("%ds since tip timestamp", status.SecondsBehind)
+	if status.BlocksBehind > 0 {
+		behind += fmt.Sprintf(", %d blocks behind upstream height %d", status.BlocksBehind, status.UpstreamHeight)
+	}
+	if status.LastCheckError != "" {
+		behind += ", last check failed: " + html.EscapeString(status.LastCheckError)
+	}
+
+	return fmt.Sprintf(`<div><span class="label">Last Header:</span><span class="value">%s</span></div>
+            <div><span class="label">Behind:</span><span class="value">%s</span></div>`, lastHeader, behind)
+}
+
+// renderSyncProgress generates HTML for the forward sync status, or nothing if no sync has run
+func (h *DashboardHandler) renderSyncProgress() string {
+	progress, ok := h.server.cm.GetSyncProgress()
+	if !ok {
+		return ""
+	}
+
+	status := fmt.Sprintf("%d / %d (%.1f%%, %.0f headers/s)", progress.Height, progress.TargetHeight, progress.Percent(), progress.Rate())
+	if progress.Error != "" {
+		status += " failed: " + html.EscapeString(progress.Error)
+	} else if progress.Done {
+		status += " complete"
+	}
+	return fmt.Sprintf(`<div><span class="label">Sync:</span><span class="value">%s</span></div>`, status)
+}
+
+// renderBootstrapSources generates a section listing each bootstrap source and whether it agrees with the chain
+func (h *DashboardHandler) renderBootstrapSources() string {
+	statuses := h.server.cm.GetBootstrapStatus()
+	if len(statuses) == 0 {
+		return ""
+	}
+
+	rows := ""
+	for _, status := range statuses {
+		state := "agrees"
+		switch {
+		case status.Conflict != "":
+			state = "CONFLICT: " + status.Conflict
+		case status.Error != "":
+			state = "error: " + status.Error
+		case status.Ahead:
+			state = "ahead (tip not known locally)"
+		case !status.Agrees:
+			state = "unverified"
+		}
+		rows += fmt.Sprintf(`<div><span class="label">%s:</span><span class="value hash">%s - %s</span></div>`,
+			status.Source.Kind, html.EscapeString(status.Source.URL), html.EscapeString(state))
+	}
+
+	return `<div class="section">
+            <h2>Bootstrap Sources</h2>
+            ` + rows + `
+        </div>`
+}
+
 // renderPeerList generates HTML for the peer list
 func (h *DashboardHandler) renderPeerList(peers []chaintracks.PeerInfo) string {
 	if len(peers) == 0 {
@@ -181,14 +259,72 @@ func (h *DashboardHandler) renderPeerList(peers []chaintracks.PeerInfo) string {
 			addrs += fmt.Sprintf(`<div class="peer-addr">%s</div>`, addr)
 		}
 
+		reputation := ""
+		if peer.Reputation != nil {
+			reputation = fmt.Sprintf(`<div class="peer-addr">%s</div>`, formatPeerScore(*peer.Reputation))
+		}
+
 		html += fmt.Sprintf(`
 			<div class="peer">
 				<div><strong>%s</strong></div>
 				<div class="peer-id">%s</div>
 				%s
+				%s
 			</div>
-		`, name, peer.ID, addrs)
+		`, name, peer.ID, addrs, reputation)
 	}
 
 	return html
 }
+
+// maxDashboardPeerScores limits the reputation section to the worst-scoring peers
+const maxDashboardPeerScores = 20
+
+// renderPeerScores generates a section listing the reputation of announcing peers, worst first
+func (h *DashboardHandler) renderPeerScores() string {
+	scores := h.server.cm.GetPeerScores()
+	if len(scores) == 0 {
+		return ""
+	}
+
+	ids := make([]string, 0, len(scores))
+	for id := range scores {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		a, b := scores[ids[i]], scores[ids[j]]
+		if a.Banned(time.Now()) != b.Banned(time.Now()) {
+			return a.Banned(time.Now())
+		}
+		if a.Score != b.Score {
+			return a.Score < b.Score
+		}
+		return ids[i] < ids[j]
+	})
+	if len(ids) > maxDashboardPeerScores {
+		ids = ids[:maxDashboardPeerScores]
+	}
+
+	rows := ""
+	for _, id := range ids {
+		rows += fmt.Sprintf(`<div><span class="label">%s:</span><span class="value">%s</span></div>`,
+			html.EscapeString(id), formatPeerScore(scores[id]))
+	}
+
+	return `<div class="section">
+            <h2>Peer Reputation</h2>
+            ` + rows + `
+        </div>`
+}
+
+// formatPeerScore summarizes a peer's reputation
+func formatPeerScore(score chaintracks.PeerScore) string {
+	summary := fmt.Sprintf("score %d, %d good, %d bad", score.Score, score.Good, score.Bad)
+	if score.LastOffense != "" {
+		summary += fmt.Sprintf(" (last: %s)", score.LastOffense)
+	}
+	if score.Banned(time.Now()) {
+		summary += fmt.Sprintf(", BANNED until %s (%d ignored)", score.BannedUntil.Format("15:04:05 MST"), score.Ignored)
+	}
+	return summary
+}
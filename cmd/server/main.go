@@ -5,9 +5,11 @@ import (
 	"fmt"
 	"io"
 	"log"
+	"net/http"
 	"os"
 	"os/signal"
 	"path/filepath"
+	"strings"
 	"syscall"
 
 	"github.com/bsv-blockchain/go-chaintracks/pkg/chaintracks"
@@ -27,17 +29,75 @@ func main() {
 	log.Printf("  Network: %s", config.Network)
 	log.Printf("  Port: %d", config.Port)
 	log.Printf("  Storage Path: %s", config.StoragePath)
+	log.Printf("  Header Store: %s", config.HeaderStore)
+	if config.VerifyMode != chaintracks.VerifyOff {
+		log.Printf("  Verify Store: %s", config.VerifyMode)
+	}
 	if config.BootstrapURL != "" {
 		log.Printf("  Bootstrap URL: %s", config.BootstrapURL)
 	}
+	for _, source := range config.Sources {
+		log.Printf("  Bootstrap Source: %s", source)
+	}
+	if config.SyncWindow > 0 {
+		log.Printf("  Forward Sync Window: %d", config.SyncWindow)
+	}
+	if config.BootstrapCDN != "" {
+		log.Printf("  Bootstrap CDN: %s", config.BootstrapCDN)
+	}
+	if config.Resync > 0 {
+		log.Printf("  Resync Interval: %v", config.Resync)
+	}
+	if config.StartupWait > 0 {
+		log.Printf("  Startup Timeout: %v", config.StartupWait)
+	}
+	if len(config.DataHub.AllowedHosts) > 0 {
+		log.Printf("  DataHub Allowed Hosts: %s", strings.Join(config.DataHub.AllowedHosts, ", "))
+	}
+	if config.DataHub.AllowPrivate {
+		log.Printf("  DataHub Private Addresses: allowed")
+	}
+	for _, addr := range config.NodePeers {
+		log.Printf("  SV Node Peer: %s", addr)
+	}
+	if len(config.Checkpoints) > 0 {
+		log.Printf("  Extra Checkpoints: %d", len(config.Checkpoints))
+	}
 
 	if err := ensureHeadersExist(config.StoragePath, config.Network); err != nil {
 		log.Fatalf("Failed to initialize headers: %v", err)
 	}
 
+	opts := []chaintracks.Option{
+		chaintracks.WithBootstrapURL(config.BootstrapURL),
+		chaintracks.WithBootstrapCDN(config.BootstrapCDN),
+		chaintracks.WithUnhashedCDNFiles(config.CDNUnhashed),
+		chaintracks.WithBootstrapSources(config.Sources...),
+		chaintracks.WithForwardSync(config.SyncWindow),
+		chaintracks.WithNodePeers(config.NodePeers...),
+		chaintracks.WithResync(config.Resync),
+		chaintracks.WithDataHubPolicy(config.DataHub),
+		chaintracks.WithPeerBan(config.BanThreshold, config.BanDuration),
+		chaintracks.WithCrawlWorkers(config.CrawlWorkers),
+		chaintracks.WithHTTPClient(&http.Client{Timeout: config.HTTPTimeout}),
+		chaintracks.WithRetryPolicy(config.Retry),
+		chaintracks.WithStartupTimeout(config.StartupWait),
+		chaintracks.WithCheckpoints(config.Checkpoints...),
+		chaintracks.WithVerify(config.VerifyMode),
+	}
+
+	if config.HeaderStore == "bolt" {
+		store, err := openBoltStore(config.StoragePath, config.Network)
+		if err != nil {
+			log.Fatalf("Failed to open header database: %v", err)
+		}
+		defer store.Close()
+		opts = append(opts, chaintracks.WithHeaderStore(store))
+	}
+
 	// Create chain manager with optional bootstrap URL
 	// Bootstrap happens synchronously in the constructor before returning
-	cm, err := chaintracks.NewChainManager(config.Network, config.StoragePath, config.BootstrapURL)
+	cm, err := chaintracks.NewChainManager(config.Network, config.StoragePath, opts...)
 	if err != nil {
 		log.Fatalf("Failed to create chain manager: %v", err)
 	}
@@ -53,16 +113,20 @@ func main() {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	blockMsgChan, err := cm.Start(ctx)
-	if err != nil {
+	if _, err := cm.Start(ctx); err != nil {
 		log.Fatalf("Failed to start P2P: %v", err)
 	}
 	log.Printf("P2P listener started for network: %s", config.Network)
 
 	server := NewServer(cm)
 
+	// Serve the header files so other instances can bootstrap from this one
+	if fileStore, ok := cm.HeaderStore().(*chaintracks.FileStore); ok {
+		server.ServeCDN(fileStore, config.CDNPublicURL)
+	}
+
 	// Start broadcasting tip changes to SSE clients
-	server.StartBroadcasting(ctx, blockMsgChan)
+	server.StartBroadcasting(ctx)
 
 	// Create Fiber app
 	app := fiber.New(fiber.Config{
@@ -97,6 +161,7 @@ func main() {
 		log.Printf("  GET  http://localhost%s/v2/height - Current blockchain height", addr)
 		log.Printf("  GET  http://localhost%s/v2/tip/header - Chain tip header", addr)
 		log.Printf("  GET  http://localhost%s/v2/tip/stream - SSE stream for tip updates", addr)
+		log.Printf("  GET  http://localhost%s/cdn/%sNetBlockHeaders.json - CDN manifest for bootstrapping other instances", addr, config.Network)
 		log.Printf("Press Ctrl+C to stop")
 
 		if err := app.Listen(addr); err != nil {
@@ -158,6 +223,34 @@ func ensureHeadersExist(storagePath, network string) error {
 	return nil
 }
 
+// openBoltStore opens the bbolt header database, importing the flat header files on first use
+func openBoltStore(storagePath, network string) (*chaintracks.BoltStore, error) {
+	if err := os.MkdirAll(storagePath, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create storage directory: %w", err)
+	}
+
+	store, err := chaintracks.NewBoltStore(filepath.Join(storagePath, network+"NetBlockHeaders.db"), network)
+	if err != nil {
+		return nil, err
+	}
+
+	metadata, err := store.ReadMetadata()
+	if err != nil {
+		store.Close()
+		return nil, err
+	}
+
+	if metadata == nil {
+		log.Printf("Header database is empty, importing header files from %s...", storagePath)
+		if err := chaintracks.CopyHeaders(store, chaintracks.NewFileStore(storagePath, network)); err != nil {
+			store.Close()
+			return nil, fmt.Errorf("failed to import header files: %w", err)
+		}
+	}
+
+	return store, nil
+}
+
 // copyFile copies a file from src to dst
 func copyFile(src, dst string) error {
 	sourceFile, err := os.Open(src)
@@ -0,0 +1,58 @@
+package chaintracks
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// tempSuffix marks a file that is being written and has not been renamed into place yet
+const tempSuffix = ".tmp"
+
+// writeFileAtomic replaces path with data so that readers (and a restart after a crash)
+// see either the old or the new contents, never a partial write.
+// The data is written to a temp file, fsynced, renamed over path and the directory is fsynced.
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	tmpPath := path + tempSuffix
+
+	f, err := os.OpenFile(tmpPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return fmt.Errorf("failed to create temp file: %w", err)
+	}
+
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to write temp file: %w", err)
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to sync temp file: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to close temp file: %w", err)
+	}
+
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to rename temp file: %w", err)
+	}
+
+	return syncDir(filepath.Dir(path))
+}
+
+// syncDir fsyncs a directory so that file creations and renames in it are durable
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return fmt.Errorf("failed to open directory %s: %w", dir, err)
+	}
+	defer d.Close()
+
+	if err := d.Sync(); err != nil {
+		return fmt.Errorf("failed to sync directory %s: %w", dir, err)
+	}
+	return nil
+}
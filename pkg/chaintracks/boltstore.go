@@ -0,0 +1,326 @@
+package chaintracks
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/bsv-blockchain/go-sdk/block"
+	"github.com/bsv-blockchain/go-sdk/chainhash"
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	// headersBucket maps block hash → header record for main chain and fork headers
+	headersBucket = []byte("headers")
+	// heightBucket maps big-endian height → main chain block hash
+	heightBucket = []byte("height")
+	// forksBucket indexes headers that are (or were) off the main chain by height+hash
+	forksBucket = []byte("forks")
+)
+
+// boltLockTimeout is how long NewBoltStore waits for another process to release the database file lock
+var boltLockTimeout = 10 * time.Second
+
+// headerRecordSize is the encoded size of a header record: header, height and chainwork
+const headerRecordSize = headerSize + 4 + 32
+
+// BoltStore persists headers in an embedded bbolt database indexed by hash and by height.
+// Unlike FileStore it keeps chainwork and fork headers, so startup needs no rehashing
+// and side chains survive a restart.
+type BoltStore struct {
+	db      *bolt.DB
+	network string
+}
+
+// NewBoltStore opens (or creates) a bbolt database at path.
+// It fails if another process still holds the database open after boltLockTimeout.
+func NewBoltStore(path, network string) (*BoltStore, error) {
+	db, err := bolt.Open(path, 0644, &bolt.Options{Timeout: boltLockTimeout})
+	if errors.Is(err, bolt.ErrTimeout) {
+		return nil, fmt.Errorf("header database %s is locked by another process (waited %v): %w", path, boltLockTimeout, err)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open header database %s: %w", path, err)
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{headersBucket, heightBucket, forksBucket} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to initialize header database: %w", err)
+	}
+
+	return &BoltStore{db: db, network: network}, nil
+}
+
+// Close closes the underlying database
+func (bs *BoltStore) Close() error {
+	return bs.db.Close()
+}
+
+// heightKey encodes a height as a sortable key
+func heightKey(height uint32) []byte {
+	key := make([]byte, 4)
+	binary.BigEndian.PutUint32(key, height)
+	return key
+}
+
+// forkKey encodes height+hash so forks can be scanned in height order
+func forkKey(height uint32, hash chainhash.Hash) []byte {
+	return append(heightKey(height), hash[:]...)
+}
+
+// encodeHeaderRecord serializes a header with its height and chainwork
+func encodeHeaderRecord(header *BlockHeader) []byte {
+	record := make([]byte, headerRecordSize)
+	copy(record, header.Header.Bytes())
+	binary.BigEndian.PutUint32(record[headerSize:], header.Height)
+	header.ChainWork.FillBytes(record[headerSize+4:])
+	return record
+}
+
+// decodeHeaderRecord parses a header record stored under hash
+func decodeHeaderRecord(hash []byte, record []byte) (*BlockHeader, error) {
+	if len(record) != headerRecordSize {
+		return nil, fmt.Errorf("invalid header record size %d", len(record))
+	}
+
+	header, err := block.NewHeaderFromBytes(record[:headerSize])
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse header record: %w", err)
+	}
+
+	blockHeader := &BlockHeader{
+		Header:    header,
+		Height:    binary.BigEndian.Uint32(record[headerSize:]),
+		ChainWork: new(big.Int).SetBytes(record[headerSize+4:]),
+	}
+	copy(blockHeader.Hash[:], hash)
+	return blockHeader, nil
+}
+
+// PutBranch stores the headers, points the height index at them and discards the index above the last one.
+// Displaced main chain headers are kept as fork headers.
+func (bs *BoltStore) PutBranch(headers []*BlockHeader) error {
+	if len(headers) == 0 {
+		return nil
+	}
+
+	return bs.db.Update(func(tx *bolt.Tx) error {
+		byHash := tx.Bucket(headersBucket)
+		byHeight := tx.Bucket(heightBucket)
+		forks := tx.Bucket(forksBucket)
+
+		for _, header := range headers {
+			if err := byHash.Put(header.Hash[:], encodeHeaderRecord(header)); err != nil {
+				return fmt.Errorf("failed to store header %s: %w", header.Hash.String(), err)
+			}
+
+			key := heightKey(header.Height)
+			if err := displaceMainHeader(byHeight, forks, key, header.Height, header.Hash); err != nil {
+				return err
+			}
+			if err := byHeight.Put(key, header.Hash[:]); err != nil {
+				return fmt.Errorf("failed to index height %d: %w", header.Height, err)
+			}
+			if err := forks.Delete(forkKey(header.Height, header.Hash)); err != nil {
+				return err
+			}
+		}
+
+		return truncateHeightIndex(byHeight, forks, headers[len(headers)-1].Height)
+	})
+}
+
+// PutHeader stores a header off the main chain
+func (bs *BoltStore) PutHeader(header *BlockHeader) error {
+	return bs.db.Update(func(tx *bolt.Tx) error {
+		if err := tx.Bucket(headersBucket).Put(header.Hash[:], encodeHeaderRecord(header)); err != nil {
+			return fmt.Errorf("failed to store header %s: %w", header.Hash.String(), err)
+		}
+
+		// Headers already on the main chain are not forks
+		if mainHash := tx.Bucket(heightBucket).Get(heightKey(header.Height)); mainHash != nil && chainhash.Hash(mainHash) == header.Hash {
+			return nil
+		}
+		return tx.Bucket(forksBucket).Put(forkKey(header.Height, header.Hash), nil)
+	})
+}
+
+// TruncateAbove removes heights above height from the main chain index, keeping the headers as forks
+func (bs *BoltStore) TruncateAbove(height uint32) error {
+	return bs.db.Update(func(tx *bolt.Tx) error {
+		return truncateHeightIndex(tx.Bucket(heightBucket), tx.Bucket(forksBucket), height)
+	})
+}
+
+// displaceMainHeader moves the main chain header at key (if it differs from hash) into the fork index
+func displaceMainHeader(byHeight, forks *bolt.Bucket, key []byte, height uint32, hash chainhash.Hash) error {
+	existing := byHeight.Get(key)
+	if existing == nil || chainhash.Hash(existing) == hash {
+		return nil
+	}
+	return forks.Put(forkKey(height, chainhash.Hash(existing)), nil)
+}
+
+// truncateHeightIndex deletes height index entries above height, recording them as forks
+func truncateHeightIndex(byHeight, forks *bolt.Bucket, height uint32) error {
+	// Collect keys first; deleting while iterating a bbolt cursor can skip entries
+	var stale [][]byte
+	c := byHeight.Cursor()
+	for k, v := c.Seek(heightKey(height + 1)); k != nil; k, v = c.Next() {
+		if err := forks.Put(forkKey(binary.BigEndian.Uint32(k), chainhash.Hash(v)), nil); err != nil {
+			return err
+		}
+		stale = append(stale, append([]byte(nil), k...))
+	}
+
+	for _, k := range stale {
+		if err := byHeight.Delete(k); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// LoadRange returns main chain headers from height from to height to (inclusive), with chainwork
+func (bs *BoltStore) LoadRange(from, to uint32) ([]*BlockHeader, error) {
+	if to < from {
+		return nil, nil
+	}
+
+	result := make([]*BlockHeader, 0, to-from+1)
+	err := bs.db.View(func(tx *bolt.Tx) error {
+		byHash := tx.Bucket(headersBucket)
+		c := tx.Bucket(heightBucket).Cursor()
+
+		for k, v := c.Seek(heightKey(from)); k != nil && binary.BigEndian.Uint32(k) <= to; k, v = c.Next() {
+			header, err := decodeHeaderRecord(v, byHash.Get(v))
+			if err != nil {
+				return fmt.Errorf("height %d: %w", binary.BigEndian.Uint32(k), err)
+			}
+			result = append(result, header)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(result) != int(to-from+1) {
+		return nil, fmt.Errorf("%w: only %d of heights %d-%d are stored", ErrHeaderNotFound, len(result), from, to)
+	}
+	return result, nil
+}
+
+// LoadForkHeaders returns stored headers off the main chain at or above minHeight
+func (bs *BoltStore) LoadForkHeaders(minHeight uint32) ([]*BlockHeader, error) {
+	var result []*BlockHeader
+	err := bs.db.View(func(tx *bolt.Tx) error {
+		byHash := tx.Bucket(headersBucket)
+		byHeight := tx.Bucket(heightBucket)
+		c := tx.Bucket(forksBucket).Cursor()
+
+		for k, _ := c.Seek(heightKey(minHeight)); k != nil; k, _ = c.Next() {
+			hash := k[4:]
+			if mainHash := byHeight.Get(k[:4]); mainHash != nil && chainhash.Hash(mainHash) == chainhash.Hash(hash) {
+				continue
+			}
+
+			header, err := decodeHeaderRecord(hash, byHash.Get(hash))
+			if err != nil {
+				return err
+			}
+			result = append(result, header)
+		}
+		return nil
+	})
+	return result, err
+}
+
+// PruneForkHeaders deletes headers off the main chain below height, with their fork index entries
+func (bs *BoltStore) PruneForkHeaders(height uint32) error {
+	return bs.db.Update(func(tx *bolt.Tx) error {
+		byHash := tx.Bucket(headersBucket)
+		byHeight := tx.Bucket(heightBucket)
+		forks := tx.Bucket(forksBucket)
+
+		// Collect keys first; deleting while iterating a bbolt cursor can skip entries
+		var stale [][]byte
+		c := forks.Cursor()
+		for k, _ := c.First(); k != nil && binary.BigEndian.Uint32(k[:4]) < height; k, _ = c.Next() {
+			stale = append(stale, append([]byte(nil), k...))
+		}
+
+		for _, k := range stale {
+			hash := k[4:]
+			if mainHash := byHeight.Get(k[:4]); mainHash == nil || chainhash.Hash(mainHash) != chainhash.Hash(hash) {
+				if err := byHash.Delete(hash); err != nil {
+					return fmt.Errorf("failed to delete header %s: %w", chainhash.Hash(hash).String(), err)
+				}
+			}
+			if err := forks.Delete(k); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// ReadMetadata describes the main chain in the CDN layout, or returns nil if no headers are stored
+func (bs *BoltStore) ReadMetadata() (*CDNMetadata, error) {
+	var metadata *CDNMetadata
+	err := bs.db.View(func(tx *bolt.Tx) error {
+		byHash := tx.Bucket(headersBucket)
+		byHeight := tx.Bucket(heightBucket)
+
+		lastKey, _ := byHeight.Cursor().Last()
+		if lastKey == nil {
+			return nil
+		}
+		tipHeight := binary.BigEndian.Uint32(lastKey)
+
+		metadata = &CDNMetadata{
+			JSONFilename:   bs.network + "NetBlockHeaders.json",
+			HeadersPerFile: headersPerFile,
+		}
+
+		for fileIndex := uint32(0); fileIndex <= tipHeight/headersPerFile; fileIndex++ {
+			firstHeight := fileIndex * headersPerFile
+			lastHeight := min(tipHeight, firstHeight+headersPerFile-1)
+
+			hash := byHeight.Get(heightKey(lastHeight))
+			if hash == nil {
+				return fmt.Errorf("%w: height %d missing from index", ErrHeaderNotFound, lastHeight)
+			}
+			last, err := decodeHeaderRecord(hash, byHash.Get(hash))
+			if err != nil {
+				return fmt.Errorf("height %d: %w", lastHeight, err)
+			}
+
+			metadata.Files = append(metadata.Files, CDNFileEntry{
+				Chain:         bs.network,
+				Count:         int(lastHeight-firstHeight) + 1,
+				FileName:      fileNameForIndex(bs.network, fileIndex),
+				FirstHeight:   firstHeight,
+				LastChainWork: ChainWorkToHex(last.ChainWork),
+				LastHash:      last.Hash,
+			})
+		}
+		linkMetadataFiles(metadata)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return metadata, nil
+}
@@ -0,0 +1,303 @@
+package chaintracks
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/bsv-blockchain/go-sdk/chainhash"
+)
+
+// SourceKind identifies the API a bootstrap source serves
+type SourceKind string
+
+const (
+	// SourceTeranode is a Teranode asset API, synced by walking back from /bestblockheader
+	SourceTeranode SourceKind = "teranode"
+	// SourceChaintracks is another Chaintracks server, synced forward by height through the v2 API
+	SourceChaintracks SourceKind = "chaintracks"
+	// SourceCDN is a Chaintracks CDN serving <network>NetBlockHeaders.json and .headers files
+	SourceCDN SourceKind = "cdn"
+)
+
+// BootstrapSource is an upstream the ChainManager syncs from at startup
+type BootstrapSource struct {
+	Kind SourceKind `json:"kind"`
+	URL  string     `json:"url"`
+}
+
+// String returns the source as kind=url, the form ParseBootstrapSources accepts
+func (s BootstrapSource) String() string {
+	return string(s.Kind) + "=" + s.URL
+}
+
+// ParseBootstrapSources parses a comma-separated list of kind=url pairs, where kind is teranode, chaintracks or cdn
+func ParseBootstrapSources(s string) ([]BootstrapSource, error) {
+	var sources []BootstrapSource
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		kind, url, found := strings.Cut(entry, "=")
+		if !found || url == "" {
+			return nil, fmt.Errorf("invalid bootstrap source %q: expected kind=url", entry)
+		}
+
+		switch SourceKind(kind) {
+		case SourceTeranode, SourceChaintracks, SourceCDN:
+		default:
+			return nil, fmt.Errorf("invalid bootstrap source kind %q: expected teranode, chaintracks or cdn", kind)
+		}
+
+		sources = append(sources, BootstrapSource{Kind: SourceKind(kind), URL: url})
+	}
+	return sources, nil
+}
+
+// SourceStatus is the outcome of bootstrapping from one source
+type SourceStatus struct {
+	Source      BootstrapSource `json:"source"`
+	TipHash     chainhash.Hash  `json:"tipHash"`
+	TipHeight   uint32          `json:"tipHeight"`
+	HeightKnown bool            `json:"heightKnown"` // Teranode tips carry no height until their headers are known locally
+	Synced      bool            `json:"synced"`      // Headers were fetched from this source
+	Agrees      bool            `json:"agrees"`      // No header from this source conflicts with the selected chain
+	Ahead       bool            `json:"ahead"`       // The source's tip is not known locally, so it cannot be checked yet
+	Conflict    string          `json:"conflict,omitempty"`
+	Error       string          `json:"error,omitempty"`
+}
+
+// GetBootstrapStatus returns the per-source outcome of the last bootstrap
+func (cm *ChainManager) GetBootstrapStatus() []SourceStatus {
+	cm.syncMu.Lock()
+	defer cm.syncMu.Unlock()
+	return append([]SourceStatus(nil), cm.sourceStatus...)
+}
+
+// bootstrapSources returns the configured sources, including those set with WithBootstrapCDN and WithBootstrapURL
+func (cm *ChainManager) bootstrapSources() []BootstrapSource {
+	sources := append([]BootstrapSource(nil), cm.extraSources...)
+	if cm.bootstrapCDN != "" {
+		sources = append(sources, BootstrapSource{Kind: SourceCDN, URL: cm.bootstrapCDN})
+	}
+	if cm.bootstrapURL != "" {
+		kind := SourceTeranode
+		if cm.syncWindow > 0 {
+			kind = SourceChaintracks
+		}
+		sources = append(sources, BootstrapSource{Kind: kind, URL: cm.bootstrapURL})
+	}
+	return sources
+}
+
+// Bootstrap queries the tips of all sources and syncs from them in order of claimed height, CDNs first because
+// they are the cheapest way to import a long range. Each sync only replaces local headers with a branch of more
+// verified chainwork, so a source that lies about its tip or serves invalid headers cannot displace the chain of
+// an honest one. Afterwards every source is cross-checked against the selected chain and disagreements are flagged.
+func (cm *ChainManager) Bootstrap(ctx context.Context, sources []BootstrapSource) []SourceStatus {
+	statuses := make([]SourceStatus, len(sources))
+	var wg sync.WaitGroup
+	for i, source := range sources {
+		statuses[i].Source = source
+		wg.Add(1)
+		go func(status *SourceStatus) {
+			defer wg.Done()
+			if err := cm.fetchSourceTip(ctx, status); err != nil {
+				status.Error = err.Error()
+				log.Printf("Bootstrap source %s unavailable: %v", status.Source, err)
+			}
+		}(&statuses[i])
+	}
+	wg.Wait()
+
+	order := make([]*SourceStatus, 0, len(statuses))
+	for i := range statuses {
+		if statuses[i].Error == "" {
+			order = append(order, &statuses[i])
+			log.Printf("Bootstrap source %s tip: %s%s", statuses[i].Source, statuses[i].TipHash, heightSuffix(&statuses[i]))
+		}
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		a, b := order[i], order[j]
+		if (a.Source.Kind == SourceCDN) != (b.Source.Kind == SourceCDN) {
+			return a.Source.Kind == SourceCDN
+		}
+		if a.HeightKnown != b.HeightKnown {
+			return a.HeightKnown
+		}
+		return a.TipHeight > b.TipHeight
+	})
+
+	for _, status := range order {
+		if cm.onMainChain(status.TipHash) {
+			continue
+		}
+
+		log.Printf("Syncing from bootstrap source %s", status.Source)
+		status.Synced = true
+		if err := cm.syncFromSource(ctx, status); err != nil {
+			status.Error = err.Error()
+			log.Printf("Bootstrap sync from %s failed: %v (will try the remaining sources)", status.Source, err)
+		}
+	}
+
+	for i := range statuses {
+		status := &statuses[i]
+		if status.Error != "" && status.TipHash.IsEqual(&chainhash.Hash{}) {
+			continue
+		}
+		cm.crossCheckSource(ctx, status)
+		if status.Conflict != "" {
+			log.Printf("WARNING: bootstrap source %s disagrees with the selected chain: %s", status.Source, status.Conflict)
+		} else if status.Ahead {
+			log.Printf("Bootstrap source %s has tip %s which is not known locally, it may be ahead", status.Source, status.TipHash)
+		}
+	}
+
+	if tip := cm.GetTip(); tip != nil {
+		log.Printf("Chain tip after bootstrap: %s at height %d", tip.Hash, tip.Height)
+	}
+
+	cm.syncMu.Lock()
+	cm.sourceStatus = statuses
+	cm.syncMu.Unlock()
+	return statuses
+}
+
+// heightSuffix formats a source's tip height for logging
+func heightSuffix(status *SourceStatus) string {
+	if !status.HeightKnown {
+		return ""
+	}
+	return fmt.Sprintf(" at height %d", status.TipHeight)
+}
+
+// fetchSourceTip fills in the tip a source claims
+func (cm *ChainManager) fetchSourceTip(ctx context.Context, status *SourceStatus) error {
+	switch status.Source.Kind {
+	case SourceTeranode:
+		hash, err := cm.fetcher.fetchLatestBlock(ctx, status.Source.URL)
+		if err != nil {
+			return err
+		}
+		status.TipHash = hash
+
+	case SourceChaintracks:
+		tip, err := cm.fetcher.fetchRemoteHeader(ctx, strings.TrimSuffix(status.Source.URL, "/")+"/v2/tip/header")
+		if err != nil {
+			return fmt.Errorf("failed to fetch remote tip: %w", err)
+		}
+		status.TipHash, status.TipHeight, status.HeightKnown = tip.Hash, tip.Height, true
+
+	case SourceCDN:
+		metadata, err := cm.fetcher.fetchCDNMetadata(ctx, status.Source.URL, cm.network)
+		if err != nil {
+			return err
+		}
+		if len(metadata.Files) == 0 {
+			return fmt.Errorf("CDN manifest lists no files")
+		}
+		last := metadata.Files[len(metadata.Files)-1]
+		status.TipHash, status.TipHeight, status.HeightKnown = last.LastHash, last.FirstHeight+uint32(last.Count)-1, true
+
+	default:
+		return fmt.Errorf("unknown source kind %q", status.Source.Kind)
+	}
+	return nil
+}
+
+// syncFromSource imports the source's chain if it has more work than the local one
+func (cm *ChainManager) syncFromSource(ctx context.Context, status *SourceStatus) error {
+	switch status.Source.Kind {
+	case SourceTeranode:
+		return cm.syncFromRemoteTip(ctx, status.TipHash, status.Source.URL, cm.fetcher)
+	case SourceChaintracks:
+		return cm.SyncForward(ctx, status.Source.URL, cm.syncWindow)
+	case SourceCDN:
+		return cm.BootstrapFromCDN(ctx, status.Source.URL)
+	default:
+		return fmt.Errorf("unknown source kind %q", status.Source.Kind)
+	}
+}
+
+// onMainChain reports whether hash is a header on the local main chain
+func (cm *ChainManager) onMainChain(hash chainhash.Hash) bool {
+	header, err := cm.GetHeaderByHash(&hash)
+	if err != nil {
+		return false
+	}
+	local, err := cm.GetHeaderByHeight(header.Height)
+	return err == nil && local.Hash == hash
+}
+
+// crossCheckSource compares a source's headers with the selected chain at heights both have,
+// setting Agrees or describing the first conflict found
+func (cm *ChainManager) crossCheckSource(ctx context.Context, status *SourceStatus) {
+	if header, err := cm.GetHeaderByHash(&status.TipHash); err == nil {
+		status.TipHeight, status.HeightKnown = header.Height, true
+	}
+
+	if cm.onMainChain(status.TipHash) {
+		status.Agrees = true
+		return
+	}
+
+	tip := cm.GetTip()
+	if tip == nil {
+		return
+	}
+
+	// The source's tip is at a height the selected chain has, so it is on a different branch
+	if status.HeightKnown && status.TipHeight <= tip.Height {
+		local, _ := cm.GetHeaderByHeight(status.TipHeight)
+		status.Conflict = fmt.Sprintf("has %s at height %d, selected chain has %s", status.TipHash, status.TipHeight, local.Hash)
+		return
+	}
+
+	// The source is ahead of the selected chain; compare what it has at the local tip height
+	switch status.Source.Kind {
+	case SourceChaintracks:
+		remote, err := cm.fetcher.fetchRemoteHeader(ctx, fmt.Sprintf("%s/v2/header/height/%d", strings.TrimSuffix(status.Source.URL, "/"), tip.Height))
+		if err != nil {
+			status.Error = fmt.Sprintf("cross-check failed: %v", err)
+			return
+		}
+		if remote.Hash != tip.Hash {
+			status.Conflict = fmt.Sprintf("has %s at height %d, selected chain has %s", remote.Hash, tip.Height, tip.Hash)
+			return
+		}
+		status.Agrees = true
+
+	case SourceCDN:
+		metadata, err := cm.fetcher.fetchCDNMetadata(ctx, status.Source.URL, cm.network)
+		if err != nil {
+			status.Error = fmt.Sprintf("cross-check failed: %v", err)
+			return
+		}
+		for _, entry := range metadata.Files {
+			lastHeight := entry.FirstHeight + uint32(entry.Count) - 1
+			if entry.Count <= 0 || lastHeight > tip.Height {
+				break
+			}
+			if local, err := cm.GetHeaderByHeight(lastHeight); err == nil && local.Hash != entry.LastHash {
+				status.Conflict = fmt.Sprintf("has %s at height %d, selected chain has %s", entry.LastHash, lastHeight, local.Hash)
+				return
+			}
+		}
+		status.Agrees = true
+
+	default:
+		// A Teranode tip we hold off the selected chain is on a fork. One we do not have cannot be placed at a
+		// height; the source is most likely just ahead.
+		if _, err := cm.GetHeaderByHash(&status.TipHash); err == nil {
+			status.Conflict = fmt.Sprintf("tip %s at height %d is on a fork of the selected chain", status.TipHash, status.TipHeight)
+			return
+		}
+		status.Ahead = true
+	}
+}
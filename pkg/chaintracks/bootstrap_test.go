@@ -0,0 +1,192 @@
+package chaintracks
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/bsv-blockchain/go-sdk/block"
+	"github.com/bsv-blockchain/go-sdk/chainhash"
+)
+
+// sourceStatus returns the status reported for url
+func sourceStatus(t *testing.T, statuses []SourceStatus, url string) SourceStatus {
+	t.Helper()
+
+	for _, status := range statuses {
+		if status.Source.URL == url {
+			return status
+		}
+	}
+	t.Fatalf("No status for source %s", url)
+	return SourceStatus{}
+}
+
+func TestBootstrapChoosesChainWithMostWork(t *testing.T) {
+	cm := newTestChainManager(t)
+	genesis := cm.GetTip()
+	honest := append([]*BlockHeader{genesis}, extendChain(t, genesis, 200)...)
+
+	// A second node on a shorter fork from height 50
+	fork := mineHeader(t, honest[50], easyBits, honest[50].Timestamp+300)
+	forked := append(append(append([]*BlockHeader(nil), honest[:51]...), fork), extendChain(t, fork, 60)...)
+
+	honestNode := newFakeUpstream(t, honest)
+	forkedNode := newFakeUpstream(t, forked)
+
+	statuses := cm.Bootstrap(context.Background(), []BootstrapSource{
+		{Kind: SourceChaintracks, URL: forkedNode.URL},
+		{Kind: SourceChaintracks, URL: honestNode.URL},
+	})
+
+	if tip := cm.GetTip(); tip.Hash != honest[200].Hash {
+		t.Fatalf("Tip after bootstrap = height %d %s, want height 200 %s", tip.Height, tip.Hash, honest[200].Hash)
+	}
+
+	if status := sourceStatus(t, statuses, honestNode.URL); !status.Agrees || status.Conflict != "" || !status.Synced {
+		t.Errorf("Honest source status = %+v, want synced and agreeing", status)
+	}
+	status := sourceStatus(t, statuses, forkedNode.URL)
+	if status.Agrees || !strings.Contains(status.Conflict, "height 111") {
+		t.Errorf("Forked source status = %+v, want a conflict at height 111", status)
+	}
+	if got := cm.GetBootstrapStatus(); len(got) != 2 {
+		t.Errorf("GetBootstrapStatus() returned %d sources, want 2", len(got))
+	}
+}
+
+func TestBootstrapRejectsSourceWithInvalidHeaders(t *testing.T) {
+	cm := newTestChainManager(t)
+	genesis := cm.GetTip()
+	honest := append([]*BlockHeader{genesis}, extendChain(t, genesis, 200)...)
+
+	// A longer chain claiming far more work without doing it
+	lying := append([]*BlockHeader(nil), honest[:51]...)
+	parent := honest[50]
+	for i := 0; i < 300; i++ {
+		header := &block.Header{Version: 1, PrevHash: parent.Hash, Timestamp: parent.Timestamp + 600, Bits: 0x1d00ffff}
+		parent = &BlockHeader{Header: header, Height: parent.Height + 1, Hash: header.Hash(), ChainWork: AddWork(parent.ChainWork, header.Bits)}
+		lying = append(lying, parent)
+	}
+
+	honestNode := newFakeUpstream(t, honest)
+	lyingNode := newFakeUpstream(t, lying)
+
+	statuses := cm.Bootstrap(context.Background(), []BootstrapSource{
+		{Kind: SourceChaintracks, URL: honestNode.URL},
+		{Kind: SourceChaintracks, URL: lyingNode.URL},
+	})
+
+	if tip := cm.GetTip(); tip.Hash != honest[200].Hash {
+		t.Fatalf("Tip after bootstrap = height %d %s, want the honest tip at height 200", tip.Height, tip.Hash)
+	}
+	status := sourceStatus(t, statuses, lyingNode.URL)
+	if status.Error == "" || status.Agrees || !strings.Contains(status.Conflict, "height 200") {
+		t.Errorf("Lying source status = %+v, want a sync error and a conflict at height 200", status)
+	}
+	if cm.GetRejectedHeaderCounts()[lyingNode.URL] == 0 {
+		t.Error("No rejected headers were attributed to the lying source")
+	}
+}
+
+func TestBootstrapCombinesCDNAndTeranode(t *testing.T) {
+	cm := newTestChainManager(t)
+	genesis := cm.GetTip()
+	chain := append([]*BlockHeader{genesis}, extendChain(t, genesis, 149)...)
+
+	// The CDN lags behind the node
+	cdn := newTestCDN(t, chain[:100], 50)
+	teranode := newFakeUpstream(t, chain)
+
+	statuses := cm.Bootstrap(context.Background(), []BootstrapSource{
+		{Kind: SourceTeranode, URL: teranode.URL},
+		{Kind: SourceCDN, URL: cdn.URL},
+	})
+
+	if tip := cm.GetTip(); tip.Hash != chain[149].Hash {
+		t.Fatalf("Tip after bootstrap = height %d, want 149", tip.Height)
+	}
+	for _, status := range statuses {
+		if !status.Agrees || !status.Synced || status.Error != "" {
+			t.Errorf("Source %s status = %+v, want synced and agreeing", status.Source, status)
+		}
+	}
+	if status := sourceStatus(t, statuses, teranode.URL); !status.HeightKnown || status.TipHeight != 149 {
+		t.Errorf("Teranode tip height = %d (known %v), want 149 once its headers are imported", status.TipHeight, status.HeightKnown)
+	}
+	if got := len(cdn.downloadedFiles()); got != 2 {
+		t.Errorf("Downloaded %d CDN files, want 2", got)
+	}
+}
+
+func TestBootstrapReportsTeranodeAheadWithoutConflict(t *testing.T) {
+	cm := newTestChainManager(t)
+	genesis := cm.GetTip()
+	chain := append([]*BlockHeader{genesis}, extendChain(t, genesis, 150)...)
+	node := newFakeUpstream(t, chain[:150])
+
+	// A Teranode one block ahead that does not serve the headers of its new tip yet
+	teranode := newFakeUpstream(t, chain[:150], withAdvertisedTip(chain[150]))
+
+	statuses := cm.Bootstrap(context.Background(), []BootstrapSource{
+		{Kind: SourceTeranode, URL: teranode.URL},
+		{Kind: SourceChaintracks, URL: node.URL},
+	})
+
+	if tip := cm.GetTip(); tip.Hash != chain[149].Hash {
+		t.Fatalf("Tip after bootstrap = height %d, want 149", tip.Height)
+	}
+	if status := sourceStatus(t, statuses, teranode.URL); status.Conflict != "" || !status.Ahead || status.Agrees {
+		t.Errorf("Teranode status = %+v, want ahead without a conflict", status)
+	}
+	if status := sourceStatus(t, statuses, node.URL); !status.Agrees || status.Ahead {
+		t.Errorf("Chaintracks status = %+v, want agreeing", status)
+	}
+}
+
+func TestBootstrapFlagsUnreachableSource(t *testing.T) {
+	cm := newTestChainManager(t)
+	genesis := cm.GetTip()
+	chain := append([]*BlockHeader{genesis}, extendChain(t, genesis, 20)...)
+	node := newFakeUpstream(t, chain)
+
+	down := httptest.NewServer(http.NotFoundHandler())
+	down.Close()
+
+	statuses := cm.Bootstrap(context.Background(), []BootstrapSource{
+		{Kind: SourceTeranode, URL: down.URL},
+		{Kind: SourceChaintracks, URL: node.URL},
+	})
+
+	if tip := cm.GetTip(); tip.Hash != chain[20].Hash {
+		t.Fatalf("Tip after bootstrap = height %d, want 20", tip.Height)
+	}
+	if status := sourceStatus(t, statuses, down.URL); status.Error == "" || status.Synced || status.TipHash != (chainhash.Hash{}) {
+		t.Errorf("Unreachable source status = %+v, want an error", status)
+	}
+}
+
+func TestParseBootstrapSources(t *testing.T) {
+	sources, err := ParseBootstrapSources("teranode=http://node:8090/api/v1, chaintracks=http://peer:3011,cdn=https://cdn.example.com/headers")
+	if err != nil {
+		t.Fatalf("ParseBootstrapSources() error = %v", err)
+	}
+
+	want := []BootstrapSource{
+		{Kind: SourceTeranode, URL: "http://node:8090/api/v1"},
+		{Kind: SourceChaintracks, URL: "http://peer:3011"},
+		{Kind: SourceCDN, URL: "https://cdn.example.com/headers"},
+	}
+	if !reflect.DeepEqual(sources, want) {
+		t.Errorf("ParseBootstrapSources() = %v, want %v", sources, want)
+	}
+
+	for _, invalid := range []string{"http://node:8090", "ftp=http://node", "cdn="} {
+		if _, err := ParseBootstrapSources(invalid); err == nil {
+			t.Errorf("ParseBootstrapSources(%q) succeeded, want an error", invalid)
+		}
+	}
+}
@@ -0,0 +1,246 @@
+package chaintracks
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bsv-blockchain/go-sdk/chainhash"
+)
+
+// cdnDownloadWorkers is the number of .headers files downloaded in parallel
+const cdnDownloadWorkers = 4
+
+// BootstrapFromCDN imports headers from a Chaintracks CDN. It fetches <network>NetBlockHeaders.json from baseURL,
+// downloads the files the local chain is missing or disagrees with in parallel, and checks each file against the
+// manifest's FileHash, PrevHash and PrevChainWork, then validates and imports it in order as it arrives.
+// It stops at the first file where the local chain has at least as much work.
+// Entries without a FileHash are refused unless WithUnhashedCDNFiles is set.
+func (cm *ChainManager) BootstrapFromCDN(ctx context.Context, baseURL string) error {
+	metadata, err := cm.fetcher.fetchCDNMetadata(ctx, baseURL, cm.network)
+	if err != nil {
+		return err
+	}
+
+	files, err := cm.cdnFilesToDownload(metadata)
+	if err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		log.Printf("Local chain already has all %d CDN files from %s", len(metadata.Files), baseURL)
+		return nil
+	}
+
+	prevHash, prevWork := chainhash.Hash{}, new(big.Int)
+	if first := files[0].FirstHeight; first > 0 {
+		parent, err := cm.GetHeaderByHeight(first - 1)
+		if err != nil {
+			return fmt.Errorf("missing local parent of CDN file %s: %w", files[0].FileName, err)
+		}
+		prevHash, prevWork = parent.Hash, parent.ChainWork
+	}
+
+	startTime := time.Now()
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	slots := make(chan struct{}, cdnDownloadWorkers)
+	downloads := cm.fetcher.downloadCDNFiles(ctx, baseURL, files, slots)
+
+	// Files arrive in any order; verify and import them in order
+	pending := make(map[int]cdnDownload)
+	next := 0
+	var first, last *BlockHeader
+
+	for download := range downloads {
+		if download.err != nil {
+			return download.err
+		}
+		pending[download.index] = download
+
+		for {
+			download, ok := pending[next]
+			if !ok {
+				break
+			}
+			delete(pending, next)
+			<-slots
+
+			headers, err := cm.verifyCDNFile(&files[next], download.data, prevHash, prevWork)
+			if err != nil {
+				return err
+			}
+			next++
+			last = headers[len(headers)-1]
+			prevHash, prevWork = last.Hash, last.ChainWork
+
+			// Headers at the start of a file that the local chain already has are not re-imported
+			for len(headers) > 0 {
+				local, err := cm.GetHeaderByHeight(headers[0].Height)
+				if err != nil || local.Hash != headers[0].Hash {
+					break
+				}
+				headers = headers[1:]
+			}
+			if len(headers) == 0 {
+				continue
+			}
+
+			// Never trade local headers for a CDN chain with less work. Stop rather than hold files until the CDN
+			// chain overtakes the tip, which would keep an unbounded branch in memory; header sync settles the rest.
+			if tip := cm.GetTip(); tip != nil && tip.Height >= headers[0].Height && tip.ChainWork.Cmp(last.ChainWork) >= 0 {
+				log.Printf("Local chain has at least as much work as the CDN chain at height %d, keeping it", last.Height)
+				return nil
+			}
+
+			if err := cm.validateHeaders(headers, baseURL); err != nil {
+				return fmt.Errorf("CDN headers failed validation: %w", err)
+			}
+			if err := cm.setChainTip(headers); err != nil {
+				return fmt.Errorf("failed to set chain tip: %w", err)
+			}
+			if first == nil {
+				first = headers[0]
+			}
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if first != nil {
+		log.Printf("CDN bootstrap complete: imported heights %d-%d in %v", first.Height, last.Height, time.Since(startTime))
+	}
+	return nil
+}
+
+// cdnURL joins a CDN base URL and a file name
+func cdnURL(baseURL, fileName string) string {
+	return strings.TrimSuffix(baseURL, "/") + "/" + fileName
+}
+
+// fetchCDNMetadata downloads and parses the CDN manifest for a network
+func (f httpFetcher) fetchCDNMetadata(ctx context.Context, baseURL, network string) (*CDNMetadata, error) {
+	data, err := f.getBody(ctx, cdnURL(baseURL, network+"NetBlockHeaders.json"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch CDN metadata: %w", err)
+	}
+
+	var metadata CDNMetadata
+	if err := json.Unmarshal(data, &metadata); err != nil {
+		return nil, fmt.Errorf("failed to parse CDN metadata: %w", err)
+	}
+	return &metadata, nil
+}
+
+// cdnFilesToDownload returns the manifest entries from the first one whose last header is not on the local chain.
+// Earlier files are skipped: holding a file's last header means holding everything before it.
+func (cm *ChainManager) cdnFilesToDownload(metadata *CDNMetadata) ([]CDNFileEntry, error) {
+	nextHeight := uint32(0)
+	for i, entry := range metadata.Files {
+		if entry.Count <= 0 {
+			return nil, fmt.Errorf("CDN file %s is empty", entry.FileName)
+		}
+		if entry.FirstHeight != nextHeight {
+			return nil, fmt.Errorf("CDN file %s starts at height %d, expected %d", entry.FileName, entry.FirstHeight, nextHeight)
+		}
+		nextHeight = entry.FirstHeight + uint32(entry.Count)
+
+		local, err := cm.GetHeaderByHeight(nextHeight - 1)
+		if err != nil || local.Hash != entry.LastHash {
+			return metadata.Files[i:], nil
+		}
+	}
+	return nil, nil
+}
+
+// cdnDownload is the content of a CDN file, identified by its index in the files requested
+type cdnDownload struct {
+	index int
+	data  []byte
+	err   error
+}
+
+// downloadCDNFiles fetches the files in parallel. A download only starts once it gets a slot, and the consumer
+// frees a slot per file it imports, so at most cap(slots) files are in flight or waiting to be imported.
+// The channel is closed once every download has finished.
+func (f httpFetcher) downloadCDNFiles(ctx context.Context, baseURL string, files []CDNFileEntry, slots chan struct{}) <-chan cdnDownload {
+	// Every file holds a slot until it is imported, so sends never block
+	downloads := make(chan cdnDownload, cap(slots))
+
+	go func() {
+		var wg sync.WaitGroup
+		defer func() {
+			wg.Wait()
+			close(downloads)
+		}()
+
+		for i := range files {
+			select {
+			case slots <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				data, err := f.getBody(ctx, cdnURL(baseURL, files[i].FileName))
+				if err == nil {
+					log.Printf("Downloaded %s (%d headers)", files[i].FileName, len(data)/headerSize)
+				}
+				downloads <- cdnDownload{index: i, data: data, err: err}
+			}(i)
+		}
+	}()
+
+	return downloads
+}
+
+// verifyCDNFile checks a downloaded file against its manifest entry and against the header before it,
+// returning the headers with heights and chainwork ready to import
+func (cm *ChainManager) verifyCDNFile(entry *CDNFileEntry, data []byte, prevHash chainhash.Hash, prevWork *big.Int) ([]*BlockHeader, error) {
+	if entry.FileHash == "" && !cm.unhashedCDNFiles {
+		return nil, fmt.Errorf("%w: %s has no fileHash", ErrCDNMismatch, entry.FileName)
+	}
+	if len(data) != entry.Count*headerSize {
+		return nil, fmt.Errorf("%w: %s has %d bytes, expected %d", ErrCDNMismatch, entry.FileName, len(data), entry.Count*headerSize)
+	}
+
+	headers, err := parseHeaders(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", entry.FileName, err)
+	}
+
+	fileHeaders := make([]*BlockHeader, len(headers))
+	prev, work := prevHash, prevWork
+	for j, header := range headers {
+		if header.PrevHash != prev {
+			return nil, fmt.Errorf("%w: %s header %d does not link to %s", ErrCDNMismatch, entry.FileName, j, prev)
+		}
+
+		height := entry.FirstHeight + uint32(j)
+		blockHeader := &BlockHeader{
+			Header:    header,
+			Height:    height,
+			Hash:      header.Hash(),
+			ChainWork: chainWorkAt(work, height, header.Bits),
+		}
+
+		fileHeaders[j] = blockHeader
+		prev, work = blockHeader.Hash, blockHeader.ChainWork
+	}
+
+	if entry.FileHash != "" && entry.FileHash != computeFileHash(fileHeaders) {
+		return nil, fmt.Errorf("%w: %s fileHash does not match", ErrCDNMismatch, entry.FileName)
+	}
+	if reason := checkFileEntry(entry, fileHeaders, prevHash, prevWork, work); reason != "" {
+		return nil, fmt.Errorf("%w: %s: %s", ErrCDNMismatch, entry.FileName, reason)
+	}
+
+	return fileHeaders, nil
+}
@@ -0,0 +1,355 @@
+package chaintracks
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testCDN serves a chain as regtest CDN files and records which files were requested
+type testCDN struct {
+	*httptest.Server
+	metadata *CDNMetadata
+	files    map[string][]byte
+
+	mu        sync.Mutex
+	requested []string
+	serve     func(name string) // Called before a header file is served, if set
+}
+
+// newTestCDN splits chain into files of perFile headers with a manifest carrying file hashes.
+// Tests may edit metadata and files before the first request.
+func newTestCDN(t *testing.T, chain []*BlockHeader, perFile int) *testCDN {
+	t.Helper()
+
+	cdn := &testCDN{
+		metadata: &CDNMetadata{JSONFilename: "regtestNetBlockHeaders.json", HeadersPerFile: perFile},
+		files:    make(map[string][]byte),
+	}
+
+	for first := 0; first < len(chain); first += perFile {
+		headers := chain[first:min(first+perFile, len(chain))]
+		fileName := fmt.Sprintf("regtestNet_%d.headers", first/perFile)
+
+		var data []byte
+		for _, header := range headers {
+			data = append(data, header.Header.Bytes()...)
+		}
+		cdn.files[fileName] = data
+
+		last := headers[len(headers)-1]
+		cdn.metadata.Files = append(cdn.metadata.Files, CDNFileEntry{
+			Chain:         "regtest",
+			Count:         len(headers),
+			FileHash:      computeFileHash(headers),
+			FileName:      fileName,
+			FirstHeight:   uint32(first),
+			LastChainWork: ChainWorkToHex(last.ChainWork),
+			LastHash:      last.Hash,
+		})
+	}
+	linkMetadataFiles(cdn.metadata)
+
+	cdn.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := strings.TrimPrefix(r.URL.Path, "/")
+		cdn.mu.Lock()
+		cdn.requested = append(cdn.requested, name)
+		cdn.mu.Unlock()
+
+		if name == cdn.metadata.JSONFilename {
+			json.NewEncoder(w).Encode(cdn.metadata)
+			return
+		}
+		data, ok := cdn.files[name]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		if cdn.serve != nil {
+			cdn.serve(name)
+		}
+		w.Write(data)
+	}))
+	t.Cleanup(cdn.Close)
+	return cdn
+}
+
+// downloadedFiles returns the header files requested so far
+func (cdn *testCDN) downloadedFiles() []string {
+	cdn.mu.Lock()
+	defer cdn.mu.Unlock()
+
+	var files []string
+	for _, name := range cdn.requested {
+		if strings.HasSuffix(name, ".headers") {
+			files = append(files, name)
+		}
+	}
+	return files
+}
+
+func TestBootstrapFromCDN(t *testing.T) {
+	cm := newTestChainManager(t)
+	genesis := cm.GetTip()
+	chain := append([]*BlockHeader{genesis}, extendChain(t, genesis, 249)...)
+	cdn := newTestCDN(t, chain, 100)
+
+	if err := cm.BootstrapFromCDN(context.Background(), cdn.URL); err != nil {
+		t.Fatalf("BootstrapFromCDN() error = %v", err)
+	}
+
+	tip := cm.GetTip()
+	if tip.Hash != chain[249].Hash || tip.ChainWork.Cmp(chain[249].ChainWork) != 0 {
+		t.Fatalf("Tip after bootstrap = height %d %s, want height 249 %s", tip.Height, tip.Hash, chain[249].Hash)
+	}
+	if got := len(cdn.downloadedFiles()); got != 3 {
+		t.Errorf("Downloaded %d files, want 3", got)
+	}
+
+	// The headers were persisted through the store
+	reloaded, err := NewChainManager("regtest", cm.localStoragePath)
+	if err != nil {
+		t.Fatalf("NewChainManager() error = %v", err)
+	}
+	if reloaded.GetHeight() != 249 {
+		t.Errorf("Reloaded height = %d, want 249", reloaded.GetHeight())
+	}
+
+	// A second bootstrap has nothing left to download
+	if err := cm.BootstrapFromCDN(context.Background(), cdn.URL); err != nil {
+		t.Fatalf("BootstrapFromCDN() again error = %v", err)
+	}
+	if got := len(cdn.downloadedFiles()); got != 3 {
+		t.Errorf("Downloaded %d files after the second bootstrap, want 3", got)
+	}
+}
+
+func TestBootstrapFromCDNDownloadsOnlyMissingFiles(t *testing.T) {
+	cm := newTestChainManager(t)
+	genesis := cm.GetTip()
+	chain := append([]*BlockHeader{genesis}, extendChain(t, genesis, 249)...)
+	if err := cm.SetChainTip(chain[1:150]); err != nil {
+		t.Fatalf("SetChainTip() error = %v", err)
+	}
+	cdn := newTestCDN(t, chain, 100)
+
+	if err := cm.BootstrapFromCDN(context.Background(), cdn.URL); err != nil {
+		t.Fatalf("BootstrapFromCDN() error = %v", err)
+	}
+
+	if tip := cm.GetTip(); tip.Hash != chain[249].Hash {
+		t.Fatalf("Tip after bootstrap = height %d, want 249", tip.Height)
+	}
+	downloaded := cdn.downloadedFiles()
+	if len(downloaded) != 2 {
+		t.Fatalf("Downloaded %v, want the last two files", downloaded)
+	}
+	for _, name := range downloaded {
+		if name == "regtestNet_0.headers" {
+			t.Errorf("Downloaded %s although the local chain already has it", name)
+		}
+	}
+}
+
+func TestBootstrapFromCDNImportsFilesAsTheyArrive(t *testing.T) {
+	cm := newTestChainManager(t)
+	genesis := cm.GetTip()
+	chain := append([]*BlockHeader{genesis}, extendChain(t, genesis, 249)...)
+	cdn := newTestCDN(t, chain, 25)
+
+	var (
+		mu             sync.Mutex
+		active, most   int
+		lastFileWaited bool
+	)
+	cdn.serve = func(name string) {
+		mu.Lock()
+		active++
+		most = max(most, active)
+		mu.Unlock()
+		defer func() {
+			mu.Lock()
+			active--
+			mu.Unlock()
+		}()
+
+		// The last file is only served once everything before it has been imported
+		if name == "regtestNet_9.headers" {
+			deadline := time.Now().Add(5 * time.Second)
+			for cm.GetHeight() < 224 && time.Now().Before(deadline) {
+				time.Sleep(time.Millisecond)
+			}
+			mu.Lock()
+			lastFileWaited = cm.GetHeight() == 224
+			mu.Unlock()
+		}
+	}
+
+	if err := cm.BootstrapFromCDN(context.Background(), cdn.URL); err != nil {
+		t.Fatalf("BootstrapFromCDN() error = %v", err)
+	}
+	if tip := cm.GetTip(); tip.Hash != chain[249].Hash {
+		t.Fatalf("Tip after bootstrap = height %d, want 249", tip.Height)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if !lastFileWaited {
+		t.Error("Earlier files were not imported before the last one was downloaded")
+	}
+	if most > cdnDownloadWorkers {
+		t.Errorf("%d files downloading at once, want at most %d", most, cdnDownloadWorkers)
+	}
+}
+
+func TestBootstrapFromCDNUnhashedFiles(t *testing.T) {
+	for _, allow := range []bool{false, true} {
+		t.Run(fmt.Sprintf("allow=%v", allow), func(t *testing.T) {
+			cm := newTestChainManager(t, WithUnhashedCDNFiles(allow))
+			genesis := cm.GetTip()
+			chain := append([]*BlockHeader{genesis}, extendChain(t, genesis, 149)...)
+			cdn := newTestCDN(t, chain, 100)
+			cdn.metadata.Files[0].FileHash = ""
+
+			err := cm.BootstrapFromCDN(context.Background(), cdn.URL)
+			if allow {
+				if err != nil || cm.GetHeight() != 149 {
+					t.Errorf("BootstrapFromCDN() = %v, height %d, want the chain imported", err, cm.GetHeight())
+				}
+				return
+			}
+			if !errors.Is(err, ErrCDNMismatch) {
+				t.Errorf("BootstrapFromCDN() error = %v, want ErrCDNMismatch", err)
+			}
+			if tip := cm.GetTip(); tip.Hash != genesis.Hash {
+				t.Errorf("Tip moved to height %d from a file without a fileHash", tip.Height)
+			}
+		})
+	}
+}
+
+func TestBootstrapFromCDNRejectsMismatchedFiles(t *testing.T) {
+	// Files before the bad one are imported as they arrive; the bad one and everything after it are not
+	tests := []struct {
+		name    string
+		badFile int
+		tamper  func(cdn *testCDN, chain []*BlockHeader)
+	}{
+		{
+			name:    "file hash",
+			badFile: 1,
+			tamper: func(cdn *testCDN, _ []*BlockHeader) {
+				cdn.metadata.Files[1].FileHash = computeFileHash(nil)
+			},
+		},
+		{
+			name:    "prev hash",
+			badFile: 2,
+			tamper: func(cdn *testCDN, chain []*BlockHeader) {
+				cdn.metadata.Files[2].PrevHash = chain[150].Hash
+			},
+		},
+		{
+			name:    "prev chainwork",
+			badFile: 2,
+			tamper: func(cdn *testCDN, _ []*BlockHeader) {
+				cdn.metadata.Files[2].PrevChainWork = ChainWorkToHex(big.NewInt(1))
+			},
+		},
+		{
+			name:    "truncated file",
+			badFile: 1,
+			tamper: func(cdn *testCDN, _ []*BlockHeader) {
+				cdn.files["regtestNet_1.headers"] = cdn.files["regtestNet_1.headers"][:50*headerSize]
+			},
+		},
+		{
+			name:    "broken linkage",
+			badFile: 1,
+			tamper: func(cdn *testCDN, _ []*BlockHeader) {
+				// Swap two headers so the file no longer links, keeping the manifest's hash in sync
+				data := cdn.files["regtestNet_1.headers"]
+				a := append([]byte(nil), data[10*headerSize:11*headerSize]...)
+				copy(data[10*headerSize:], data[11*headerSize:12*headerSize])
+				copy(data[11*headerSize:], a)
+				headers, _ := parseHeaders(data)
+				blockHeaders := make([]*BlockHeader, len(headers))
+				for i, header := range headers {
+					blockHeaders[i] = &BlockHeader{Header: header}
+				}
+				cdn.metadata.Files[1].FileHash = computeFileHash(blockHeaders)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cm := newTestChainManager(t)
+			genesis := cm.GetTip()
+			chain := append([]*BlockHeader{genesis}, extendChain(t, genesis, 249)...)
+			cdn := newTestCDN(t, chain, 100)
+			tt.tamper(cdn, chain)
+
+			err := cm.BootstrapFromCDN(context.Background(), cdn.URL)
+			if !errors.Is(err, ErrCDNMismatch) {
+				t.Errorf("BootstrapFromCDN() error = %v, want ErrCDNMismatch", err)
+			}
+			want := chain[cdn.metadata.Files[tt.badFile].FirstHeight-1]
+			if tip := cm.GetTip(); tip.Hash != want.Hash {
+				t.Errorf("Tip after a rejected bootstrap = height %d, want %d", tip.Height, want.Height)
+			}
+		})
+	}
+}
+
+func TestBootstrapFromCDNKeepsChainWithMoreWork(t *testing.T) {
+	cm := newTestChainManager(t)
+	genesis := cm.GetTip()
+	local := extendChain(t, genesis, 120)
+	if err := cm.SetChainTip(local); err != nil {
+		t.Fatalf("SetChainTip() error = %v", err)
+	}
+
+	// The CDN serves a shorter fork
+	fork := append([]*BlockHeader{genesis}, mineHeader(t, genesis, easyBits, genesis.Timestamp+300))
+	fork = append(fork, extendChain(t, fork[1], 98)...)
+	cdn := newTestCDN(t, fork, 100)
+
+	if err := cm.BootstrapFromCDN(context.Background(), cdn.URL); err != nil {
+		t.Fatalf("BootstrapFromCDN() error = %v", err)
+	}
+	if tip := cm.GetTip(); tip.Hash != local[119].Hash {
+		t.Errorf("Tip = height %d %s, want the local chain tip", tip.Height, tip.Hash)
+	}
+}
+
+func TestBootstrapFromCDNStopsWhereLocalChainHasMoreWork(t *testing.T) {
+	cm := newTestChainManager(t)
+	genesis := cm.GetTip()
+	local := extendChain(t, genesis, 120)
+	if err := cm.SetChainTip(local); err != nil {
+		t.Fatalf("SetChainTip() error = %v", err)
+	}
+
+	// The CDN fork only overtakes the local chain in its last files, which are not held waiting for it
+	fork := append([]*BlockHeader{genesis}, mineHeader(t, genesis, easyBits, genesis.Timestamp+300))
+	fork = append(fork, extendChain(t, fork[1], 298)...)
+	cdn := newTestCDN(t, fork, 10)
+
+	if err := cm.BootstrapFromCDN(context.Background(), cdn.URL); err != nil {
+		t.Fatalf("BootstrapFromCDN() error = %v", err)
+	}
+	if tip := cm.GetTip(); tip.Hash != local[119].Hash {
+		t.Errorf("Tip = height %d %s, want the local chain tip", tip.Height, tip.Hash)
+	}
+	if downloaded := len(cdn.downloadedFiles()); downloaded > 1+2*cdnDownloadWorkers {
+		t.Errorf("Downloaded %d of %d files after the local chain won", downloaded, len(cdn.metadata.Files))
+	}
+}
@@ -1,35 +1,90 @@
 package chaintracks
 
 import (
+	"context"
 	"fmt"
 	"log"
+	"net/http"
 	"os"
 	"path/filepath"
 	"sync"
+	"time"
 
 	p2p "github.com/bsv-blockchain/go-p2p-message-bus"
 	"github.com/bsv-blockchain/go-sdk/chainhash"
 )
 
+// orphanRetention is how many blocks below the tip side chain headers are kept
+const orphanRetention = 100
+
 // ChainManager is the main orchestrator for chain management
 type ChainManager struct {
-	mu sync.RWMutex
+	mu       sync.RWMutex
+	importMu sync.Mutex // Serializes tip changes so the work check, in-memory update and store write happen together
 
 	byHeight []chainhash.Hash                // Main chain hashes indexed by height
 	byHash   map[chainhash.Hash]*BlockHeader // Hash → Header (all headers: main + orphans)
 	tip      *BlockHeader                    // Current chain tip
 
+	byMerkleRoot map[chainhash.Hash]uint32 // Merkle root → main chain height
+	sideHeaders  map[uint32][]*BlockHeader // Height → headers added or displaced off the main chain (see pruneOrphans)
+
 	localStoragePath string
+	store            HeaderStore // Persistence for the main chain (FileStore at localStoragePath by default)
 	network          string
+	params           *NetworkParams    // Consensus parameters for header validation (nil if unknown network)
+	bootstrapURL     string            // Optional remote node to sync from at startup
+	bootstrapCDN     string            // Optional Chaintracks CDN to bulk import headers from at startup
+	unhashedCDNFiles bool              // Accept CDN manifest entries without a FileHash
+	syncWindow       int               // Parallel batch requests for forward sync from the bootstrap URL (0 walks back from its tip)
+	extraSources     []BootstrapSource // Bootstrap sources from WithBootstrapSources
+	verifyMode       VerifyMode        // Startup integrity check of the header store
+	resyncInterval   time.Duration     // How often Start's background loop checks upstreams for missed headers (0 disables)
+	dataHubPolicy    DataHubPolicy     // Which peer-announced DataHub URLs crawl-back may fetch from
+	dataHubFetcher   httpFetcher       // Fetcher enforcing dataHubPolicy
+	httpClient       *http.Client      // Client for requests to bootstrap sources (DefaultHTTPClient if nil)
+	httpTransport    http.RoundTripper // Replaces httpClient's transport if set
+	retryPolicy      RetryPolicy       // Retries for outbound sync requests
+	fetcher          httpFetcher       // Fetcher for requests to bootstrap sources
+	startupTimeout   time.Duration     // Deadline for NewChainManager's bootstrap (0 waits as long as it takes)
+	peerBook         *peerBook         // Reputation of peers announcing blocks
+	crawlWorkers     int               // Crawl-backs that run at once (DefaultCrawlWorkers if not positive)
+	crawls           *crawlRegistry    // Crawl-backs in flight for P2P announcements with unknown parents
+
+	// Checkpoint fields (immutable after construction)
+	checkpoints       map[uint32]chainhash.Hash // Height → required main chain hash
+	checkpointHeights []uint32                  // Checkpoint heights in ascending order
+	extraCheckpoints  []Checkpoint              // Operator-supplied checkpoints from options
 
 	// P2P fields
 	p2pClient p2p.Client        // P2P client for network communication
 	msgChan   chan *BlockHeader // Channel for broadcasting tip changes to consumers
+	nodePeers []string          // SV Node peers followed over the legacy wire protocol once started
+
+	// Subscribers notified of tip changes and reorgs
+	events eventBus
+
+	// Forward sync progress and bootstrap source outcomes
+	syncMu       sync.Mutex
+	syncProgress *SyncProgress
+	sourceStatus []SourceStatus // Outcome of the last Bootstrap per source
+
+	// Tip freshness, guarded by syncMu
+	lastHeaderAt   time.Time     // When the tip last changed
+	upstreamHeight uint32        // Highest height reported by an upstream or peer
+	bestAnnounced  *announcement // Highest block announced over P2P
+	lastCheckAt    time.Time     // When Resync last ran
+	lastCheckError string
+
+	// Validation fields
+	rejectedMu      sync.Mutex
+	rejectedHeaders map[string]uint64 // Count of headers rejected by validation, keyed by source (at most maxTrackedPeers)
 }
 
-// NewChainManager creates a new ChainManager and restores from local files if present
-// If bootstrapURL is provided, it will sync from a remote teranode before returning
-func NewChainManager(network, localStoragePath string, bootstrapURL ...string) (*ChainManager, error) {
+// NewChainManager creates a new ChainManager and restores from its header store if it holds headers
+// If bootstrap sources are configured (WithBootstrapSources, WithBootstrapCDN, WithBootstrapURL), it syncs from them before returning,
+// giving up at the WithStartupTimeout deadline
+func NewChainManager(network, localStoragePath string, opts ...Option) (*ChainManager, error) {
 	// Default to ~/.chaintracks if no path provided
 	if localStoragePath == "" {
 		homeDir, err := os.UserHomeDir()
@@ -49,35 +104,72 @@ func NewChainManager(network, localStoragePath string, bootstrapURL ...string) (
 	cm := &ChainManager{
 		byHeight:         make([]chainhash.Hash, 0, 1000000),
 		byHash:           make(map[chainhash.Hash]*BlockHeader),
+		byMerkleRoot:     make(map[chainhash.Hash]uint32),
+		sideHeaders:      make(map[uint32][]*BlockHeader),
+		rejectedHeaders:  make(map[string]uint64),
+		checkpoints:      make(map[uint32]chainhash.Hash),
+		dataHubPolicy:    DefaultDataHubPolicy(),
+		retryPolicy:      DefaultRetryPolicy(),
 		network:          network,
 		localStoragePath: localStoragePath,
 	}
 
+	for _, opt := range opts {
+		opt(cm)
+	}
+	if cm.httpClient == nil {
+		cm.httpClient = DefaultHTTPClient()
+	}
+	if cm.httpTransport != nil {
+		client := *cm.httpClient
+		client.Transport = cm.httpTransport
+		cm.httpClient = &client
+	}
+	cm.fetcher = httpFetcher{client: cm.httpClient, retry: cm.retryPolicy}
+	cm.dataHubFetcher = httpFetcher{client: cm.dataHubPolicy.Client(), retry: cm.retryPolicy}
+	if cm.peerBook == nil {
+		cm.peerBook = newPeerBook(DefaultPeerBanThreshold, DefaultPeerBanDuration)
+	}
+	cm.crawls = newCrawlRegistry(cm.crawlWorkers)
+
 	log.Printf("ChainManager initializing: network=%s, path=%s", network, localStoragePath)
 
-	// Auto-restore from local files if they exist
-	if err := cm.loadFromLocalFiles(); err != nil {
-		return nil, fmt.Errorf("failed to load checkpoint files: %w", err)
+	if params, ok := GetNetworkParams(network); ok {
+		cm.params = params
+		cm.addCheckpoints(params.Checkpoints)
+	} else {
+		log.Printf("No consensus parameters for network %s, difficulty validation disabled", network)
+	}
+	cm.addCheckpoints(cm.extraCheckpoints)
+	if len(cm.checkpointHeights) > 0 {
+		log.Printf("Loaded %d checkpoints (last at height %d)", len(cm.checkpointHeights), cm.checkpointHeights[len(cm.checkpointHeights)-1])
 	}
 
-	// Run bootstrap sync if configured (optional parameter)
-	if len(bootstrapURL) > 0 && bootstrapURL[0] != "" {
-		log.Printf("Bootstrap URL configured: %s", bootstrapURL[0])
+	if cm.store == nil {
+		cm.store = NewFileStore(localStoragePath, network)
+	}
+	sources := cm.bootstrapSources()
+	if cm.verifyMode == VerifyRedownload && len(sources) == 0 {
+		return nil, fmt.Errorf("verify mode %s requires a bootstrap source", cm.verifyMode)
+	}
 
-		// Get the latest block hash from the bootstrap node
-		remoteTipHash, err := FetchLatestBlock(bootstrapURL[0])
-		if err != nil {
-			log.Printf("Failed to get bootstrap node tip: %v (will continue with P2P sync)", err)
-		} else {
-			log.Printf("Bootstrap node tip: %s", remoteTipHash.String())
-			if err := cm.SyncFromRemoteTip(remoteTipHash, bootstrapURL[0]); err != nil {
-				log.Printf("Bootstrap sync failed: %v (will continue with P2P sync)", err)
-			}
+	// Auto-restore from the store if it holds headers
+	if err := cm.loadFromStore(); err != nil {
+		return nil, fmt.Errorf("failed to load checkpoint files: %w", err)
+	}
+
+	if len(sources) > 0 {
+		ctx := context.Background()
+		if cm.startupTimeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, cm.startupTimeout)
+			defer cancel()
 		}
 
-		// Log updated chain state after bootstrap
-		if tip := cm.GetTip(); tip != nil {
-			log.Printf("Chain tip after bootstrap: %s at height %d", tip.Header.Hash().String(), tip.Height)
+		log.Printf("Bootstrapping from %d source(s)", len(sources))
+		cm.Bootstrap(ctx, sources)
+		if ctx.Err() != nil {
+			log.Printf("WARNING: bootstrap stopped at the startup deadline of %v, continuing at height %d", cm.startupTimeout, cm.GetHeight())
 		}
 	}
 
@@ -102,6 +194,29 @@ func (cm *ChainManager) GetHeaderByHeight(height uint32) (*BlockHeader, error) {
 	return header, nil
 }
 
+// GetHeadersByHeight retrieves up to count consecutive main chain headers starting at height, stopping at the tip.
+// The headers come from one view of the chain, so they link even if a reorg happens during the call.
+func (cm *ChainManager) GetHeadersByHeight(height, count uint32) []*BlockHeader {
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
+
+	if height >= uint32(len(cm.byHeight)) {
+		return nil
+	}
+
+	end := min(uint64(height)+uint64(count), uint64(len(cm.byHeight)))
+	headers := make([]*BlockHeader, 0, end-uint64(height))
+	for h := uint64(height); h < end; h++ {
+		header, ok := cm.byHash[cm.byHeight[h]]
+		if !ok {
+			break
+		}
+		headers = append(headers, header)
+	}
+
+	return headers
+}
+
 // GetHeaderByHash retrieves a header by hash
 func (cm *ChainManager) GetHeaderByHash(hash *chainhash.Hash) (*BlockHeader, error) {
 	cm.mu.RLock()
@@ -115,6 +230,44 @@ func (cm *ChainManager) GetHeaderByHash(hash *chainhash.Hash) (*BlockHeader, err
 	return header, nil
 }
 
+// GetHeaderByMerkleRoot retrieves the main chain header with a merkle root
+func (cm *ChainManager) GetHeaderByMerkleRoot(root *chainhash.Hash) (*BlockHeader, error) {
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
+
+	height, ok := cm.byMerkleRoot[*root]
+	if !ok {
+		return nil, ErrHeaderNotFound
+	}
+
+	header, ok := cm.byHash[cm.byHeight[height]]
+	if !ok {
+		return nil, ErrHeaderNotFound
+	}
+
+	return header, nil
+}
+
+// indexMerkleRoot records the merkle root of the main chain header at its height (must be called with lock held).
+// Where two main chain blocks share a root, as the duplicate coinbase blocks of BIP 30 do, the lower one is kept.
+func (cm *ChainManager) indexMerkleRoot(header *BlockHeader) {
+	if _, ok := cm.byMerkleRoot[header.MerkleRoot]; !ok {
+		cm.byMerkleRoot[header.MerkleRoot] = header.Height
+	}
+}
+
+// unindexMerkleRoot forgets the merkle root of the main chain header at height before it is replaced or removed
+// (must be called with lock held)
+func (cm *ChainManager) unindexMerkleRoot(height uint32) {
+	header, ok := cm.byHash[cm.byHeight[height]]
+	if !ok {
+		return
+	}
+	if indexed, ok := cm.byMerkleRoot[header.MerkleRoot]; ok && indexed == height {
+		delete(cm.byMerkleRoot, header.MerkleRoot)
+	}
+}
+
 // GetTip returns the current chain tip
 func (cm *ChainManager) GetTip() *BlockHeader {
 	cm.mu.RLock()
@@ -135,9 +288,16 @@ func (cm *ChainManager) GetHeight() uint32 {
 // AddHeader adds a header to byHash for lookups without modifying the chain tip
 func (cm *ChainManager) AddHeader(header *BlockHeader) error {
 	cm.mu.Lock()
-	defer cm.mu.Unlock()
-
 	cm.byHash[header.Hash] = header
+	cm.indexSideHeader(header)
+	cm.mu.Unlock()
+
+	// Persist the header if the store keeps fork history
+	if forkStore, ok := cm.store.(ForkStore); ok {
+		if err := forkStore.PutHeader(header); err != nil {
+			return fmt.Errorf("failed to store header: %w", err)
+		}
+	}
 
 	return nil
 }
@@ -147,26 +307,56 @@ func (cm *ChainManager) GetNetwork() (string, error) {
 	return cm.network, nil
 }
 
+// HeaderStore returns the store the main chain is persisted to
+func (cm *ChainManager) HeaderStore() HeaderStore {
+	return cm.store
+}
+
+// orphanHorizon returns the height below which side chain headers are pruned when the tip is at tipHeight
+func orphanHorizon(tipHeight uint32) uint32 {
+	if tipHeight > orphanRetention {
+		return tipHeight - orphanRetention
+	}
+	return 0
+}
+
 // pruneOrphans removes old orphaned headers (must be called with lock held)
 func (cm *ChainManager) pruneOrphans() {
 	if cm.tip == nil {
 		return
 	}
 
-	pruneHeight := uint32(0)
-	if cm.tip.Height > 100 {
-		pruneHeight = cm.tip.Height - 100
-	}
+	pruneHeight := orphanHorizon(cm.tip.Height)
 
-	// Remove headers that are not in byHeight (orphans) and too old
-	for hash, header := range cm.byHash {
-		// Check if it's in the main chain
-		if header.Height < uint32(len(cm.byHeight)) && cm.byHeight[header.Height] == hash {
+	// Every header off the main chain is in sideHeaders, so only those need checking
+	for height, headers := range cm.sideHeaders {
+		if height >= pruneHeight {
 			continue
 		}
-		// It's an orphan, check if too old
-		if header.Height < pruneHeight {
-			delete(cm.byHash, hash)
+		for _, header := range headers {
+			if header.Height < uint32(len(cm.byHeight)) && cm.byHeight[header.Height] == header.Hash {
+				continue
+			}
+			delete(cm.byHash, header.Hash)
+		}
+		delete(cm.sideHeaders, height)
+	}
+}
+
+// displaceHeader indexes the main chain header at height as a side chain header before it is replaced or removed
+// (must be called with lock held)
+func (cm *ChainManager) displaceHeader(height uint32) {
+	if header, ok := cm.byHash[cm.byHeight[height]]; ok {
+		cm.indexSideHeader(header)
+	}
+}
+
+// indexSideHeader records a header that is, or may end up, off the main chain (must be called with lock held)
+func (cm *ChainManager) indexSideHeader(header *BlockHeader) {
+	for _, indexed := range cm.sideHeaders[header.Height] {
+		if indexed.Hash == header.Hash {
+			return
 		}
 	}
+	cm.sideHeaders[header.Height] = append(cm.sideHeaders[header.Height], header)
 }
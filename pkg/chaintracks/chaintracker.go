@@ -19,8 +19,103 @@ func (cm *ChainManager) IsValidRootForHeight(ctx context.Context, root *chainhas
 	return header.MerkleRoot.IsEqual(root), nil
 }
 
+// IsValidRootsForHeights validates a batch of merkle roots against the main chain in one pass.
+// Heights beyond the tip are reported as RootUnknown rather than RootInvalid.
+func (cm *ChainManager) IsValidRootsForHeights(ctx context.Context, checks []RootCheck) ([]RootResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
+
+	results := make([]RootResult, len(checks))
+	for i, check := range checks {
+		results[i] = RootResult{Height: check.Height, MerkleRoot: check.MerkleRoot, Result: cm.rootValidity(&check.MerkleRoot, check.Height)}
+	}
+	return results, nil
+}
+
+// ValidateRoot reports how a merkle root at a height relates to the local chain: on the main chain and how deeply
+// confirmed, in a known side chain header instead, or not yet judgeable because the height is beyond the tip
+func (cm *ChainManager) ValidateRoot(ctx context.Context, root *chainhash.Hash, height uint32) (RootStatus, error) {
+	if err := ctx.Err(); err != nil {
+		return RootStatus{}, err
+	}
+
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
+
+	status := RootStatus{Height: height, MerkleRoot: *root}
+	if cm.tip == nil || height > cm.tip.Height {
+		status.BeyondTip = true
+		if cm.tip != nil {
+			status.TipHeight = cm.tip.Height
+		}
+		return status, nil
+	}
+	status.TipHeight = cm.tip.Height
+
+	mainHash := cm.byHeight[height]
+	if header, ok := cm.byHash[mainHash]; ok && header.MerkleRoot.IsEqual(root) {
+		status.OnMainChain = true
+		status.Confirmations = cm.tip.Height - height + 1
+		status.BlockHash = &header.Hash
+		return status, nil
+	}
+
+	// Side chain headers are only kept for the last 100 blocks (see pruneOrphans)
+	for _, header := range cm.sideHeaders[height] {
+		if header.Hash != mainHash && header.MerkleRoot.IsEqual(root) {
+			status.OnFork = true
+			status.BlockHash = &header.Hash
+			break
+		}
+	}
+	return status, nil
+}
+
+// rootValidity judges a merkle root against the main chain header at height (must be called with lock held)
+func (cm *ChainManager) rootValidity(root *chainhash.Hash, height uint32) RootValidity {
+	if height >= uint32(len(cm.byHeight)) {
+		return RootUnknown
+	}
+	header, ok := cm.byHash[cm.byHeight[height]]
+	if !ok {
+		return RootUnknown
+	}
+	if header.MerkleRoot.IsEqual(root) {
+		return RootValid
+	}
+	return RootInvalid
+}
+
 // CurrentHeight implements the ChainTracker interface
 // Returns the current height of the blockchain
 func (cm *ChainManager) CurrentHeight(ctx context.Context) (uint32, error) {
 	return cm.GetHeight(), nil
 }
+
+// GetMedianTimePast returns the median timestamp of the main chain block at height and the
+// 10 blocks before it, as used for nLockTime and relative lock-time checks
+func (cm *ChainManager) GetMedianTimePast(height uint32) (uint32, error) {
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
+
+	if height >= uint32(len(cm.byHeight)) {
+		return 0, ErrHeaderNotFound
+	}
+
+	header, ok := cm.byHash[cm.byHeight[height]]
+	if !ok {
+		return 0, ErrHeaderNotFound
+	}
+
+	cv := &chainView{cm: cm}
+	mtp, ok := cv.medianTimePast(header)
+	if !ok {
+		return 0, ErrHeaderNotFound
+	}
+
+	return mtp, nil
+}
@@ -0,0 +1,186 @@
+package chaintracks
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync/atomic"
+	"testing"
+
+	"github.com/bsv-blockchain/go-sdk/chainhash"
+)
+
+func TestIsValidRootsForHeights(t *testing.T) {
+	cm := newTestChainManager(t)
+	chain := extendChain(t, cm.GetTip(), 5)
+	if err := cm.SetChainTip(chain); err != nil {
+		t.Fatalf("SetChainTip() error = %v", err)
+	}
+
+	checks := []RootCheck{
+		{Height: 3, MerkleRoot: chain[2].MerkleRoot},
+		{Height: 4, MerkleRoot: chain[2].Hash},
+		{Height: 6, MerkleRoot: chain[4].MerkleRoot},
+		{Height: 5, MerkleRoot: chain[4].MerkleRoot},
+	}
+	results, err := cm.IsValidRootsForHeights(context.Background(), checks)
+	if err != nil {
+		t.Fatalf("IsValidRootsForHeights() error = %v", err)
+	}
+
+	want := []RootValidity{RootValid, RootInvalid, RootUnknown, RootValid}
+	for i, result := range results {
+		if result.Result != want[i] || result.Height != checks[i].Height || result.MerkleRoot != checks[i].MerkleRoot {
+			t.Errorf("Result %d = %+v, want %s for %+v", i, result, want[i], checks[i])
+		}
+	}
+}
+
+func TestClientIsValidRootsForHeights(t *testing.T) {
+	cm := newTestChainManager(t)
+	chain := extendChain(t, cm.GetTip(), 3)
+	if err := cm.SetChainTip(chain); err != nil {
+		t.Fatalf("SetChainTip() error = %v", err)
+	}
+
+	// The whole batch is answered by one request
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		if r.Method != http.MethodPost || r.URL.Path != "/v2/merkleroots/verify" {
+			http.NotFound(w, r)
+			return
+		}
+		var checks []RootCheck
+		if err := json.NewDecoder(r.Body).Decode(&checks); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		results, _ := cm.IsValidRootsForHeights(r.Context(), checks)
+		json.NewEncoder(w).Encode(map[string]any{"status": "success", "value": results})
+	}))
+	t.Cleanup(server.Close)
+
+	checks := []RootCheck{
+		{Height: 1, MerkleRoot: chain[0].MerkleRoot},
+		{Height: 2, MerkleRoot: chain[0].Hash},
+		{Height: 9, MerkleRoot: chain[2].MerkleRoot},
+	}
+	got, err := NewClient(server.URL).IsValidRootsForHeights(context.Background(), checks)
+	if err != nil {
+		t.Fatalf("IsValidRootsForHeights() error = %v", err)
+	}
+	want, _ := cm.IsValidRootsForHeights(context.Background(), checks)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Client results = %+v, want %+v", got, want)
+	}
+	if n := requests.Load(); n != 1 {
+		t.Errorf("Server received %d requests, want 1", n)
+	}
+}
+
+func TestValidateRoot(t *testing.T) {
+	cm := newTestChainManager(t)
+	chain := extendChain(t, cm.GetTip(), 3)
+	if err := cm.SetChainTip(chain); err != nil {
+		t.Fatalf("SetChainTip() error = %v", err)
+	}
+	fork := mineHeaderWithRoot(t, chain[1], chainhash.Hash{'f'})
+	if err := cm.AddHeader(fork); err != nil {
+		t.Fatalf("AddHeader() error = %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		root   chainhash.Hash
+		height uint32
+		want   RootStatus
+	}{
+		{"main chain", chain[0].MerkleRoot, 1, RootStatus{OnMainChain: true, Confirmations: 3, BlockHash: &chain[0].Hash}},
+		{"tip", chain[2].MerkleRoot, 3, RootStatus{OnMainChain: true, Confirmations: 1, BlockHash: &chain[2].Hash}},
+		{"fork", fork.MerkleRoot, 3, RootStatus{OnFork: true, BlockHash: &fork.Hash}},
+		{"mismatch", chain[0].Hash, 2, RootStatus{}},
+		{"beyond tip", chain[0].MerkleRoot, 4, RootStatus{BeyondTip: true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.want.Height, tt.want.MerkleRoot, tt.want.TipHeight = tt.height, tt.root, 3
+
+			got, err := cm.ValidateRoot(context.Background(), &tt.root, tt.height)
+			if err != nil {
+				t.Fatalf("ValidateRoot() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ValidateRoot() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateRootFindsDisplacedHeaders(t *testing.T) {
+	cm := newTestChainManager(t)
+	chain := extendChain(t, cm.GetTip(), 3)
+	if err := cm.SetChainTip(chain); err != nil {
+		t.Fatalf("SetChainTip() error = %v", err)
+	}
+
+	// A longer branch from height 1 displaces the headers at heights 2 and 3
+	branch := []*BlockHeader{mineHeaderWithRoot(t, chain[0], chainhash.Hash{'b'})}
+	branch = append(branch, extendChain(t, branch[0], 2)...)
+	if err := cm.SetChainTip(branch); err != nil {
+		t.Fatalf("SetChainTip() error = %v", err)
+	}
+
+	status, err := cm.ValidateRoot(context.Background(), &chain[1].MerkleRoot, 2)
+	if err != nil || !status.OnFork || status.OnMainChain || *status.BlockHash != chain[1].Hash {
+		t.Fatalf("ValidateRoot() of a displaced header = %+v, %v, want it on a fork", status, err)
+	}
+
+	// Once the main chain is 100 blocks past them, side chain headers are pruned with their index entries
+	if err := cm.SetChainTip(extendChain(t, branch[2], 100)); err != nil {
+		t.Fatalf("SetChainTip() error = %v", err)
+	}
+	if status, _ := cm.ValidateRoot(context.Background(), &chain[1].MerkleRoot, 2); status.OnFork {
+		t.Errorf("ValidateRoot() of a pruned header = %+v, want it forgotten", status)
+	}
+	if _, err := cm.GetHeaderByHash(&chain[1].Hash); err == nil {
+		t.Error("Pruned side chain header is still stored")
+	}
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
+	for height := range cm.sideHeaders {
+		if height < cm.tip.Height-100 {
+			t.Errorf("Side chain index still holds height %d", height)
+		}
+	}
+}
+
+func TestClientValidateRoot(t *testing.T) {
+	cm := newTestChainManager(t)
+	chain := extendChain(t, cm.GetTip(), 2)
+	if err := cm.SetChainTip(chain); err != nil {
+		t.Fatalf("SetChainTip() error = %v", err)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		want := fmt.Sprintf("/v2/merkleroot/%s/height/1", chain[0].MerkleRoot)
+		if r.URL.Path != want {
+			http.NotFound(w, r)
+			return
+		}
+		status, _ := cm.ValidateRoot(r.Context(), &chain[0].MerkleRoot, 1)
+		json.NewEncoder(w).Encode(map[string]any{"status": "success", "value": status})
+	}))
+	t.Cleanup(server.Close)
+
+	got, err := NewClient(server.URL).ValidateRoot(context.Background(), &chain[0].MerkleRoot, 1)
+	if err != nil {
+		t.Fatalf("ValidateRoot() error = %v", err)
+	}
+	if !got.OnMainChain || got.Confirmations != 2 || *got.BlockHash != chain[0].Hash {
+		t.Errorf("Client ValidateRoot() = %+v, want on the main chain with 2 confirmations", got)
+	}
+}
@@ -38,6 +38,39 @@ func CompactToBig(compact uint32) *big.Int {
 	return bn
 }
 
+// BigToCompact converts a big.Int to the compact representation used in block header Bits.
+// It is the inverse of CompactToBig, truncating the number to a 3-byte mantissa.
+func BigToCompact(n *big.Int) uint32 {
+	if n.Sign() == 0 {
+		return 0
+	}
+
+	// The exponent is the number of bytes needed to represent the number,
+	// and the mantissa is the three most significant of those bytes
+	var mantissa uint32
+	exponent := uint(len(n.Bytes()))
+	if exponent <= 3 {
+		mantissa = uint32(new(big.Int).Abs(n).Uint64())
+		mantissa <<= 8 * (3 - exponent)
+	} else {
+		shifted := new(big.Int).Rsh(new(big.Int).Abs(n), 8*(exponent-3))
+		mantissa = uint32(shifted.Uint64())
+	}
+
+	// The sign bit is part of the mantissa, so if it is set, shift the
+	// mantissa down a byte and bump the exponent to keep the number positive
+	if mantissa&0x00800000 != 0 {
+		mantissa >>= 8
+		exponent++
+	}
+
+	compact := uint32(exponent<<24) | mantissa
+	if n.Sign() < 0 {
+		compact |= 0x00800000
+	}
+	return compact
+}
+
 // CalculateWork calculates the work represented by a given difficulty target (bits).
 // Work is calculated as: work = 2^256 / (target + 1)
 // This gives higher work values for more difficult targets (smaller target numbers).
@@ -64,6 +97,15 @@ func AddWork(cumulativeWork *big.Int, bits uint32) *big.Int {
 	return result
 }
 
+// chainWorkAt returns the chainwork of the header at height given its parent's. Genesis counts no work, as in the
+// chainwork recorded by existing stores and manifests.
+func chainWorkAt(prevWork *big.Int, height, bits uint32) *big.Int {
+	if height == 0 {
+		return big.NewInt(0)
+	}
+	return AddWork(prevWork, bits)
+}
+
 // CompareChainWork compares two chainwork values
 // Returns:
 //   -1 if a < b
@@ -0,0 +1,128 @@
+package chaintracks
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bsv-blockchain/go-sdk/chainhash"
+)
+
+// Checkpoint pins the main chain to a known block hash at a height
+type Checkpoint struct {
+	Height uint32         `json:"height"`
+	Hash   chainhash.Hash `json:"hash"`
+}
+
+// newCheckpoint builds a checkpoint from a hex hash, panicking on invalid input (built-in tables only)
+func newCheckpoint(height uint32, hexHash string) Checkpoint {
+	hash, err := chainhash.NewHashFromHex(hexHash)
+	if err != nil {
+		panic(fmt.Sprintf("invalid checkpoint hash %s: %v", hexHash, err))
+	}
+	return Checkpoint{Height: height, Hash: *hash}
+}
+
+// ParseCheckpoints parses a comma-separated list of height:hash pairs
+func ParseCheckpoints(s string) ([]Checkpoint, error) {
+	var checkpoints []Checkpoint
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		heightStr, hashStr, found := strings.Cut(entry, ":")
+		if !found {
+			return nil, fmt.Errorf("invalid checkpoint %q: expected height:hash", entry)
+		}
+
+		height, err := strconv.ParseUint(heightStr, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid checkpoint height %q: %w", heightStr, err)
+		}
+
+		hash, err := chainhash.NewHashFromHex(hashStr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid checkpoint hash %q: %w", hashStr, err)
+		}
+
+		checkpoints = append(checkpoints, Checkpoint{Height: uint32(height), Hash: *hash})
+	}
+	return checkpoints, nil
+}
+
+// addCheckpoints merges checkpoints into the ChainManager's table.
+// Operator-supplied checkpoints override built-in ones at the same height.
+func (cm *ChainManager) addCheckpoints(checkpoints []Checkpoint) {
+	for _, cp := range checkpoints {
+		if _, exists := cm.checkpoints[cp.Height]; !exists {
+			cm.checkpointHeights = append(cm.checkpointHeights, cp.Height)
+		}
+		cm.checkpoints[cp.Height] = cp.Hash
+	}
+	sort.Slice(cm.checkpointHeights, func(i, j int) bool { return cm.checkpointHeights[i] < cm.checkpointHeights[j] })
+}
+
+// lastCheckpoint returns the highest checkpoint at or below height
+func (cm *ChainManager) lastCheckpoint(height uint32) (Checkpoint, bool) {
+	i := sort.Search(len(cm.checkpointHeights), func(i int) bool { return cm.checkpointHeights[i] > height })
+	if i == 0 {
+		return Checkpoint{}, false
+	}
+	cpHeight := cm.checkpointHeights[i-1]
+	return Checkpoint{Height: cpHeight, Hash: cm.checkpoints[cpHeight]}, true
+}
+
+// checkCheckpoint verifies that a header does not contradict a checkpoint at its height
+func (cm *ChainManager) checkCheckpoint(header *BlockHeader) error {
+	if expected, ok := cm.checkpoints[header.Height]; ok && expected != header.Hash {
+		return fmt.Errorf("%w: header %s at height %d, checkpoint is %s", ErrCheckpointMismatch, header.Hash.String(), header.Height, expected.String())
+	}
+	return nil
+}
+
+// checkForkPoint verifies that a branch whose first header is at firstHeight does not
+// replace any main chain block at or below the last checkpoint (must be called with lock held)
+func (cm *ChainManager) checkForkPoint(firstHeight uint32) error {
+	if cm.tip == nil || firstHeight == 0 {
+		return nil
+	}
+
+	cp, ok := cm.lastCheckpoint(cm.tip.Height)
+	if ok && firstHeight <= cp.Height {
+		return fmt.Errorf("%w: branch forks at height %d, below checkpoint at height %d", ErrCheckpointMismatch, firstHeight-1, cp.Height)
+	}
+	return nil
+}
+
+// walkLimit returns how many headers a walk back from a remote tip may collect without linking to the chain before
+// the branch must fork at or below the last checkpoint, and that checkpoint. Zero means no checkpoint bounds the walk.
+// The remote may be ahead of the local tip by a batch plus one block per minute since the tip was mined, ten times
+// the target rate, so catching up after downtime is not refused (must be called with lock held).
+func (cm *ChainManager) walkLimit(now time.Time) (int, Checkpoint) {
+	if cm.tip == nil {
+		return 0, Checkpoint{}
+	}
+	cp, ok := cm.lastCheckpoint(cm.tip.Height)
+	if !ok {
+		return 0, Checkpoint{}
+	}
+
+	ahead := maxHeadersPerRequest
+	if elapsed := now.Sub(time.Unix(int64(cm.tip.Timestamp), 0)); elapsed > 0 {
+		ahead += int(elapsed / time.Minute)
+	}
+	return int(cm.tip.Height-cp.Height) + ahead, cp
+}
+
+// GetCheckpoints returns all checkpoints in height order
+func (cm *ChainManager) GetCheckpoints() []Checkpoint {
+	checkpoints := make([]Checkpoint, len(cm.checkpointHeights))
+	for i, height := range cm.checkpointHeights {
+		checkpoints[i] = Checkpoint{Height: height, Hash: cm.checkpoints[height]}
+	}
+	return checkpoints
+}
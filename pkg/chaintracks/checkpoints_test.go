@@ -0,0 +1,166 @@
+package chaintracks
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/bsv-blockchain/go-sdk/block"
+	"github.com/bsv-blockchain/go-sdk/chainhash"
+)
+
+// extendChain mines count headers on top of parent with 10 minute spacing
+func extendChain(t *testing.T, parent *BlockHeader, count int) []*BlockHeader {
+	t.Helper()
+
+	headers := make([]*BlockHeader, 0, count)
+	for i := 0; i < count; i++ {
+		parent = mineHeader(t, parent, easyBits, parent.Timestamp+600)
+		headers = append(headers, parent)
+	}
+	return headers
+}
+
+func TestParseCheckpoints(t *testing.T) {
+	checkpoints, err := ParseCheckpoints("0:000000000019d6689c085ae165831e934ff763ae46a2a6c172b3f1b60a8ce26f, 11111:0000000069e244f73d78e8fd29ba2fd2ed618bd6fa2ee92559f542fdb26e7c1d")
+	if err != nil {
+		t.Fatalf("ParseCheckpoints() error = %v", err)
+	}
+
+	if len(checkpoints) != 2 {
+		t.Fatalf("Expected 2 checkpoints, got %d", len(checkpoints))
+	}
+
+	if checkpoints[1].Height != 11111 || checkpoints[1].Hash.String() != "0000000069e244f73d78e8fd29ba2fd2ed618bd6fa2ee92559f542fdb26e7c1d" {
+		t.Errorf("Unexpected checkpoint: %+v", checkpoints[1])
+	}
+
+	for _, invalid := range []string{"11111", "abc:0000", "1:nothex"} {
+		if _, err := ParseCheckpoints(invalid); err == nil {
+			t.Errorf("ParseCheckpoints(%q) should fail", invalid)
+		}
+	}
+}
+
+func TestBuiltinCheckpoints(t *testing.T) {
+	for _, params := range []*NetworkParams{&MainNetParams, &TestNetParams, &TeraTestNetParams} {
+		if len(params.Checkpoints) == 0 || params.Checkpoints[0].Height != 0 {
+			t.Errorf("%s: expected a genesis checkpoint", params.Name)
+		}
+		for i := 1; i < len(params.Checkpoints); i++ {
+			if params.Checkpoints[i].Height <= params.Checkpoints[i-1].Height {
+				t.Errorf("%s: checkpoints not in ascending order at index %d", params.Name, i)
+			}
+		}
+	}
+}
+
+func TestWithCheckpointsRejectsGenesis(t *testing.T) {
+	// Mining is deterministic, so this is the same genesis newTestChainManager produces
+	genesis := mineHeader(t, nil, easyBits, 1700000000)
+	other := mineHeader(t, nil, easyBits, 1700000001)
+
+	cm, err := NewChainManager("regtest", t.TempDir(), WithCheckpoints(Checkpoint{Height: 0, Hash: other.Hash}))
+	if err != nil {
+		t.Fatalf("Failed to create ChainManager: %v", err)
+	}
+	cm.params = &testParams
+
+	if err := cm.SetChainTip([]*BlockHeader{genesis}); !errors.Is(err, ErrCheckpointMismatch) {
+		t.Fatalf("SetChainTip() error = %v, want ErrCheckpointMismatch", err)
+	}
+
+	if err := cm.SetChainTip([]*BlockHeader{other}); err != nil {
+		t.Fatalf("SetChainTip() rejected the checkpointed genesis: %v", err)
+	}
+}
+
+func TestSetChainTipRejectsBranchBelowCheckpoint(t *testing.T) {
+	cm := newTestChainManager(t)
+	genesis := cm.GetTip()
+
+	mainChain := extendChain(t, genesis, 5)
+	if err := cm.SetChainTip(mainChain); err != nil {
+		t.Fatalf("SetChainTip() error = %v", err)
+	}
+	cm.addCheckpoints([]Checkpoint{{Height: 3, Hash: mainChain[2].Hash}})
+
+	// A heavier branch forking at height 2 would replace the checkpointed block at height 3
+	fork := extendChain(t, mainChain[1], 6)
+	if err := cm.SetChainTip(fork); !errors.Is(err, ErrCheckpointMismatch) {
+		t.Fatalf("SetChainTip() error = %v, want ErrCheckpointMismatch", err)
+	}
+	if cm.GetTip() != mainChain[4] {
+		t.Errorf("Tip changed after rejected branch")
+	}
+
+	// Branches forking above the checkpoint are still accepted
+	fork = extendChain(t, mainChain[3], 3)
+	if err := cm.SetChainTip(fork); err != nil {
+		t.Fatalf("SetChainTip() rejected a branch above the checkpoint: %v", err)
+	}
+}
+
+func TestSetChainTipRejectsCheckpointMismatch(t *testing.T) {
+	cm := newTestChainManager(t)
+	genesis := cm.GetTip()
+
+	expected := mineHeader(t, genesis, easyBits, genesis.Timestamp+600)
+	cm.addCheckpoints([]Checkpoint{{Height: 1, Hash: expected.Hash}})
+
+	wrong := mineHeader(t, genesis, easyBits, genesis.Timestamp+601)
+	if err := cm.SetChainTip([]*BlockHeader{wrong}); !errors.Is(err, ErrCheckpointMismatch) {
+		t.Fatalf("SetChainTip() error = %v, want ErrCheckpointMismatch", err)
+	}
+
+	if err := cm.SetChainTip([]*BlockHeader{expected}); err != nil {
+		t.Fatalf("SetChainTip() rejected the checkpointed header: %v", err)
+	}
+
+	if cp, ok := cm.lastCheckpoint(100); !ok || cp.Height != 1 {
+		t.Errorf("lastCheckpoint(100) = %+v, %v, expected height 1", cp, ok)
+	}
+}
+
+func TestSyncFromRemoteTipStopsBelowCheckpoint(t *testing.T) {
+	cm := newTestChainManager(t)
+	genesis := cm.GetTip()
+
+	// A recent tip, so the allowance for a remote that is ahead is a single batch
+	first := mineHeader(t, genesis, easyBits, uint32(time.Now().Unix())-1200)
+	chain := append([]*BlockHeader{first}, extendChain(t, first, 2)...)
+	if err := cm.SetChainTip(chain); err != nil {
+		t.Fatalf("SetChainTip() error = %v", err)
+	}
+	cm.addCheckpoints([]Checkpoint{{Height: 1, Hash: first.Hash}})
+
+	// An upstream that serves fabricated headers that never link to the chain
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		batch := requests.Add(1)
+		n, _ := strconv.Atoi(r.URL.Query().Get("n"))
+		for i := 0; i < n; i++ {
+			header := block.Header{Version: 1, Bits: easyBits, Nonce: uint32(i), PrevHash: chainhash.Hash{byte(batch), byte(i), byte(i >> 8)}}
+			w.Write(header.Bytes())
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	err := cm.SyncFromRemoteTip(ctx, chainhash.Hash{'x'}, server.URL)
+	if !errors.Is(err, ErrCheckpointMismatch) {
+		t.Fatalf("SyncFromRemoteTip() error = %v, want ErrCheckpointMismatch", err)
+	}
+	if n := requests.Load(); n > 2 {
+		t.Errorf("Walk made %d requests, want it to stop once it passed the checkpoint", n)
+	}
+	if cm.GetTip() != chain[2] {
+		t.Errorf("Tip changed after refused branch")
+	}
+}
@@ -2,6 +2,7 @@ package chaintracks
 
 import (
 	"bufio"
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
@@ -21,6 +22,7 @@ type Client struct {
 	tipMu      sync.RWMutex
 	msgChan    chan *BlockHeader
 	cancelFunc context.CancelFunc
+	events     eventBus
 }
 
 // NewClient creates a new HTTP client for chaintracks server
@@ -74,6 +76,7 @@ func (cc *Client) readSSE(ctx context.Context, body io.ReadCloser) {
 
 	reader := bufio.NewReader(body)
 	var lastHash *chainhash.Hash
+	var eventType string
 
 	for {
 		select {
@@ -91,6 +94,15 @@ func (cc *Client) readSSE(ctx context.Context, body io.ReadCloser) {
 		}
 
 		line = strings.TrimSpace(line)
+		if line == "" {
+			// Blank line ends the current event
+			eventType = ""
+			continue
+		}
+		if strings.HasPrefix(line, "event: ") {
+			eventType = strings.TrimPrefix(line, "event: ")
+			continue
+		}
 		if !strings.HasPrefix(line, "data: ") {
 			continue
 		}
@@ -100,6 +112,15 @@ func (cc *Client) readSSE(ctx context.Context, body io.ReadCloser) {
 			continue
 		}
 
+		if eventType == "reorg" {
+			var reorg ReorgEvent
+			if err := json.Unmarshal([]byte(data), &reorg); err != nil || len(reorg.Disconnected) == 0 {
+				continue
+			}
+			cc.events.publish(Event{Type: EventReorg, Reorg: &reorg})
+			continue
+		}
+
 		var blockHeader BlockHeader
 		if err := json.Unmarshal([]byte(data), &blockHeader); err != nil {
 			continue
@@ -115,6 +136,8 @@ func (cc *Client) readSSE(ctx context.Context, body io.ReadCloser) {
 		cc.currentTip = &blockHeader
 		cc.tipMu.Unlock()
 
+		cc.events.publish(Event{Type: EventTip, Tip: &blockHeader})
+
 		select {
 		case cc.msgChan <- &blockHeader:
 		case <-ctx.Done():
@@ -124,6 +147,19 @@ func (cc *Client) readSSE(ctx context.Context, body io.ReadCloser) {
 	}
 }
 
+// Subscribe registers a listener for tip and reorg events from the server's SSE stream.
+// It can be called any number of times; events are only delivered while the client is started.
+func (cc *Client) Subscribe(ctx context.Context, opts SubscribeOptions) (<-chan Event, func()) {
+	return cc.events.subscribe(ctx, opts)
+}
+
+// SubscribeReorgs returns a channel that receives reorg events from the server's SSE stream
+// Events are only delivered while the client is started
+func (cc *Client) SubscribeReorgs(ctx context.Context) <-chan *ReorgEvent {
+	events, unsubscribe := cc.Subscribe(ctx, SubscribeOptions{Policy: PolicyLossless, BufferSize: reorgBufferSize})
+	return reorgsOnly(ctx, events, unsubscribe)
+}
+
 // Stop closes the SSE connection
 func (cc *Client) Stop() error {
 	if cc.cancelFunc != nil {
@@ -161,6 +197,40 @@ func (cc *Client) GetHeaderByHash(hash *chainhash.Hash) (*BlockHeader, error) {
 	return cc.fetchHeader(url)
 }
 
+// GetHeaderByMerkleRoot retrieves the main chain header with a merkle root from the server
+func (cc *Client) GetHeaderByMerkleRoot(root *chainhash.Hash) (*BlockHeader, error) {
+	url := fmt.Sprintf("%s/v2/header/merkleroot/%s", cc.baseURL, root.String())
+	return cc.fetchHeader(url)
+}
+
+// GetMedianTimePast retrieves the median time past at a height from the server
+func (cc *Client) GetMedianTimePast(height uint32) (uint32, error) {
+	resp, err := cc.httpClient.Get(fmt.Sprintf("%s/v2/mediantimepast/%d", cc.baseURL, height))
+	if err != nil {
+		return 0, fmt.Errorf("failed to fetch median time past: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("server returned status %d", resp.StatusCode)
+	}
+
+	var response struct {
+		Status string  `json:"status"`
+		Value  *uint32 `json:"value"`
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return 0, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	if response.Status != "success" || response.Value == nil {
+		return 0, ErrHeaderNotFound
+	}
+
+	return *response.Value, nil
+}
+
 // fetchHeader is a helper to fetch and parse a header from the server
 func (cc *Client) fetchHeader(url string) (*BlockHeader, error) {
 	resp, err := cc.httpClient.Get(url)
@@ -198,6 +268,82 @@ func (cc *Client) IsValidRootForHeight(ctx context.Context, root *chainhash.Hash
 	return header.MerkleRoot.IsEqual(root), nil
 }
 
+// IsValidRootsForHeights validates a batch of merkle roots with a single request to the server
+func (cc *Client) IsValidRootsForHeights(ctx context.Context, checks []RootCheck) ([]RootResult, error) {
+	body, err := json.Marshal(checks)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode checks: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, cc.baseURL+"/v2/merkleroots/verify", bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := cc.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to verify merkle roots: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("server returned status %d", resp.StatusCode)
+	}
+
+	var response struct {
+		Status string       `json:"status"`
+		Value  []RootResult `json:"value"`
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	if response.Status != "success" {
+		return nil, fmt.Errorf("server returned error status")
+	}
+	if len(response.Value) != len(checks) {
+		return nil, fmt.Errorf("server returned %d results for %d checks", len(response.Value), len(checks))
+	}
+
+	return response.Value, nil
+}
+
+// ValidateRoot retrieves the status of a merkle root at a height from the server
+func (cc *Client) ValidateRoot(ctx context.Context, root *chainhash.Hash, height uint32) (RootStatus, error) {
+	url := fmt.Sprintf("%s/v2/merkleroot/%s/height/%d", cc.baseURL, root.String(), height)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return RootStatus{}, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := cc.httpClient.Do(req)
+	if err != nil {
+		return RootStatus{}, fmt.Errorf("failed to validate merkle root: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return RootStatus{}, fmt.Errorf("server returned status %d", resp.StatusCode)
+	}
+
+	var response struct {
+		Status string      `json:"status"`
+		Value  *RootStatus `json:"value"`
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return RootStatus{}, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	if response.Status != "success" || response.Value == nil {
+		return RootStatus{}, fmt.Errorf("server returned error status")
+	}
+
+	return *response.Value, nil
+}
+
 // CurrentHeight implements the ChainTracker interface
 func (cc *Client) CurrentHeight(ctx context.Context) (uint32, error) {
 	return cc.GetHeight(), nil
@@ -0,0 +1,202 @@
+package chaintracks
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+
+	"github.com/bsv-blockchain/go-sdk/block"
+	"github.com/bsv-blockchain/go-sdk/chainhash"
+)
+
+const (
+	// DefaultCrawlWorkers is how many crawl-backs run at once
+	DefaultCrawlWorkers = 4
+
+	// maxPendingCrawls bounds the crawl-backs running or waiting for a worker; block hashes come from unauthenticated messages
+	maxPendingCrawls = 64
+	// maxCrawlWaiters bounds the announcements queued behind one crawl-back
+	maxCrawlWaiters = 100
+)
+
+// announcedBlock is a block announced over P2P whose parent is unknown
+type announcedBlock struct {
+	header     *block.Header
+	height     uint32
+	dataHubURL string
+	peer       string
+}
+
+// crawlJob is a crawl-back towards one announced block, shared by every announcement of that block or its descendants
+type crawlJob struct {
+	hash       chainhash.Hash
+	height     uint32
+	dataHubURL string
+	peer       string // Announcer whose DataHub is crawled, empty for Resync
+
+	// Guarded by the registry's mu until the job leaves the registry
+	keys    []chainhash.Hash  // Hashes the job is registered under: the target and the descendants waiting on it
+	waiting []*announcedBlock // Later announcements of the target or its descendants, in arrival order
+
+	done chan struct{} // Closed once the crawl has finished and its announcements are settled
+	err  error         // Outcome of the crawl, set before done is closed
+}
+
+// wait blocks until the job is settled or ctx is cancelled, and returns the outcome of the crawl
+func (j *crawlJob) wait(ctx context.Context) error {
+	select {
+	case <-j.done:
+		return j.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// crawlRegistry tracks crawl-backs in flight, so a burst of announcements for one branch walks it back only once
+type crawlRegistry struct {
+	mu      sync.Mutex
+	jobs    map[chainhash.Hash]*crawlJob // By target hash and by the hashes of descendants waiting on the target
+	pending int                          // Jobs running or waiting for a worker
+	workers chan struct{}                // Semaphore capping the crawl-backs that run at once
+	wg      sync.WaitGroup               // Jobs not yet settled
+}
+
+// newCrawlRegistry creates a crawlRegistry running up to workers crawl-backs at once (DefaultCrawlWorkers if not positive)
+func newCrawlRegistry(workers int) *crawlRegistry {
+	if workers <= 0 {
+		workers = DefaultCrawlWorkers
+	}
+	return &crawlRegistry{
+		jobs:    make(map[chainhash.Hash]*crawlJob),
+		workers: make(chan struct{}, workers),
+	}
+}
+
+// queueAnnouncement hands a block whose parent is unknown to the crawl registry. It joins the crawl-back already in
+// flight for the block, waits behind the one fetching its parent, or starts a new one, and returns without waiting.
+func (cm *ChainManager) queueAnnouncement(ctx context.Context, b *announcedBlock) error {
+	if err := cm.dataHubPolicy.Check(b.dataHubURL); err != nil {
+		return err
+	}
+
+	hash := b.header.Hash()
+	r := cm.crawls
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if job, ok := r.jobs[hash]; ok {
+		if len(job.waiting) < maxCrawlWaiters {
+			job.waiting = append(job.waiting, b)
+		}
+		return nil
+	}
+	if job, ok := r.jobs[b.header.PrevHash]; ok && len(job.waiting) < maxCrawlWaiters {
+		log.Printf("Block %s waits for the crawl-back to %s", hash, job.hash)
+		job.waiting = append(job.waiting, b)
+		job.keys = append(job.keys, hash)
+		r.jobs[hash] = job
+		return nil
+	}
+
+	_, err := cm.startCrawl(ctx, hash, b.height, b.dataHubURL, b.peer)
+	return err
+}
+
+// crawlBack crawls back to a block through dataHubURL, or joins the crawl-back already in flight for it, and returns
+// the job without waiting for it
+func (cm *ChainManager) crawlBack(ctx context.Context, hash chainhash.Hash, height uint32, dataHubURL string) (*crawlJob, error) {
+	if err := cm.dataHubPolicy.Check(dataHubURL); err != nil {
+		return nil, err
+	}
+
+	r := cm.crawls
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if job, ok := r.jobs[hash]; ok {
+		return job, nil
+	}
+	return cm.startCrawl(ctx, hash, height, dataHubURL, "")
+}
+
+// startCrawl registers a crawl-back and runs it once a worker is free. The caller must hold the registry's mu.
+func (cm *ChainManager) startCrawl(ctx context.Context, hash chainhash.Hash, height uint32, dataHubURL, peer string) (*crawlJob, error) {
+	r := cm.crawls
+	if r.pending >= maxPendingCrawls {
+		return nil, fmt.Errorf("%w: %d pending, dropping block %s", ErrCrawlQueueFull, r.pending, hash)
+	}
+
+	job := &crawlJob{
+		hash:       hash,
+		height:     height,
+		dataHubURL: dataHubURL,
+		peer:       peer,
+		keys:       []chainhash.Hash{hash},
+		done:       make(chan struct{}),
+	}
+	r.jobs[hash] = job
+	r.pending++
+	r.wg.Add(1)
+
+	go cm.runCrawl(ctx, job)
+	return job, nil
+}
+
+// runCrawl runs a crawl-back once a worker is free, then scores its announcer and settles the announcements
+// waiting on it. Cancelling ctx abandons the crawl, whether it is running or still waiting for a worker.
+func (cm *ChainManager) runCrawl(ctx context.Context, job *crawlJob) {
+	r := cm.crawls
+	defer r.wg.Done()
+
+	select {
+	case r.workers <- struct{}{}:
+		job.err = cm.crawlBackAndMerge(ctx, job.hash, job.height, job.dataHubURL)
+		<-r.workers
+	case <-ctx.Done():
+		job.err = ctx.Err()
+	}
+
+	// Leave the registry first, so later announcements act on the outcome instead of queueing behind it
+	r.mu.Lock()
+	for _, key := range job.keys {
+		delete(r.jobs, key)
+	}
+	r.pending--
+	waiting := job.waiting
+	r.mu.Unlock()
+
+	if job.err != nil {
+		log.Printf("Crawl-back to block %s from %s failed: %v", job.hash, job.dataHubURL, job.err)
+	}
+	if job.peer != "" {
+		cm.scoreAnnouncement(job.peer, job.err)
+	}
+	for _, b := range waiting {
+		cm.settleAnnouncement(b)
+	}
+	close(job.done)
+}
+
+// settleAnnouncement imports and scores an announcement that waited on a crawl-back. If the crawl did not fetch the
+// block's parent, the announcement is dropped unscored; Resync catches up later.
+func (cm *ChainManager) settleAnnouncement(b *announcedBlock) {
+	hash := b.header.Hash()
+	var err error
+	if header, getErr := cm.GetHeaderByHash(&hash); getErr == nil {
+		if header.Height != b.height {
+			err = fmt.Errorf("%w: block %s announced at height %d by %s, chain places it at height %d",
+				ErrHeightMismatch, hash, b.height, b.peer, header.Height)
+		}
+	} else if parent, getErr := cm.GetHeaderByHash(&b.header.PrevHash); getErr == nil {
+		err = cm.addAnnouncedBlock(b.header, parent, b.height, b.peer)
+	} else {
+		log.Printf("Dropping block %s from %s, the crawl-back did not fetch its parent", hash, b.peer)
+		return
+	}
+
+	if err != nil {
+		log.Printf("Error handling block message: %v", err)
+	}
+	cm.scoreAnnouncement(b.peer, err)
+}
@@ -0,0 +1,109 @@
+package chaintracks
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestCrawlBackCoalescesAnnouncements(t *testing.T) {
+	cm := newTestChainManager(t, WithDataHubPolicy(DataHubPolicy{AllowPrivate: true}))
+	genesis := cm.GetTip()
+	chain := append([]*BlockHeader{genesis}, extendChain(t, genesis, 12)...)
+	node := newFakeUpstream(t, chain, withGate())
+
+	// Several peers announce the same block, then its descendants arrive while the crawl-back is held
+	announce := func(peer string, header *BlockHeader, height uint32) {
+		t.Helper()
+		if err := cm.handleBlockMessage(context.Background(), peer, peerBlockMessage(t, peer, header, height, node.URL)); err != nil {
+			t.Fatalf("handleBlockMessage() from %s error = %v", peer, err)
+		}
+	}
+	announce("peer-1", chain[10], 10)
+	announce("peer-2", chain[10], 10)
+	announce("peer-3", chain[10], 10)
+	announce("peer-4", chain[11], 11)
+	announce("peer-5", chain[12], 99)
+
+	node.waitForRequests(t, 1)
+	node.release()
+	cm.crawls.wg.Wait()
+
+	if n := node.requests.Load(); n != 1 {
+		t.Errorf("Teranode received %d requests, want one backward walk", n)
+	}
+	if tip := cm.GetTip(); tip.Hash != chain[11].Hash {
+		t.Errorf("Tip = height %d, want 11", tip.Height)
+	}
+
+	scores := cm.GetPeerScores()
+	for _, peer := range []string{"peer-1", "peer-2", "peer-3", "peer-4"} {
+		if score := scores[peer]; score.Good != 1 || score.Bad != 0 {
+			t.Errorf("%s score = %+v, want one good announcement", peer, score)
+		}
+	}
+	if score := scores["peer-5"]; score.LastOffense != OffenseHeightMismatch {
+		t.Errorf("peer-5 score = %+v, want a height mismatch", score)
+	}
+	if len(cm.crawls.jobs) != 0 || cm.crawls.pending != 0 {
+		t.Errorf("Registry holds %d keys and %d pending jobs after the crawl, want none", len(cm.crawls.jobs), cm.crawls.pending)
+	}
+}
+
+func TestCrawlBackCapsWorkers(t *testing.T) {
+	cm := newTestChainManager(t, WithDataHubPolicy(DataHubPolicy{AllowPrivate: true}), WithCrawlWorkers(1))
+	genesis := cm.GetTip()
+	chain := append([]*BlockHeader{genesis}, extendChain(t, genesis, 10)...)
+	node := newFakeUpstream(t, chain, withGate())
+
+	// Neither block is the parent of the other, so each needs its own crawl-back
+	for _, height := range []uint32{5, 10} {
+		if err := cm.handleBlockMessage(context.Background(), "peer-1", blockMessage(t, chain[height], height, node.URL)); err != nil {
+			t.Fatalf("handleBlockMessage() error = %v", err)
+		}
+	}
+
+	node.waitForRequests(t, 1)
+	time.Sleep(50 * time.Millisecond)
+	if n := node.requests.Load(); n != 1 {
+		t.Fatalf("Teranode received %d requests with one worker busy, want 1", n)
+	}
+
+	node.release()
+	cm.crawls.wg.Wait()
+	if n := node.maxInFlight.Load(); n != 1 {
+		t.Errorf("%d crawl-backs ran at once, want 1", n)
+	}
+	if tip := cm.GetTip(); tip.Hash != chain[10].Hash {
+		t.Errorf("Tip = height %d, want 10", tip.Height)
+	}
+}
+
+func TestCrawlBackStopsOnCancel(t *testing.T) {
+	cm := newTestChainManager(t, WithDataHubPolicy(DataHubPolicy{AllowPrivate: true}), WithCrawlWorkers(1))
+	genesis := cm.GetTip()
+	chain := append([]*BlockHeader{genesis}, extendChain(t, genesis, 10)...)
+	node := newFakeUpstream(t, chain, withGate())
+
+	// One crawl-back is running and another waits for the worker
+	ctx, cancel := context.WithCancel(context.Background())
+	for _, height := range []uint32{5, 10} {
+		if err := cm.handleBlockMessage(ctx, "peer-1", blockMessage(t, chain[height], height, node.URL)); err != nil {
+			t.Fatalf("handleBlockMessage() error = %v", err)
+		}
+	}
+	node.waitForRequests(t, 1)
+
+	cancel()
+	cm.crawls.wg.Wait()
+
+	if n := node.requests.Load(); n != 1 {
+		t.Errorf("Teranode received %d requests, want the queued crawl-back abandoned", n)
+	}
+	if tip := cm.GetTip(); tip.Hash != genesis.Hash {
+		t.Errorf("Tip = height %d, want nothing imported", tip.Height)
+	}
+	if score := cm.GetPeerScores()["peer-1"]; score.Bad != 0 {
+		t.Errorf("Peer score = %+v, want cancellation not charged to the peer", score)
+	}
+}
@@ -0,0 +1,128 @@
+package chaintracks
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// DefaultDataHubTimeout bounds each request to a peer-announced DataHub URL
+const DefaultDataHubTimeout = 30 * time.Second
+
+// DataHubPolicy decides which peer-announced DataHub URLs crawl-back may fetch headers from.
+// Block messages are unauthenticated, so without a policy any peer could make the server send requests to
+// arbitrary hosts, including services on its own network.
+type DataHubPolicy struct {
+	AllowedSchemes []string      // URL schemes that may be fetched (http and https if empty)
+	AllowedHosts   []string      // If set, only these hosts; a leading dot matches any subdomain (".example.com")
+	DeniedHosts    []string      // Hosts never fetched, matched like AllowedHosts
+	AllowPrivate   bool          // Permit loopback, private, link-local and other non-public addresses
+	Timeout        time.Duration // Per-request timeout (DefaultDataHubTimeout if zero)
+}
+
+// DefaultDataHubPolicy allows public http and https DataHubs with DefaultDataHubTimeout
+func DefaultDataHubPolicy() DataHubPolicy {
+	return DataHubPolicy{Timeout: DefaultDataHubTimeout}
+}
+
+// cgnatRange is the shared address space of RFC 6598, not covered by net.IP.IsPrivate
+var cgnatRange = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+// isPublicIP reports whether ip is a globally routable unicast address
+func isPublicIP(ip net.IP) bool {
+	return !(ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified() || cgnatRange.Contains(ip))
+}
+
+// matchHost reports whether host matches any pattern, where a leading dot matches the domain and its subdomains
+func matchHost(host string, patterns []string) bool {
+	for _, pattern := range patterns {
+		pattern = strings.ToLower(pattern)
+		if host == pattern {
+			return true
+		}
+		if strings.HasPrefix(pattern, ".") && (host == pattern[1:] || strings.HasSuffix(host, pattern)) {
+			return true
+		}
+	}
+	return false
+}
+
+// Check returns an error if rawURL may not be fetched under the policy. Hostnames are checked against the
+// private-address rule again when they are resolved, see Client.
+func (p DataHubPolicy) Check(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrDataHubDenied, err)
+	}
+
+	schemes := p.AllowedSchemes
+	if len(schemes) == 0 {
+		schemes = []string{"http", "https"}
+	}
+	allowed := false
+	for _, scheme := range schemes {
+		allowed = allowed || strings.EqualFold(scheme, u.Scheme)
+	}
+	if !allowed {
+		return fmt.Errorf("%w: scheme %q of %s", ErrDataHubDenied, u.Scheme, rawURL)
+	}
+
+	host := strings.ToLower(strings.TrimSuffix(u.Hostname(), "."))
+	if host == "" {
+		return fmt.Errorf("%w: no host in %s", ErrDataHubDenied, rawURL)
+	}
+	if matchHost(host, p.DeniedHosts) {
+		return fmt.Errorf("%w: host %s is denied", ErrDataHubDenied, host)
+	}
+	if len(p.AllowedHosts) > 0 && !matchHost(host, p.AllowedHosts) {
+		return fmt.Errorf("%w: host %s is not allowed", ErrDataHubDenied, host)
+	}
+	if ip := net.ParseIP(host); ip != nil && !p.AllowPrivate && !isPublicIP(ip) {
+		return fmt.Errorf("%w: %s is not a public address", ErrDataHubDenied, host)
+	}
+	return nil
+}
+
+// Client returns an HTTP client that applies the policy's timeout, refuses to connect to non-public addresses
+// unless AllowPrivate is set (so hostnames cannot resolve around the rule), and checks every redirect
+func (p DataHubPolicy) Client() *http.Client {
+	timeout := p.Timeout
+	if timeout <= 0 {
+		timeout = DefaultDataHubTimeout
+	}
+
+	dialer := &net.Dialer{Timeout: timeout}
+	if !p.AllowPrivate {
+		dialer.Control = func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || !isPublicIP(ip) {
+				return fmt.Errorf("%w: %s is not a public address", ErrDataHubDenied, host)
+			}
+			return nil
+		}
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil // A proxy would connect on our behalf and bypass the address check
+	transport.DialContext = dialer.DialContext
+
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: transport,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= 10 {
+				return errors.New("stopped after 10 redirects")
+			}
+			return p.Check(req.URL.String())
+		},
+	}
+}
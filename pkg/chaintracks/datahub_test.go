@@ -0,0 +1,88 @@
+package chaintracks
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDataHubPolicyCheck(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy DataHubPolicy
+		url    string
+		allow  bool
+	}{
+		{name: "public https", url: "https://teranode.example.com/api/v1", allow: true},
+		{name: "public http", url: "http://8.8.8.8:8090/api/v1", allow: true},
+		{name: "other scheme", url: "file:///etc/passwd"},
+		{name: "scheme not allowed", policy: DataHubPolicy{AllowedSchemes: []string{"https"}}, url: "http://teranode.example.com"},
+		{name: "no host", url: "http:///api/v1"},
+		{name: "loopback", url: "http://127.0.0.1:8090"},
+		{name: "loopback IPv6", url: "http://[::1]:8090"},
+		{name: "private", url: "http://10.1.2.3"},
+		{name: "link-local metadata", url: "http://169.254.169.254/latest/meta-data"},
+		{name: "shared address space", url: "http://100.64.0.1"},
+		{name: "unspecified", url: "http://0.0.0.0:3011"},
+		{name: "private allowed", policy: DataHubPolicy{AllowPrivate: true}, url: "http://10.1.2.3", allow: true},
+		{name: "allowed host", policy: DataHubPolicy{AllowedHosts: []string{"node.example.com"}}, url: "https://node.example.com", allow: true},
+		{name: "host not allowed", policy: DataHubPolicy{AllowedHosts: []string{"node.example.com"}}, url: "https://other.example.com"},
+		{name: "allowed domain", policy: DataHubPolicy{AllowedHosts: []string{".example.com"}}, url: "https://a.b.EXAMPLE.com.", allow: true},
+		{name: "allowed domain apex", policy: DataHubPolicy{AllowedHosts: []string{".example.com"}}, url: "https://example.com", allow: true},
+		{name: "lookalike domain", policy: DataHubPolicy{AllowedHosts: []string{".example.com"}}, url: "https://badexample.com"},
+		{name: "denied host", policy: DataHubPolicy{DeniedHosts: []string{".bad.example"}}, url: "https://node.bad.example"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.policy.Check(tt.url)
+			if tt.allow && err != nil {
+				t.Errorf("Check(%q) error = %v, want allowed", tt.url, err)
+			}
+			if !tt.allow && !errors.Is(err, ErrDataHubDenied) {
+				t.Errorf("Check(%q) error = %v, want ErrDataHubDenied", tt.url, err)
+			}
+		})
+	}
+}
+
+func TestDataHubClientBlocksResolvedPrivateAddresses(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	t.Cleanup(server.Close)
+
+	// A hostname passes Check, but the connection to the address it resolves to is refused
+	url := strings.Replace(server.URL, "127.0.0.1", "localhost", 1)
+	policy := DefaultDataHubPolicy()
+	if err := policy.Check(url); err != nil {
+		t.Fatalf("Check(%q) error = %v", url, err)
+	}
+	if _, err := policy.Client().Get(url); !errors.Is(err, ErrDataHubDenied) {
+		t.Errorf("Get(%q) error = %v, want ErrDataHubDenied", url, err)
+	}
+
+	policy.AllowPrivate = true
+	resp, err := policy.Client().Get(url)
+	if err != nil {
+		t.Fatalf("Get(%q) with private addresses allowed error = %v", url, err)
+	}
+	resp.Body.Close()
+}
+
+func TestDataHubClientTimeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(500 * time.Millisecond)
+	}))
+	t.Cleanup(server.Close)
+
+	policy := DataHubPolicy{AllowPrivate: true, Timeout: 50 * time.Millisecond}
+	start := time.Now()
+	if _, err := policy.Client().Get(server.URL); err == nil {
+		t.Fatal("Get() succeeded past the policy timeout")
+	}
+	if elapsed := time.Since(start); elapsed > 400*time.Millisecond {
+		t.Errorf("Get() returned after %v, want the 50ms timeout", elapsed)
+	}
+}
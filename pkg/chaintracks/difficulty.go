@@ -0,0 +1,251 @@
+package chaintracks
+
+import (
+	"math/big"
+	"sort"
+
+	"github.com/bsv-blockchain/go-sdk/chainhash"
+)
+
+const (
+	// medianTimeBlocks is the number of previous blocks used to calculate median time past
+	medianTimeBlocks = 11
+
+	// daaWindow is the number of blocks the cw-144 difficulty adjustment averages over
+	daaWindow = 144
+)
+
+// chainView resolves ancestors for contextual validation. Headers from the branch
+// being validated are consulted before headers already known to the ChainManager.
+// It reads ChainManager state directly, so cm.mu must be held while it is in use.
+type chainView struct {
+	cm      *ChainManager
+	pending map[chainhash.Hash]*BlockHeader
+}
+
+// lookup returns the header with the given hash, or nil if it is unknown
+func (cv *chainView) lookup(hash *chainhash.Hash) *BlockHeader {
+	if header, ok := cv.pending[*hash]; ok {
+		return header
+	}
+	return cv.cm.byHash[*hash]
+}
+
+// parent returns the parent of header, or nil if it is unknown
+func (cv *chainView) parent(header *BlockHeader) *BlockHeader {
+	if header.Height == 0 {
+		return nil
+	}
+	return cv.lookup(&header.PrevHash)
+}
+
+// ancestor returns the ancestor of header at the given height, or nil if it is unknown
+func (cv *chainView) ancestor(header *BlockHeader, height uint32) *BlockHeader {
+	for header != nil && header.Height > height {
+		// Once the walk reaches the main chain we can jump straight to the height
+		if header.Height < uint32(len(cv.cm.byHeight)) && cv.cm.byHeight[header.Height] == header.Hash {
+			return cv.cm.byHash[cv.cm.byHeight[height]]
+		}
+		header = cv.parent(header)
+	}
+
+	if header == nil || header.Height != height {
+		return nil
+	}
+	return header
+}
+
+// medianTimePast returns the median timestamp of header and up to 10 of its ancestors.
+// Returns false if any of the required ancestors are unknown.
+func (cv *chainView) medianTimePast(header *BlockHeader) (uint32, bool) {
+	timestamps := make([]uint32, 0, medianTimeBlocks)
+	for current := header; len(timestamps) < medianTimeBlocks; current = cv.parent(current) {
+		if current == nil {
+			return 0, false
+		}
+		timestamps = append(timestamps, current.Timestamp)
+		if current.Height == 0 {
+			break
+		}
+	}
+
+	sort.Slice(timestamps, func(i, j int) bool { return timestamps[i] < timestamps[j] })
+	return timestamps[len(timestamps)/2], true
+}
+
+// nextRequiredBits returns the Bits a child of parent with the given timestamp must carry.
+// Returns false when not enough ancestry is known to decide, in which case the check is skipped.
+func (cv *chainView) nextRequiredBits(params *NetworkParams, parent *BlockHeader, timestamp uint32) (uint32, bool) {
+	// Regtest never retargets
+	if params.NoRetargeting {
+		return parent.Bits, true
+	}
+
+	if parent.Height >= params.DAAHeight {
+		return cv.nextCashWorkRequired(params, parent, timestamp)
+	}
+
+	return cv.nextEDAWorkRequired(params, parent, timestamp)
+}
+
+// nextCashWorkRequired implements the cw-144 difficulty adjustment algorithm active since November 2017.
+// The target is derived from the work done over the last 144 blocks, using the median of three
+// timestamps at each end of the window to dampen timestamp manipulation.
+func (cv *chainView) nextCashWorkRequired(params *NetworkParams, parent *BlockHeader, timestamp uint32) (uint32, bool) {
+	// Testnet allows a minimum difficulty block after 20 minutes without one
+	if params.ReduceMinDifficulty && int64(timestamp) > int64(parent.Timestamp)+2*params.TargetSpacing {
+		return params.PowLimitBits, true
+	}
+
+	// The algorithm cannot handle the first retarget interval
+	if parent.Height < params.RetargetInterval() {
+		return 0, false
+	}
+
+	last := cv.suitableBlock(parent)
+	first := cv.suitableBlock(cv.ancestor(parent, parent.Height-daaWindow))
+	if last == nil || first == nil {
+		return 0, false
+	}
+
+	target := computeCashTarget(params, first, last)
+	if target.Cmp(params.PowLimit) > 0 {
+		return params.PowLimitBits, true
+	}
+
+	return BigToCompact(target), true
+}
+
+// suitableBlock returns the block with the median timestamp of header and its two parents
+func (cv *chainView) suitableBlock(header *BlockHeader) *BlockHeader {
+	if header == nil {
+		return nil
+	}
+
+	blocks := [3]*BlockHeader{nil, cv.parent(header), header}
+	if blocks[1] == nil {
+		return nil
+	}
+	if blocks[0] = cv.parent(blocks[1]); blocks[0] == nil {
+		return nil
+	}
+
+	// Sorting network
+	if blocks[0].Timestamp > blocks[2].Timestamp {
+		blocks[0], blocks[2] = blocks[2], blocks[0]
+	}
+	if blocks[0].Timestamp > blocks[1].Timestamp {
+		blocks[0], blocks[1] = blocks[1], blocks[0]
+	}
+	if blocks[1].Timestamp > blocks[2].Timestamp {
+		blocks[1], blocks[2] = blocks[2], blocks[1]
+	}
+
+	return blocks[1]
+}
+
+// computeCashTarget computes the target that would have produced the work between first
+// and last at the target spacing. The timespan is clamped to half and double the window.
+func computeCashTarget(params *NetworkParams, first, last *BlockHeader) *big.Int {
+	work := new(big.Int).Sub(last.ChainWork, first.ChainWork)
+	work.Mul(work, big.NewInt(params.TargetSpacing))
+
+	timespan := int64(last.Timestamp) - int64(first.Timestamp)
+	if timespan > 288*params.TargetSpacing {
+		timespan = 288 * params.TargetSpacing
+	} else if timespan < 72*params.TargetSpacing {
+		timespan = 72 * params.TargetSpacing
+	}
+	work.Div(work, big.NewInt(timespan))
+
+	if work.Sign() <= 0 {
+		return new(big.Int).Set(params.PowLimit)
+	}
+
+	// target = (2^256 - work) / work
+	target := new(big.Int).Sub(oneLsh256, work)
+	return target.Div(target, work)
+}
+
+// nextEDAWorkRequired implements the original 2016-block retarget, with the Emergency
+// Difficulty Adjustment that was active between the August 2017 fork and the DAA
+func (cv *chainView) nextEDAWorkRequired(params *NetworkParams, parent *BlockHeader, timestamp uint32) (uint32, bool) {
+	interval := params.RetargetInterval()
+	height := parent.Height + 1
+
+	// Only change once per difficulty adjustment interval
+	if height%interval == 0 {
+		first := cv.ancestor(parent, height-interval)
+		if first == nil {
+			return 0, false
+		}
+		return calculateNextWorkRequired(params, parent, first.Timestamp), true
+	}
+
+	if params.ReduceMinDifficulty {
+		// Testnet allows a minimum difficulty block after 20 minutes without one
+		if int64(timestamp) > int64(parent.Timestamp)+2*params.TargetSpacing {
+			return params.PowLimitBits, true
+		}
+
+		// Otherwise use the difficulty of the last block not mined under that rule
+		current := parent
+		for current.Height%interval != 0 && current.Bits == params.PowLimitBits {
+			if current = cv.parent(current); current == nil {
+				return 0, false
+			}
+		}
+		return current.Bits, true
+	}
+
+	// We can't go below the minimum
+	if parent.Bits == params.PowLimitBits || parent.Height < params.UAHFHeight {
+		return parent.Bits, true
+	}
+
+	// If producing the last 6 blocks took less than 12 hours, keep the same difficulty
+	if height < 7 {
+		return parent.Bits, true
+	}
+	parentMTP, ok := cv.medianTimePast(parent)
+	if !ok {
+		return 0, false
+	}
+	sixBackMTP, ok := cv.medianTimePast(cv.ancestor(parent, height-7))
+	if !ok {
+		return 0, false
+	}
+	if int64(parentMTP)-int64(sixBackMTP) < 12*60*60 {
+		return parent.Bits, true
+	}
+
+	// Otherwise raise the target by 25%, reducing difficulty by 20%
+	target := CompactToBig(parent.Bits)
+	target.Add(target, new(big.Int).Rsh(target, 2))
+	if target.Cmp(params.PowLimit) > 0 {
+		target = params.PowLimit
+	}
+
+	return BigToCompact(target), true
+}
+
+// calculateNextWorkRequired scales the parent's target by how long the last retarget
+// interval actually took, limited to a factor of four in either direction
+func calculateNextWorkRequired(params *NetworkParams, parent *BlockHeader, firstTimestamp uint32) uint32 {
+	actualTimespan := int64(parent.Timestamp) - int64(firstTimestamp)
+	if actualTimespan < params.TargetTimespan/4 {
+		actualTimespan = params.TargetTimespan / 4
+	}
+	if actualTimespan > params.TargetTimespan*4 {
+		actualTimespan = params.TargetTimespan * 4
+	}
+
+	target := CompactToBig(parent.Bits)
+	target.Mul(target, big.NewInt(actualTimespan))
+	target.Div(target, big.NewInt(params.TargetTimespan))
+	if target.Cmp(params.PowLimit) > 0 {
+		target = params.PowLimit
+	}
+
+	return BigToCompact(target)
+}
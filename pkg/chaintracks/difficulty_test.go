@@ -0,0 +1,180 @@
+package chaintracks
+
+import (
+	"math/big"
+	"os"
+	"testing"
+
+	"github.com/bsv-blockchain/go-sdk/block"
+	"github.com/bsv-blockchain/go-sdk/chainhash"
+)
+
+// buildChain links count headers onto parent with fixed bits and spacing.
+// Proof of work is not checked by nextRequiredBits, so the headers are not mined.
+func buildChain(parent *BlockHeader, count int, bits uint32, spacing uint32) []*BlockHeader {
+	headers := make([]*BlockHeader, 0, count)
+	for i := 0; i < count; i++ {
+		header := &block.Header{
+			Version:   1,
+			PrevHash:  parent.Hash,
+			Timestamp: parent.Timestamp + spacing,
+			Bits:      bits,
+		}
+		child := &BlockHeader{
+			Header:    header,
+			Height:    parent.Height + 1,
+			Hash:      header.Hash(),
+			ChainWork: AddWork(parent.ChainWork, bits),
+		}
+		headers = append(headers, child)
+		parent = child
+	}
+	return headers
+}
+
+// newPendingView returns a chainView over an empty ChainManager holding only the given headers
+func newPendingView(headers []*BlockHeader) *chainView {
+	cv := &chainView{
+		cm:      &ChainManager{byHash: make(map[chainhash.Hash]*BlockHeader)},
+		pending: make(map[chainhash.Hash]*BlockHeader, len(headers)),
+	}
+	for _, header := range headers {
+		cv.pending[header.Hash] = header
+	}
+	return cv
+}
+
+func TestBigToCompact(t *testing.T) {
+	tests := []uint32{0x1d00ffff, 0x1b0404cb, 0x207fffff, 0x181399a4, 0x03123456, 0x02008000}
+
+	for _, compact := range tests {
+		if got := BigToCompact(CompactToBig(compact)); got != compact {
+			t.Errorf("BigToCompact(CompactToBig(%08x)) = %08x", compact, got)
+		}
+	}
+
+	if got := BigToCompact(MainNetParams.PowLimit); got != 0x1d00ffff {
+		t.Errorf("BigToCompact(mainnet pow limit) = %08x, expected 1d00ffff", got)
+	}
+
+	if got := BigToCompact(big.NewInt(0)); got != 0 {
+		t.Errorf("BigToCompact(0) = %08x, expected 0", got)
+	}
+}
+
+func TestCalculateNextWorkRequired(t *testing.T) {
+	// Mainnet retarget at height 32256: blocks 30240..32255 took 1022578 seconds
+	parent := &BlockHeader{
+		Header: &block.Header{Bits: 0x1d00ffff, Timestamp: 1262152739},
+		Height: 32255,
+	}
+
+	if got := calculateNextWorkRequired(&MainNetParams, parent, 1261130161); got != 0x1d00d86a {
+		t.Errorf("calculateNextWorkRequired() = %08x, expected 1d00d86a", got)
+	}
+
+	// Retargets are limited to a factor of four
+	parent.Bits = 0x1b0404cb
+	if got := calculateNextWorkRequired(&MainNetParams, parent, parent.Timestamp-60); got != BigToCompact(new(big.Int).Rsh(CompactToBig(0x1b0404cb), 2)) {
+		t.Errorf("calculateNextWorkRequired() = %08x, expected a 4x difficulty increase", got)
+	}
+}
+
+func TestEmergencyDifficultyAdjustment(t *testing.T) {
+	params := MainNetParams
+	params.UAHFHeight = 0
+	params.DAAHeight = 1 << 31
+
+	genesis := &BlockHeader{
+		Header:    &block.Header{Bits: 0x1c0fffff, Timestamp: 1500000000},
+		ChainWork: big.NewInt(0),
+	}
+	genesis.Hash = genesis.Header.Hash()
+
+	// Blocks arriving on schedule keep the difficulty
+	onTime := append([]*BlockHeader{genesis}, buildChain(genesis, 20, 0x1c0fffff, 600)...)
+	cv := newPendingView(onTime)
+	parent := onTime[len(onTime)-1]
+	if bits, ok := cv.nextRequiredBits(&params, parent, parent.Timestamp+600); !ok || bits != 0x1c0fffff {
+		t.Errorf("nextRequiredBits() = %08x, %v, expected 1c0fffff", bits, ok)
+	}
+
+	// Six blocks taking over 12 hours reduce the difficulty by 20%
+	slow := append([]*BlockHeader{genesis}, buildChain(genesis, 20, 0x1c0fffff, 3*60*60)...)
+	cv = newPendingView(slow)
+	parent = slow[len(slow)-1]
+	target := CompactToBig(0x1c0fffff)
+	expected := BigToCompact(target.Add(target, new(big.Int).Rsh(target, 2)))
+	if bits, ok := cv.nextRequiredBits(&params, parent, parent.Timestamp+600); !ok || bits != expected {
+		t.Errorf("nextRequiredBits() = %08x, %v, expected %08x", bits, ok, expected)
+	}
+}
+
+func TestReduceMinDifficulty(t *testing.T) {
+	params := TestNetParams
+	params.UAHFHeight = 1 << 31
+	params.DAAHeight = 1 << 31
+
+	genesis := &BlockHeader{
+		Header:    &block.Header{Bits: 0x1c0fffff, Timestamp: 1500000000},
+		ChainWork: big.NewInt(0),
+	}
+	genesis.Hash = genesis.Header.Hash()
+
+	headers := append([]*BlockHeader{genesis}, buildChain(genesis, 5, 0x1c0fffff, 600)...)
+	headers = append(headers, buildChain(headers[len(headers)-1], 3, params.PowLimitBits, 1500)...)
+	cv := newPendingView(headers)
+	parent := headers[len(headers)-1]
+
+	// After 20 minutes without a block, minimum difficulty is allowed
+	if bits, ok := cv.nextRequiredBits(&params, parent, parent.Timestamp+1201); !ok || bits != params.PowLimitBits {
+		t.Errorf("nextRequiredBits() = %08x, %v, expected %08x", bits, ok, params.PowLimitBits)
+	}
+
+	// Otherwise the last difficulty not mined under that rule applies
+	if bits, ok := cv.nextRequiredBits(&params, parent, parent.Timestamp+600); !ok || bits != 0x1c0fffff {
+		t.Errorf("nextRequiredBits() = %08x, %v, expected 1c0fffff", bits, ok)
+	}
+}
+
+func TestDAAMainnetHeaders(t *testing.T) {
+	const firstHeight = 900000
+	filePath := "../../data/headers/mainNet_9.headers"
+
+	if _, err := os.Stat(filePath); os.IsNotExist(err) {
+		t.Skipf("Test file not found at %s", filePath)
+	}
+
+	raw, err := loadHeadersFromFile(filePath)
+	if err != nil {
+		t.Fatalf("Failed to load headers: %v", err)
+	}
+
+	// Chainwork is relative to the first header, which is all the DAA needs
+	headers := make([]*BlockHeader, len(raw))
+	chainWork := big.NewInt(0)
+	for i, header := range raw {
+		chainWork = AddWork(chainWork, header.Bits)
+		headers[i] = &BlockHeader{
+			Header:    header,
+			Height:    firstHeight + uint32(i),
+			Hash:      header.Hash(),
+			ChainWork: chainWork,
+		}
+	}
+
+	cv := newPendingView(headers)
+	checked := 0
+	for i := daaWindow + 3; i < len(headers); i++ {
+		bits, ok := cv.nextRequiredBits(&MainNetParams, headers[i-1], headers[i].Timestamp)
+		if !ok {
+			t.Fatalf("nextRequiredBits() could not decide at height %d", headers[i].Height)
+		}
+		if bits != headers[i].Bits {
+			t.Fatalf("Height %d: expected bits %08x, got %08x", headers[i].Height, headers[i].Bits, bits)
+		}
+		checked++
+	}
+
+	t.Logf("Verified DAA difficulty for %d mainnet headers", checked)
+}
@@ -15,9 +15,33 @@ var (
 	// ErrInsufficientPoW is returned when a header doesn't meet the difficulty target
 	ErrInsufficientPoW = errors.New("insufficient proof of work")
 
+	// ErrInvalidDifficulty is returned when a header's bits don't match the difficulty required by its ancestors
+	ErrInvalidDifficulty = errors.New("invalid difficulty")
+
 	// ErrBrokenChain is returned when a header's previous hash doesn't link to known chain
 	ErrBrokenChain = errors.New("broken chain linkage")
 
 	// ErrInvalidTimestamp is returned when a header has an invalid timestamp
 	ErrInvalidTimestamp = errors.New("invalid timestamp")
+
+	// ErrCheckpointMismatch is returned when a header or branch contradicts a checkpoint
+	ErrCheckpointMismatch = errors.New("checkpoint mismatch")
+
+	// ErrCDNMismatch is returned when a downloaded CDN file does not match its manifest entry or the chain
+	ErrCDNMismatch = errors.New("CDN file mismatch")
+
+	// ErrCorruptStore is returned when stored headers or their metadata fail integrity verification
+	ErrCorruptStore = errors.New("corrupt header store")
+
+	// ErrHeightMismatch is returned when a block announcement claims a height that disagrees with its parent
+	ErrHeightMismatch = errors.New("announced height mismatch")
+
+	// ErrDataHubDenied is returned when a peer-announced DataHub URL is refused by the DataHub policy
+	ErrDataHubDenied = errors.New("DataHub URL denied")
+
+	// ErrCrawlQueueFull is returned when too many crawl-backs are pending to start another
+	ErrCrawlQueueFull = errors.New("crawl-back queue full")
+
+	// ErrInvalidProof is returned when a merkle path or BEEF cannot be parsed or does not prove its transaction
+	ErrInvalidProof = errors.New("invalid merkle proof")
 )
@@ -0,0 +1,199 @@
+package chaintracks
+
+import (
+	"context"
+	"log"
+	"sync"
+)
+
+// EventType identifies the kind of chain event
+type EventType string
+
+const (
+	// EventTip is published whenever the chain tip changes
+	EventTip EventType = "tip"
+	// EventReorg is published before the tip event when the main chain switches branches
+	EventReorg EventType = "reorg"
+)
+
+// Event is a chain event delivered to subscribers. Exactly one of Tip or Reorg is set.
+type Event struct {
+	Type  EventType    `json:"type"`
+	Tip   *BlockHeader `json:"tip,omitempty"`
+	Reorg *ReorgEvent  `json:"reorg,omitempty"`
+}
+
+// DeliveryPolicy controls what happens when a subscriber falls behind
+type DeliveryPolicy int
+
+const (
+	// PolicyLatestOnly keeps only the most recent event, replacing any unread one
+	PolicyLatestOnly DeliveryPolicy = iota
+	// PolicyLossless queues up to BufferSize events and closes the subscription if the queue overflows,
+	// so a subscriber never silently misses an event
+	PolicyLossless
+	// PolicyBlock queues up to BufferSize events and then blocks the publisher until the subscriber catches up
+	PolicyBlock
+)
+
+// defaultEventBufferSize is the queue length used when SubscribeOptions.BufferSize is not set
+const defaultEventBufferSize = 64
+
+// SubscribeOptions configures a subscription
+type SubscribeOptions struct {
+	Policy     DeliveryPolicy
+	BufferSize int // Queue length for PolicyLossless and PolicyBlock (ignored for PolicyLatestOnly)
+}
+
+// subscriber is a single registered event consumer
+type subscriber struct {
+	policy DeliveryPolicy
+	ch     chan Event
+	done   chan struct{} // Closed on unsubscribe to release a blocked publisher
+
+	sendMu sync.Mutex // Serializes delivery and closing of ch
+	closed bool
+
+	unsubscribe func()
+}
+
+// eventBus fans chain events out to any number of subscribers.
+// The zero value is ready to use.
+type eventBus struct {
+	mu   sync.RWMutex
+	subs map[*subscriber]struct{}
+}
+
+// subscribe registers a new subscriber. The returned channel is closed when ctx is cancelled,
+// when unsubscribe is called, or when a lossless subscriber overflows.
+func (b *eventBus) subscribe(ctx context.Context, opts SubscribeOptions) (<-chan Event, func()) {
+	size := opts.BufferSize
+	if opts.Policy == PolicyLatestOnly {
+		size = 1
+	} else if size <= 0 {
+		size = defaultEventBufferSize
+	}
+
+	sub := &subscriber{
+		policy: opts.Policy,
+		ch:     make(chan Event, size),
+		done:   make(chan struct{}),
+	}
+
+	var once sync.Once
+	sub.unsubscribe = func() {
+		once.Do(func() {
+			close(sub.done)
+
+			b.mu.Lock()
+			delete(b.subs, sub)
+			b.mu.Unlock()
+
+			sub.sendMu.Lock()
+			if !sub.closed {
+				sub.closed = true
+				close(sub.ch)
+			}
+			sub.sendMu.Unlock()
+		})
+	}
+
+	b.mu.Lock()
+	if b.subs == nil {
+		b.subs = make(map[*subscriber]struct{})
+	}
+	b.subs[sub] = struct{}{}
+	b.mu.Unlock()
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			sub.unsubscribe()
+		case <-sub.done:
+		}
+	}()
+
+	return sub.ch, sub.unsubscribe
+}
+
+// publish delivers an event to every subscriber according to its policy
+func (b *eventBus) publish(event Event) {
+	b.mu.RLock()
+	subs := make([]*subscriber, 0, len(b.subs))
+	for sub := range b.subs {
+		subs = append(subs, sub)
+	}
+	b.mu.RUnlock()
+
+	for _, sub := range subs {
+		sub.deliver(event)
+	}
+}
+
+// deliver sends an event to a single subscriber
+func (s *subscriber) deliver(event Event) {
+	s.sendMu.Lock()
+	defer s.sendMu.Unlock()
+
+	if s.closed {
+		return
+	}
+
+	switch s.policy {
+	case PolicyLatestOnly:
+		// Drain any unread event (we only care about the latest)
+		select {
+		case <-s.ch:
+		default:
+		}
+		select {
+		case s.ch <- event:
+		default:
+		}
+
+	case PolicyLossless:
+		select {
+		case s.ch <- event:
+		default:
+			log.Printf("Event subscriber queue full (%d events), closing subscription", cap(s.ch))
+			s.closed = true
+			close(s.ch)
+			go s.unsubscribe()
+		}
+
+	case PolicyBlock:
+		select {
+		case s.ch <- event:
+		case <-s.done:
+		}
+	}
+}
+
+// Subscribe registers a listener for tip and reorg events and can be called any number of times.
+// The channel is closed when ctx is cancelled or unsubscribe is called.
+func (cm *ChainManager) Subscribe(ctx context.Context, opts SubscribeOptions) (<-chan Event, func()) {
+	return cm.events.subscribe(ctx, opts)
+}
+
+// reorgsOnly forwards the reorg events of a subscription to a dedicated channel
+func reorgsOnly(ctx context.Context, events <-chan Event, unsubscribe func()) <-chan *ReorgEvent {
+	out := make(chan *ReorgEvent, reorgBufferSize)
+
+	go func() {
+		defer close(out)
+		defer unsubscribe()
+
+		for event := range events {
+			if event.Type != EventReorg {
+				continue
+			}
+			select {
+			case out <- event.Reorg:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return out
+}
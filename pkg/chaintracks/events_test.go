@@ -0,0 +1,136 @@
+package chaintracks
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// tipEvent builds a tip event for a header at height
+func tipEvent(height uint32) Event {
+	return Event{Type: EventTip, Tip: &BlockHeader{Height: height}}
+}
+
+func TestEventBusLatestOnly(t *testing.T) {
+	var bus eventBus
+	events, unsubscribe := bus.subscribe(context.Background(), SubscribeOptions{Policy: PolicyLatestOnly})
+	defer unsubscribe()
+
+	for height := uint32(1); height <= 3; height++ {
+		bus.publish(tipEvent(height))
+	}
+
+	if event := <-events; event.Tip.Height != 3 {
+		t.Errorf("Latest-only subscriber got height %d, want 3", event.Tip.Height)
+	}
+	select {
+	case event := <-events:
+		t.Errorf("Unexpected extra event at height %d", event.Tip.Height)
+	default:
+	}
+}
+
+func TestEventBusLosslessClosesOnOverflow(t *testing.T) {
+	var bus eventBus
+	events, unsubscribe := bus.subscribe(context.Background(), SubscribeOptions{Policy: PolicyLossless, BufferSize: 2})
+	defer unsubscribe()
+
+	for height := uint32(1); height <= 3; height++ {
+		bus.publish(tipEvent(height))
+	}
+
+	var heights []uint32
+	for event := range events {
+		heights = append(heights, event.Tip.Height)
+	}
+	if len(heights) != 2 || heights[0] != 1 || heights[1] != 2 {
+		t.Errorf("Lossless subscriber got heights %v, want [1 2] before close", heights)
+	}
+}
+
+func TestEventBusBlockWaitsForSubscriber(t *testing.T) {
+	var bus eventBus
+	events, unsubscribe := bus.subscribe(context.Background(), SubscribeOptions{Policy: PolicyBlock, BufferSize: 1})
+	defer unsubscribe()
+
+	bus.publish(tipEvent(1))
+
+	published := make(chan struct{})
+	go func() {
+		bus.publish(tipEvent(2))
+		close(published)
+	}()
+
+	select {
+	case <-published:
+		t.Fatal("Publish did not block on a full subscriber")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	for want := uint32(1); want <= 2; want++ {
+		if event := <-events; event.Tip.Height != want {
+			t.Errorf("Blocking subscriber got height %d, want %d", event.Tip.Height, want)
+		}
+	}
+	<-published
+}
+
+func TestEventBusUnsubscribeReleasesBlockedPublisher(t *testing.T) {
+	var bus eventBus
+	events, unsubscribe := bus.subscribe(context.Background(), SubscribeOptions{Policy: PolicyBlock, BufferSize: 1})
+
+	bus.publish(tipEvent(1))
+
+	published := make(chan struct{})
+	go func() {
+		bus.publish(tipEvent(2))
+		close(published)
+	}()
+
+	time.Sleep(10 * time.Millisecond)
+	unsubscribe()
+
+	select {
+	case <-published:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Unsubscribe did not release the blocked publisher")
+	}
+
+	// The buffered event is still readable, then the channel is closed
+	<-events
+	if _, ok := <-events; ok {
+		t.Error("Channel still open after unsubscribe")
+	}
+}
+
+func TestChainManagerSubscribeMultiple(t *testing.T) {
+	cm := newTestChainManager(t)
+	genesis := cm.GetTip()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	latest, _ := cm.Subscribe(ctx, SubscribeOptions{Policy: PolicyLatestOnly})
+	all, _ := cm.Subscribe(ctx, SubscribeOptions{Policy: PolicyLossless})
+
+	headers := extendChain(t, genesis, 3)
+	for _, header := range headers {
+		if err := cm.SetChainTip([]*BlockHeader{header}); err != nil {
+			t.Fatalf("SetChainTip() error = %v", err)
+		}
+	}
+
+	if event := <-latest; event.Type != EventTip || event.Tip.Hash != headers[2].Hash {
+		t.Errorf("Latest-only subscriber got %s at height %d, want the final tip", event.Type, event.Tip.Height)
+	}
+	for _, header := range headers {
+		if event := <-all; event.Type != EventTip || event.Tip.Hash != header.Hash {
+			t.Errorf("Lossless subscriber got %s at height %d, want tip at height %d", event.Type, event.Tip.Height, header.Height)
+		}
+	}
+
+	cancel()
+	for range all {
+	}
+	if _, ok := <-latest; ok {
+		t.Error("Latest-only channel still open after context cancellation")
+	}
+}
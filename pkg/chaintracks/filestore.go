@@ -0,0 +1,610 @@
+package chaintracks
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/bsv-blockchain/go-sdk/block"
+	"github.com/bsv-blockchain/go-sdk/chainhash"
+)
+
+// zeroChainWork is the hex chainwork recorded before the first header
+const zeroChainWork = "0000000000000000000000000000000000000000000000000000000000000000"
+
+// FileStore persists headers in the CDN layout: binary .headers files of 100k headers each
+// plus a <network>NetBlockHeaders.json metadata file
+type FileStore struct {
+	mu      sync.RWMutex // Serializes writes against CDN reads that need data and FileHash to agree
+	path    string
+	network string
+}
+
+// NewFileStore creates a FileStore rooted at path for a network
+func NewFileStore(path, network string) *FileStore {
+	return &FileStore{
+		path:    path,
+		network: network,
+	}
+}
+
+// metadataPath returns the path of the metadata JSON file
+func (fs *FileStore) metadataPath() string {
+	return filepath.Join(fs.path, fs.network+"NetBlockHeaders.json")
+}
+
+// loadHeadersFromFile reads a binary .headers file and returns a slice of headers
+// This function performs no validation - just parsing
+func loadHeadersFromFile(path string) ([]*block.Header, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+
+	return parseHeaders(data)
+}
+
+// parseHeaders parses concatenated 80-byte headers
+func parseHeaders(data []byte) ([]*block.Header, error) {
+	if len(data)%headerSize != 0 {
+		return nil, fmt.Errorf("invalid file size: %d bytes (not multiple of 80)", len(data))
+	}
+
+	headerCount := len(data) / headerSize
+	headers := make([]*block.Header, 0, headerCount)
+
+	for i := 0; i < headerCount; i++ {
+		headerBytes := data[i*headerSize : (i+1)*headerSize]
+		header, err := block.NewHeaderFromBytes(headerBytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse header at index %d: %w", i, err)
+		}
+		headers = append(headers, header)
+	}
+
+	return headers, nil
+}
+
+// parseMetadata reads and parses the metadata JSON file
+func parseMetadata(path string) (*CDNMetadata, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read metadata: %w", err)
+	}
+
+	var metadata CDNMetadata
+	if err := json.Unmarshal(data, &metadata); err != nil {
+		return nil, fmt.Errorf("failed to parse metadata JSON: %w", err)
+	}
+
+	return &metadata, nil
+}
+
+// ReadMetadata returns the parsed metadata file, or nil if it does not exist
+func (fs *FileStore) ReadMetadata() (*CDNMetadata, error) {
+	metadata, err := parseMetadata(fs.metadataPath())
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	return metadata, err
+}
+
+// LoadRange reads headers from height from to height to (inclusive) out of the .headers files
+func (fs *FileStore) LoadRange(from, to uint32) ([]*BlockHeader, error) {
+	if to < from {
+		return nil, nil
+	}
+
+	result := make([]*BlockHeader, 0, to-from+1)
+	for height := from; height <= to; {
+		fileIndex := height / headersPerFile
+		last := min(to, (fileIndex+1)*headersPerFile-1)
+
+		headers, err := fs.readHeaders(fileIndex, height%headersPerFile, int(last-height)+1)
+		if err != nil {
+			return nil, err
+		}
+
+		for i, header := range headers {
+			result = append(result, &BlockHeader{
+				Header: header,
+				Height: height + uint32(i),
+				Hash:   header.Hash(),
+			})
+		}
+		height = last + 1
+	}
+
+	return result, nil
+}
+
+// readHeaders reads count headers starting at a position within one .headers file
+func (fs *FileStore) readHeaders(fileIndex, position uint32, count int) ([]*block.Header, error) {
+	data, err := fs.readFileData(fileIndex, position, count)
+	if err != nil {
+		return nil, err
+	}
+
+	headers, err := parseHeaders(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse file %s: %w", fileNameForIndex(fs.network, fileIndex), err)
+	}
+	return headers, nil
+}
+
+// readFileData reads the raw bytes of count headers starting at a position within one .headers file
+func (fs *FileStore) readFileData(fileIndex, position uint32, count int) ([]byte, error) {
+	fileName := fileNameForIndex(fs.network, fileIndex)
+	f, err := os.Open(filepath.Join(fs.path, fileName))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file %s: %w", fileName, err)
+	}
+	defer f.Close()
+
+	data := make([]byte, count*headerSize)
+	if _, err := f.ReadAt(data, int64(position)*headerSize); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("file %s is shorter than expected: %w", fileName, ErrHeaderNotFound)
+		}
+		return nil, fmt.Errorf("failed to read file %s: %w", fileName, err)
+	}
+	return data, nil
+}
+
+// PutBranch writes headers into the .headers files and updates the metadata for the new tip
+func (fs *FileStore) PutBranch(headers []*BlockHeader) error {
+	if len(headers) == 0 {
+		return nil
+	}
+
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	if err := os.MkdirAll(fs.path, 0755); err != nil {
+		return fmt.Errorf("failed to create storage directory: %w", err)
+	}
+
+	if err := fs.writeHeadersToFiles(headers); err != nil {
+		return fmt.Errorf("failed to write headers to files: %w", err)
+	}
+
+	metadata, err := fs.readOrCreateMetadata()
+	if err != nil {
+		return err
+	}
+
+	tip := headers[len(headers)-1]
+	fs.resizeMetadata(metadata, tip.Height/headersPerFile)
+
+	// Record the last branch header written into each file
+	for _, header := range headers {
+		entry := &metadata.Files[header.Height/headersPerFile]
+		entry.Count = int(header.Height%headersPerFile) + 1
+		entry.LastHash = header.Hash
+		entry.LastChainWork = ChainWorkToHex(header.ChainWork)
+	}
+	linkMetadataFiles(metadata)
+
+	// Full files are hashed once; the open last file changes with every block, so its FileHash is left
+	// for CDNMetadata and ReadCDNFile to compute when it is served
+	for fileIndex := headers[0].Height / headersPerFile; fileIndex <= tip.Height/headersPerFile; fileIndex++ {
+		metadata.Files[fileIndex].FileHash = ""
+		if metadata.Files[fileIndex].Count < headersPerFile {
+			continue
+		}
+		if err := fs.hashFile(metadata, fileIndex); err != nil {
+			return err
+		}
+	}
+
+	// The metadata rename commits the branch; stale data above the tip is only removed afterwards
+	if err := fs.writeMetadata(metadata); err != nil {
+		return err
+	}
+
+	return fs.truncateFiles(tip.Height)
+}
+
+// TruncateAbove discards all headers above height from the files and metadata
+func (fs *FileStore) TruncateAbove(height uint32) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	metadata, err := fs.ReadMetadata()
+	if err != nil || metadata == nil {
+		return err
+	}
+
+	tipHeight, ok := metadataTipHeight(metadata)
+	if !ok || tipHeight <= height {
+		return nil
+	}
+
+	fileIndex := height / headersPerFile
+	fs.resizeMetadata(metadata, fileIndex)
+
+	if err := fs.sealFile(metadata, fileIndex, int(height%headersPerFile)+1); err != nil {
+		return err
+	}
+
+	if err := fs.writeMetadata(metadata); err != nil {
+		return err
+	}
+
+	return fs.truncateFiles(height)
+}
+
+// sealFile sets a file entry's count, last hash, last chainwork and file hash from the first count headers on disk
+func (fs *FileStore) sealFile(metadata *CDNMetadata, fileIndex uint32, count int) error {
+	entry := &metadata.Files[fileIndex]
+	data, err := fs.readFileData(fileIndex, 0, count)
+	if err != nil {
+		return err
+	}
+	headers, err := parseHeaders(data)
+	if err != nil {
+		return fmt.Errorf("failed to parse file %s: %w", entry.FileName, err)
+	}
+
+	// Chainwork continues from the previous file's last header
+	var chainWork *big.Int
+	if fileIndex > 0 {
+		if chainWork, err = ChainWorkFromHex(metadata.Files[fileIndex-1].LastChainWork); err != nil {
+			return fmt.Errorf("invalid lastChainWork in %s: %w", metadata.Files[fileIndex-1].FileName, err)
+		}
+	}
+	for i, header := range headers {
+		chainWork = chainWorkAt(chainWork, entry.FirstHeight+uint32(i), header.Bits)
+	}
+
+	entry.Count = count
+	entry.LastHash = headers[len(headers)-1].Hash()
+	entry.LastChainWork = ChainWorkToHex(chainWork)
+	entry.FileHash = hashFileData(data)
+	return nil
+}
+
+// hashFile sets a file entry's FileHash from the headers its count covers
+func (fs *FileStore) hashFile(metadata *CDNMetadata, fileIndex uint32) error {
+	entry := &metadata.Files[fileIndex]
+	data, err := fs.readFileData(fileIndex, 0, entry.Count)
+	if err != nil {
+		return err
+	}
+	entry.FileHash = hashFileData(data)
+	return nil
+}
+
+// writeHeadersToFiles writes headers to the appropriate .headers files
+func (fs *FileStore) writeHeadersToFiles(headers []*BlockHeader) error {
+	// Group headers by file
+	fileHeaders := make(map[uint32][]*BlockHeader)
+	for _, header := range headers {
+		fileIndex := header.Height / headersPerFile
+		fileHeaders[fileIndex] = append(fileHeaders[fileIndex], header)
+	}
+
+	// Write to each file
+	for fileIndex, hdrs := range fileHeaders {
+		fileName := fileNameForIndex(fs.network, fileIndex)
+		filePath := filepath.Join(fs.path, fileName)
+
+		// Open file for read/write (create if doesn't exist)
+		f, err := os.OpenFile(filePath, os.O_RDWR|os.O_CREATE, 0644)
+		if err != nil {
+			return fmt.Errorf("failed to open file %s: %w", fileName, err)
+		}
+
+		// Write each header at its position
+		for _, header := range hdrs {
+			positionInFile := (header.Height % headersPerFile) * headerSize
+			if _, err := f.WriteAt(header.Header.Bytes(), int64(positionInFile)); err != nil {
+				f.Close()
+				return fmt.Errorf("failed to write header: %w", err)
+			}
+		}
+
+		// Headers must be durable before the metadata that references them is committed
+		if err := f.Sync(); err != nil {
+			f.Close()
+			return fmt.Errorf("failed to sync file %s: %w", fileName, err)
+		}
+		f.Close()
+	}
+
+	return syncDir(fs.path)
+}
+
+// truncateFiles cuts the file holding tipHeight just after it and removes any later files
+func (fs *FileStore) truncateFiles(tipHeight uint32) error {
+	fileIndex := tipHeight / headersPerFile
+	size := int64(tipHeight%headersPerFile+1) * headerSize
+
+	tipPath := filepath.Join(fs.path, fileNameForIndex(fs.network, fileIndex))
+	if info, err := os.Stat(tipPath); err == nil && info.Size() > size {
+		if err := os.Truncate(tipPath, size); err != nil {
+			return fmt.Errorf("failed to truncate %s: %w", tipPath, err)
+		}
+	}
+
+	return fs.removeFilesFrom(fileIndex + 1)
+}
+
+// removeFilesFrom deletes the header file at fileIndex and every later one
+func (fs *FileStore) removeFilesFrom(fileIndex uint32) error {
+	for i := fileIndex; ; i++ {
+		err := os.Remove(filepath.Join(fs.path, fileNameForIndex(fs.network, i)))
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to remove stale header file: %w", err)
+		}
+	}
+}
+
+// readOrCreateMetadata reads existing metadata or returns an empty document
+func (fs *FileStore) readOrCreateMetadata() (*CDNMetadata, error) {
+	metadata, err := fs.ReadMetadata()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse existing metadata: %w", err)
+	}
+	if metadata == nil {
+		metadata = &CDNMetadata{
+			RootFolder:     "",
+			JSONFilename:   fs.network + "NetBlockHeaders.json",
+			HeadersPerFile: headersPerFile,
+			Files:          []CDNFileEntry{},
+		}
+	}
+	return metadata, nil
+}
+
+// resizeMetadata makes the metadata describe exactly the files up to lastFileIndex
+func (fs *FileStore) resizeMetadata(metadata *CDNMetadata, lastFileIndex uint32) {
+	for i := uint32(len(metadata.Files)); i <= lastFileIndex; i++ {
+		metadata.Files = append(metadata.Files, CDNFileEntry{
+			Chain:         fs.network,
+			FileName:      fileNameForIndex(fs.network, i),
+			FirstHeight:   i * headersPerFile,
+			LastChainWork: zeroChainWork,
+			PrevChainWork: zeroChainWork,
+		})
+	}
+	metadata.Files = metadata.Files[:lastFileIndex+1]
+}
+
+// linkMetadataFiles sets each file's previous hash and chainwork from the file before it
+func linkMetadataFiles(metadata *CDNMetadata) {
+	if len(metadata.Files) > 0 && metadata.Files[0].FirstHeight == 0 {
+		metadata.Files[0].PrevHash = chainhash.Hash{}
+		metadata.Files[0].PrevChainWork = zeroChainWork
+	}
+	for i := 1; i < len(metadata.Files); i++ {
+		metadata.Files[i].PrevHash = metadata.Files[i-1].LastHash
+		metadata.Files[i].PrevChainWork = metadata.Files[i-1].LastChainWork
+	}
+}
+
+// writeMetadata atomically replaces the metadata JSON in local storage
+func (fs *FileStore) writeMetadata(metadata *CDNMetadata) error {
+	data, err := json.MarshalIndent(metadata, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal metadata: %w", err)
+	}
+
+	if err := writeFileAtomic(fs.metadataPath(), data, 0644); err != nil {
+		return fmt.Errorf("failed to write metadata: %w", err)
+	}
+
+	return nil
+}
+
+// recoveryFiles is the number of trailing files checked by Recover; a single interrupted
+// write never touches more than the tip file and the one before it
+const recoveryFiles = 2
+
+// Recover repairs the store after an interrupted write and must run before the store is read.
+// Header files are fsynced before the metadata rename commits them, so the metadata is the source of truth:
+// a leftover temp file is removed, data past the committed tip is discarded, and if the last files
+// no longer link up (a reorg overwrote headers in place before its metadata was committed)
+// the chain is truncated to the last consistent height.
+func (fs *FileStore) Recover() error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	if err := os.Remove(fs.metadataPath() + tempSuffix); err == nil {
+		log.Printf("Removed uncommitted metadata file %s", fs.metadataPath()+tempSuffix)
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove temp metadata: %w", err)
+	}
+
+	metadata, err := fs.ReadMetadata()
+	if err != nil || metadata == nil {
+		return err
+	}
+	tipHeight, ok := metadataTipHeight(metadata)
+	if !ok {
+		return nil
+	}
+
+	tipFile := tipHeight / headersPerFile
+	firstFile := uint32(0)
+	if tipFile >= recoveryFiles {
+		firstFile = tipFile - recoveryFiles + 1
+	}
+
+	// Committed headers are fsynced before the metadata, so a crash cannot lose them;
+	// missing data is corruption that is left to verification rather than "recovered" away
+	for fileIndex := firstFile; fileIndex <= tipFile; fileIndex++ {
+		entry := metadata.Files[fileIndex]
+		stored, err := fs.storedHeaderCount(fileIndex)
+		if err != nil {
+			return err
+		}
+		if stored < entry.Count {
+			log.Printf("Skipping recovery: %s holds %d of %d committed headers", entry.FileName, stored, entry.Count)
+			return nil
+		}
+	}
+
+	var expectedPrev chainhash.Hash
+	if firstFile > 0 {
+		expectedPrev = metadata.Files[firstFile-1].LastHash
+	}
+
+	changed := false
+	for fileIndex := firstFile; fileIndex <= tipFile; fileIndex++ {
+		entry := &metadata.Files[fileIndex]
+		linked, lastHash, err := fs.linkedPrefix(fileIndex, entry.Count, expectedPrev)
+		if err != nil {
+			return err
+		}
+
+		// A header is only confirmed by the next header linking to it (or by the committed LastHash),
+		// so the last linked header is kept only if the rest of the file links and something vouches for it
+		keep := linked
+		if linked < entry.Count || (lastHash != entry.LastHash && fileIndex == tipFile) {
+			keep = max(linked-1, 0)
+		}
+
+		if keep == entry.Count {
+			if lastHash != entry.LastHash {
+				// The file holds a complete branch the metadata did not record yet, confirmed by the next file
+				log.Printf("Recovering %s: last header %s replaces %s", entry.FileName, lastHash.String(), entry.LastHash.String())
+				if err := fs.sealFile(metadata, fileIndex, keep); err != nil {
+					return err
+				}
+				changed = true
+			}
+			expectedPrev = lastHash
+			continue
+		}
+
+		// Truncate to the last confirmed header
+		log.Printf("Recovering %s: only %d of %d headers are consistent, truncating", entry.FileName, keep, entry.Count)
+		changed = true
+		if keep > 0 {
+			fs.resizeMetadata(metadata, fileIndex)
+			if err := fs.sealFile(metadata, fileIndex, keep); err != nil {
+				return err
+			}
+		} else if fileIndex > 0 {
+			fs.resizeMetadata(metadata, fileIndex-1)
+		} else {
+			metadata.Files = metadata.Files[:0]
+		}
+		break
+	}
+
+	if changed {
+		linkMetadataFiles(metadata)
+		if err := fs.writeMetadata(metadata); err != nil {
+			return err
+		}
+	}
+
+	// Discard any data past the committed tip
+	if tipHeight, ok = metadataTipHeight(metadata); !ok {
+		return fs.removeFilesFrom(0)
+	}
+	return fs.truncateFiles(tipHeight)
+}
+
+// storedHeaderCount returns the number of complete headers in a file (zero if it does not exist)
+func (fs *FileStore) storedHeaderCount(fileIndex uint32) (int, error) {
+	fileName := fileNameForIndex(fs.network, fileIndex)
+	info, err := os.Stat(filepath.Join(fs.path, fileName))
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to stat %s: %w", fileName, err)
+	}
+	return int(info.Size() / headerSize), nil
+}
+
+// linkedPrefix returns how many of the first count headers in a file link to prevHash
+// and to each other, plus the hash of the last linked header
+func (fs *FileStore) linkedPrefix(fileIndex uint32, count int, prevHash chainhash.Hash) (int, chainhash.Hash, error) {
+	headers, err := fs.readHeaders(fileIndex, 0, count)
+	if err != nil {
+		return 0, chainhash.Hash{}, err
+	}
+
+	var lastHash chainhash.Hash
+	for i, header := range headers {
+		if header.PrevHash != prevHash {
+			return i, lastHash, nil
+		}
+		lastHash = header.Hash()
+		prevHash = lastHash
+	}
+	return len(headers), lastHash, nil
+}
+
+// CDNMetadata returns the metadata for serving the store as a CDN.
+// The open last file and files written by earlier versions have no FileHash; it is computed and persisted on first use.
+func (fs *FileStore) CDNMetadata() (*CDNMetadata, error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	metadata, err := fs.ReadMetadata()
+	if err != nil || metadata == nil {
+		return metadata, err
+	}
+
+	changed := false
+	for i, entry := range metadata.Files {
+		if entry.FileHash != "" || entry.Count == 0 {
+			continue
+		}
+		if err := fs.hashFile(metadata, uint32(i)); err != nil {
+			return nil, err
+		}
+		changed = true
+	}
+
+	if changed {
+		if err := fs.writeMetadata(metadata); err != nil {
+			return nil, err
+		}
+	}
+	return metadata, nil
+}
+
+// ReadCDNFile returns the metadata entry and contents of a .headers file for serving as a CDN.
+// Only the headers the metadata commits to are returned, so the data always matches the entry's FileHash.
+func (fs *FileStore) ReadCDNFile(fileName string) (*CDNFileEntry, []byte, error) {
+	fs.mu.RLock()
+	defer fs.mu.RUnlock()
+
+	metadata, err := fs.ReadMetadata()
+	if err != nil {
+		return nil, nil, err
+	}
+	if metadata != nil {
+		for i, entry := range metadata.Files {
+			if entry.FileName != fileName || entry.Count == 0 {
+				continue
+			}
+
+			data, err := fs.readFileData(uint32(i), 0, entry.Count)
+			if err != nil {
+				return nil, nil, err
+			}
+			if entry.FileHash == "" {
+				entry.FileHash = hashFileData(data)
+			}
+			return &entry, data, nil
+		}
+	}
+
+	return nil, nil, fmt.Errorf("%w: no header file %s", ErrHeaderNotFound, fileName)
+}
@@ -0,0 +1,372 @@
+package chaintracks
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultSyncWindow is the number of header batches SyncForward requests in parallel when no window is given
+const DefaultSyncWindow = 8
+
+// SyncProgress describes the state of the most recent forward sync
+type SyncProgress struct {
+	Source       string    `json:"source"`
+	ForkHeight   uint32    `json:"forkHeight"`   // Last height shared with the remote chain
+	Height       uint32    `json:"height"`       // Last height imported
+	TargetHeight uint32    `json:"targetHeight"` // Remote tip height when the sync started
+	Imported     int       `json:"imported"`     // Headers imported so far
+	StartedAt    time.Time `json:"startedAt"`
+	Done         bool      `json:"done"`
+	Error        string    `json:"error,omitempty"`
+}
+
+// Percent returns how much of the range above the fork point has been imported
+func (p SyncProgress) Percent() float64 {
+	if p.TargetHeight <= p.ForkHeight {
+		return 100
+	}
+	return float64(p.Height-p.ForkHeight) * 100 / float64(p.TargetHeight-p.ForkHeight)
+}
+
+// Rate returns the average number of headers imported per second
+func (p SyncProgress) Rate() float64 {
+	elapsed := time.Since(p.StartedAt).Seconds()
+	if elapsed <= 0 {
+		return 0
+	}
+	return float64(p.Imported) / elapsed
+}
+
+// GetSyncProgress returns the progress of the running or last completed forward sync.
+// ok is false if no forward sync has been started.
+func (cm *ChainManager) GetSyncProgress() (progress SyncProgress, ok bool) {
+	cm.syncMu.Lock()
+	defer cm.syncMu.Unlock()
+	if cm.syncProgress == nil {
+		return SyncProgress{}, false
+	}
+	return *cm.syncProgress, true
+}
+
+// updateSyncProgress applies update to the current sync progress under the lock
+func (cm *ChainManager) updateSyncProgress(update func(p *SyncProgress)) {
+	cm.syncMu.Lock()
+	defer cm.syncMu.Unlock()
+	update(cm.syncProgress)
+}
+
+// headerBatch is one range of headers fetched by SyncForward
+type headerBatch struct {
+	height  uint32
+	headers []*BlockHeader
+	err     error
+}
+
+// SyncForward syncs from a Chaintracks server by height instead of walking back from its tip.
+// It finds the fork point with a block locator, then fetches batches of maxHeadersPerRequest headers
+// with up to window requests in flight, validating and importing each batch in height order as it arrives.
+// Headers that would replace part of the local chain are held back until the remote branch has more work.
+func (cm *ChainManager) SyncForward(ctx context.Context, baseURL string, window int) (err error) {
+	if window <= 0 {
+		window = DefaultSyncWindow
+	}
+	baseURL = strings.TrimSuffix(baseURL, "/")
+
+	remoteTip, err := cm.fetcher.fetchRemoteHeader(ctx, baseURL+"/v2/tip/header")
+	if err != nil {
+		return fmt.Errorf("failed to fetch remote tip: %w", err)
+	}
+	if _, err := cm.GetHeaderByHash(&remoteTip.Hash); err == nil {
+		log.Printf("Already have remote tip %s at height %d", remoteTip.Hash, remoteTip.Height)
+		return nil
+	}
+
+	fork, err := cm.findForkPoint(ctx, baseURL, remoteTip.Height)
+	if err != nil {
+		return err
+	}
+	log.Printf("Forward sync from %s: fork point at height %d, remote tip at height %d (window %d)", baseURL, fork.Height, remoteTip.Height, window)
+
+	cm.syncMu.Lock()
+	cm.syncProgress = &SyncProgress{
+		Source:       baseURL,
+		ForkHeight:   fork.Height,
+		Height:       fork.Height,
+		TargetHeight: remoteTip.Height,
+		StartedAt:    time.Now(),
+	}
+	cm.syncMu.Unlock()
+	defer func() {
+		cm.updateSyncProgress(func(p *SyncProgress) {
+			p.Done = true
+			if err != nil {
+				p.Error = err.Error()
+			}
+		})
+	}()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	slots := make(chan struct{}, window)
+	batches := cm.fetcher.fetchBatches(ctx, baseURL, fork.Height+1, remoteTip.Height, slots)
+
+	// Batches arrive in any order; import them in height order
+	pending := make(map[uint32]headerBatch)
+	next := fork.Height + 1
+	parent := fork
+	var held []*BlockHeader
+
+	for batch := range batches {
+		if batch.err != nil {
+			return batch.err
+		}
+		pending[batch.height] = batch
+
+		for {
+			batch, ok := pending[next]
+			if !ok {
+				break
+			}
+			delete(pending, next)
+			<-slots
+
+			if err := linkBatch(parent, batch.headers); err != nil {
+				return fmt.Errorf("remote chain changed during sync: %w", err)
+			}
+			parent = batch.headers[len(batch.headers)-1]
+			next = parent.Height + 1
+			held = append(held, batch.headers...)
+
+			// Never trade local headers for a remote branch with less work
+			if tip := cm.GetTip(); tip != nil && tip.Height > fork.Height && tip.ChainWork.Cmp(parent.ChainWork) >= 0 {
+				continue
+			}
+
+			if err := cm.validateHeaders(held, baseURL); err != nil {
+				return fmt.Errorf("remote branch failed validation: %w", err)
+			}
+			if err := cm.setChainTip(held); err != nil {
+				return fmt.Errorf("failed to set chain tip: %w", err)
+			}
+
+			imported := len(held)
+			held = nil
+			cm.updateSyncProgress(func(p *SyncProgress) {
+				p.Height = parent.Height
+				p.Imported += imported
+			})
+			progress, _ := cm.GetSyncProgress()
+			log.Printf("Forward sync: height %d/%d (%.1f%%, %.0f headers/s)", progress.Height, progress.TargetHeight, progress.Percent(), progress.Rate())
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if len(held) > 0 {
+		log.Printf("Local chain has at least as much work as the remote chain at height %d, keeping it", parent.Height)
+		return nil
+	}
+
+	progress, _ := cm.GetSyncProgress()
+	log.Printf("Forward sync complete: imported %d headers to height %d in %v", progress.Imported, progress.Height, time.Since(progress.StartedAt))
+	return nil
+}
+
+// fetchBatches requests the heights from first to last in batches of maxHeadersPerRequest. A request only starts
+// once it gets a slot, and the consumer frees a slot per batch it imports, so at most cap(slots) batches are in
+// flight or waiting to be imported. The channel is closed once every request has finished.
+func (f httpFetcher) fetchBatches(ctx context.Context, baseURL string, first, last uint32, slots chan struct{}) <-chan headerBatch {
+	// Every batch holds a slot until it is imported, so sends never block
+	batches := make(chan headerBatch, cap(slots))
+
+	go func() {
+		var wg sync.WaitGroup
+		defer func() {
+			wg.Wait()
+			close(batches)
+		}()
+
+		for height := first; height <= last; height += maxHeadersPerRequest {
+			select {
+			case slots <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+
+			wg.Add(1)
+			go func(height, count uint32) {
+				defer wg.Done()
+				headers, err := f.fetchHeadersByHeight(ctx, baseURL, height, count)
+				if err == nil && uint32(len(headers)) != count {
+					err = fmt.Errorf("requested %d headers at height %d, got %d", count, height, len(headers))
+				}
+				batches <- headerBatch{height: height, headers: headers, err: err}
+			}(height, min(last-height+1, maxHeadersPerRequest))
+		}
+	}()
+
+	return batches
+}
+
+// linkBatch sets heights and chainwork on a batch that must extend parent
+func linkBatch(parent *BlockHeader, headers []*BlockHeader) error {
+	prev := parent
+	for _, header := range headers {
+		if header.PrevHash != prev.Hash {
+			return fmt.Errorf("header %s does not link to %s at height %d", header.Hash, prev.Hash, prev.Height)
+		}
+		header.Height = prev.Height + 1
+		header.ChainWork = AddWork(prev.ChainWork, header.Bits)
+		prev = header
+	}
+	return nil
+}
+
+// findForkPoint returns the highest local main chain header that the remote chain also has at the same height.
+// Local hashes at the locator heights (tip, tip-1, tip-2, tip-4, ... genesis) are compared with the remote chain,
+// then the gap between the first match and the last mismatch is binary searched.
+func (cm *ChainManager) findForkPoint(ctx context.Context, baseURL string, remoteHeight uint32) (*BlockHeader, error) {
+	tip := cm.GetTip()
+	if tip == nil {
+		return nil, fmt.Errorf("no local chain to sync from")
+	}
+
+	matches := func(height uint32) (*BlockHeader, bool, error) {
+		local, err := cm.GetHeaderByHeight(height)
+		if err != nil {
+			return nil, false, err
+		}
+		remote, err := cm.fetcher.fetchRemoteHeader(ctx, fmt.Sprintf("%s/v2/header/height/%d", baseURL, height))
+		if errors.Is(err, ErrHeaderNotFound) {
+			return local, false, nil
+		}
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to fetch remote header at height %d: %w", height, err)
+		}
+		return local, remote.Hash == local.Hash, nil
+	}
+
+	mismatch := min(tip.Height, remoteHeight) + 1
+	var fork *BlockHeader
+	for _, height := range blockLocatorHeights(min(tip.Height, remoteHeight)) {
+		local, ok, err := matches(height)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			fork = local
+			break
+		}
+		mismatch = height
+	}
+	if fork == nil {
+		return nil, fmt.Errorf("remote chain does not share a genesis header with the local chain")
+	}
+
+	// Invariant: fork matches, mismatch does not (or is above both tips)
+	for mismatch-fork.Height > 1 {
+		local, ok, err := matches(fork.Height + (mismatch-fork.Height)/2)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			fork = local
+		} else {
+			mismatch = local.Height
+		}
+	}
+	return fork, nil
+}
+
+// blockLocatorHeights returns descending heights from top: the ten highest, then doubling gaps, ending at genesis
+func blockLocatorHeights(top uint32) []uint32 {
+	heights := make([]uint32, 0, 32)
+	step := uint32(1)
+	for height := top; ; height -= step {
+		heights = append(heights, height)
+		if len(heights) >= 10 {
+			step *= 2
+		}
+		if height < step {
+			break
+		}
+	}
+	if heights[len(heights)-1] != 0 {
+		heights = append(heights, 0)
+	}
+	return heights
+}
+
+// fetchRemoteHeader fetches a single header from a Chaintracks v2 endpoint
+func (f httpFetcher) fetchRemoteHeader(ctx context.Context, url string) (*BlockHeader, error) {
+	var header *BlockHeader
+	if err := f.fetchV2(ctx, url, &header); err != nil {
+		return nil, err
+	}
+	if header == nil {
+		return nil, ErrHeaderNotFound
+	}
+	return header, nil
+}
+
+// fetchHeadersByHeight fetches up to count consecutive headers starting at height from a Chaintracks server.
+// Heights and chainwork are left for the caller to fill in.
+func (f httpFetcher) fetchHeadersByHeight(ctx context.Context, baseURL string, height, count uint32) ([]*BlockHeader, error) {
+	var hexData string
+	if err := f.fetchV2(ctx, fmt.Sprintf("%s/v2/headers?height=%d&count=%d", baseURL, height, count), &hexData); err != nil {
+		return nil, fmt.Errorf("failed to fetch headers at height %d: %w", height, err)
+	}
+
+	data, err := hex.DecodeString(hexData)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode headers at height %d: %w", height, err)
+	}
+	parsed, err := parseHeaders(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse headers at height %d: %w", height, err)
+	}
+
+	headers := make([]*BlockHeader, len(parsed))
+	for i, header := range parsed {
+		headers[i] = &BlockHeader{Header: header, Hash: header.Hash()}
+	}
+	return headers, nil
+}
+
+// fetchV2 GETs a Chaintracks v2 endpoint and decodes the value of a success response.
+// A 404 response is reported as ErrHeaderNotFound.
+func (f httpFetcher) fetchV2(ctx context.Context, url string, value any) error {
+	resp, err := f.get(ctx, url)
+	if err != nil {
+		return fmt.Errorf("failed to fetch %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return ErrHeaderNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned status %d", url, resp.StatusCode)
+	}
+
+	response := struct {
+		Status string `json:"status"`
+		Value  any    `json:"value"`
+	}{Value: value}
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	if response.Status != "success" {
+		return fmt.Errorf("%s returned status %q", url, response.Status)
+	}
+	return nil
+}
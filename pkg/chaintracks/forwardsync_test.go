@@ -0,0 +1,133 @@
+package chaintracks
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestSyncForward(t *testing.T) {
+	cm := newTestChainManager(t)
+	genesis := cm.GetTip()
+	chain := append([]*BlockHeader{genesis}, extendChain(t, genesis, 3499)...)
+	node := newFakeUpstream(t, chain)
+
+	if err := cm.SyncForward(context.Background(), node.URL, 2); err != nil {
+		t.Fatalf("SyncForward() error = %v", err)
+	}
+
+	tip := cm.GetTip()
+	if tip.Hash != chain[3499].Hash || tip.ChainWork.Cmp(chain[3499].ChainWork) != 0 {
+		t.Fatalf("Tip after sync = height %d %s, want height 3499 %s", tip.Height, tip.Hash, chain[3499].Hash)
+	}
+	if maxInFlight := node.maxInFlight.Load(); maxInFlight > 2 {
+		t.Errorf("Up to %d batch requests were in flight, want at most the window of 2", maxInFlight)
+	}
+
+	progress, ok := cm.GetSyncProgress()
+	if !ok {
+		t.Fatal("GetSyncProgress() reported no sync")
+	}
+	if !progress.Done || progress.Error != "" || progress.ForkHeight != 0 || progress.Height != 3499 || progress.TargetHeight != 3499 || progress.Imported != 3499 {
+		t.Errorf("Progress after sync = %+v", progress)
+	}
+	if progress.Percent() != 100 {
+		t.Errorf("Percent() = %v, want 100", progress.Percent())
+	}
+
+	// The batches were persisted in order
+	reloaded, err := NewChainManager("regtest", cm.localStoragePath)
+	if err != nil {
+		t.Fatalf("NewChainManager() error = %v", err)
+	}
+	if reloaded.GetHeight() != 3499 {
+		t.Errorf("Reloaded height = %d, want 3499", reloaded.GetHeight())
+	}
+}
+
+func TestSyncForwardReplacesForkedChain(t *testing.T) {
+	cm := newTestChainManager(t)
+	genesis := cm.GetTip()
+	local := extendChain(t, genesis, 30)
+	if err := cm.SetChainTip(local); err != nil {
+		t.Fatalf("SetChainTip() error = %v", err)
+	}
+
+	// The remote chain forks after height 20 and is longer
+	fork := mineHeader(t, local[19], easyBits, local[19].Timestamp+300)
+	remote := append([]*BlockHeader{genesis}, local[:20]...)
+	remote = append(append(remote, fork), extendChain(t, fork, 1100)...)
+	node := newFakeUpstream(t, remote)
+
+	if err := cm.SyncForward(context.Background(), node.URL, 4); err != nil {
+		t.Fatalf("SyncForward() error = %v", err)
+	}
+
+	if tip := cm.GetTip(); tip.Hash != remote[len(remote)-1].Hash {
+		t.Fatalf("Tip after sync = height %d, want remote tip at height %d", tip.Height, len(remote)-1)
+	}
+	if header, err := cm.GetHeaderByHeight(21); err != nil || header.Hash != fork.Hash {
+		t.Errorf("Header at height 21 = %v, want the remote fork header", header)
+	}
+	if progress, _ := cm.GetSyncProgress(); progress.ForkHeight != 20 {
+		t.Errorf("ForkHeight = %d, want 20", progress.ForkHeight)
+	}
+}
+
+func TestSyncForwardKeepsChainWithMoreWork(t *testing.T) {
+	cm := newTestChainManager(t)
+	genesis := cm.GetTip()
+	local := extendChain(t, genesis, 30)
+	if err := cm.SetChainTip(local); err != nil {
+		t.Fatalf("SetChainTip() error = %v", err)
+	}
+
+	fork := mineHeader(t, local[19], easyBits, local[19].Timestamp+300)
+	remote := append([]*BlockHeader{genesis}, local[:20]...)
+	remote = append(append(remote, fork), extendChain(t, fork, 4)...)
+	node := newFakeUpstream(t, remote)
+
+	if err := cm.SyncForward(context.Background(), node.URL, 4); err != nil {
+		t.Fatalf("SyncForward() error = %v", err)
+	}
+	if tip := cm.GetTip(); tip.Hash != local[29].Hash {
+		t.Errorf("Tip after sync = height %d %s, want the local tip %s", tip.Height, tip.Hash, local[29].Hash)
+	}
+}
+
+func TestSyncForwardStopsOnFailedBatch(t *testing.T) {
+	cm := newTestChainManager(t)
+	genesis := cm.GetTip()
+	chain := append([]*BlockHeader{genesis}, extendChain(t, genesis, 3499)...)
+	node := newFakeUpstream(t, chain, withFailingBatch(2001))
+
+	if err := cm.SyncForward(context.Background(), node.URL, 3); err == nil {
+		t.Fatal("SyncForward() succeeded although a batch failed")
+	}
+
+	// Batches below the failed one may have been imported, nothing above it
+	if height := cm.GetHeight(); height > 2000 {
+		t.Errorf("Height after failed sync = %d, want at most 2000", height)
+	}
+	if progress, _ := cm.GetSyncProgress(); !progress.Done || progress.Error == "" {
+		t.Errorf("Progress after failed sync = %+v, want a recorded error", progress)
+	}
+}
+
+func TestBlockLocatorHeights(t *testing.T) {
+	tests := []struct {
+		top  uint32
+		want []uint32
+	}{
+		{top: 0, want: []uint32{0}},
+		{top: 5, want: []uint32{5, 4, 3, 2, 1, 0}},
+		{top: 12, want: []uint32{12, 11, 10, 9, 8, 7, 6, 5, 4, 3, 1, 0}},
+		{top: 30, want: []uint32{30, 29, 28, 27, 26, 25, 24, 23, 22, 21, 19, 15, 7, 0}},
+	}
+
+	for _, tt := range tests {
+		if got := blockLocatorHeights(tt.top); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("blockLocatorHeights(%d) = %v, want %v", tt.top, got, tt.want)
+		}
+	}
+}
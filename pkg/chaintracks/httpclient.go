@@ -0,0 +1,117 @@
+package chaintracks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math/rand/v2"
+	"net/http"
+	"time"
+)
+
+// DefaultHTTPTimeout bounds each outbound sync request, including reading the response
+const DefaultHTTPTimeout = 30 * time.Second
+
+// RetryPolicy controls how outbound sync requests are retried after network errors, 429 and 5xx responses
+type RetryPolicy struct {
+	MaxAttempts int           // Attempts per request including the first (1 disables retries)
+	BaseDelay   time.Duration // Delay before the first retry, doubled for each one after
+	MaxDelay    time.Duration // Upper bound on the delay between attempts
+}
+
+// DefaultRetryPolicy makes up to 4 attempts, backing off from 500ms to at most 10s
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{MaxAttempts: 4, BaseDelay: 500 * time.Millisecond, MaxDelay: 10 * time.Second}
+}
+
+// DefaultHTTPClient returns the client used for sync requests when none is configured
+func DefaultHTTPClient() *http.Client {
+	return &http.Client{Timeout: DefaultHTTPTimeout}
+}
+
+// backoff returns the delay before retry n, counting from 0. Half of it is random, so callers that failed together
+// do not retry together.
+func (p RetryPolicy) backoff(n int) time.Duration {
+	delay := p.MaxDelay
+	if n < 32 && p.BaseDelay<<n > 0 && p.BaseDelay<<n < p.MaxDelay {
+		delay = p.BaseDelay << n
+	}
+	if delay <= 0 {
+		return 0
+	}
+	return delay/2 + rand.N(delay/2+1)
+}
+
+// httpFetcher makes the outbound requests of header sync with a client and retry policy
+type httpFetcher struct {
+	client *http.Client
+	retry  RetryPolicy
+}
+
+// defaultFetcher uses DefaultHTTPClient and DefaultRetryPolicy
+func defaultFetcher() httpFetcher {
+	return httpFetcher{client: DefaultHTTPClient(), retry: DefaultRetryPolicy()}
+}
+
+// retryable reports whether a response status may succeed if the request is repeated
+func retryable(status int) bool {
+	return status == http.StatusTooManyRequests || status >= http.StatusInternalServerError
+}
+
+// get fetches url, retrying network errors, 429 and 5xx responses with exponential backoff and jitter until the
+// policy's attempts run out or ctx is cancelled. Any other response is returned for the caller to check and close.
+func (f httpFetcher) get(ctx context.Context, url string) (*http.Response, error) {
+	attempts := max(f.retry.MaxAttempts, 1)
+	for attempt := 1; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create request: %w", err)
+		}
+
+		resp, err := f.client.Do(req)
+		if err == nil && (!retryable(resp.StatusCode) || attempt == attempts) {
+			return resp, nil
+		}
+		if err != nil {
+			if ctx.Err() != nil || errors.Is(err, ErrDataHubDenied) || attempt == attempts {
+				return nil, err
+			}
+		} else {
+			// Drain so the connection can be reused
+			io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+			resp.Body.Close()
+			err = fmt.Errorf("status %d", resp.StatusCode)
+		}
+
+		delay := f.retry.backoff(attempt - 1)
+		log.Printf("GET %s failed (attempt %d of %d): %v, retrying in %v", url, attempt, attempts, err, delay.Round(time.Millisecond))
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// getBody fetches url with get and returns the body of a 200 response
+func (f httpFetcher) getBody(ctx context.Context, url string) ([]byte, error) {
+	resp, err := f.get(ctx, url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s returned status %d", url, resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", url, err)
+	}
+	return data, nil
+}
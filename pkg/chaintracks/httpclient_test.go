@@ -0,0 +1,153 @@
+package chaintracks
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// testRetryPolicy retries like DefaultRetryPolicy without the delays
+var testRetryPolicy = RetryPolicy{MaxAttempts: 4, BaseDelay: time.Millisecond, MaxDelay: 2 * time.Millisecond}
+
+// flakyServer fails the first failures requests with status, then answers with body
+func flakyServer(t *testing.T, failures int32, status int, body string) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) <= failures {
+			w.WriteHeader(status)
+			return
+		}
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+	return server, &requests
+}
+
+func TestHTTPFetcherRetriesTransientFailures(t *testing.T) {
+	for _, status := range []int{http.StatusServiceUnavailable, http.StatusTooManyRequests} {
+		server, requests := flakyServer(t, 2, status, "ok")
+		f := httpFetcher{client: DefaultHTTPClient(), retry: testRetryPolicy}
+
+		data, err := f.getBody(context.Background(), server.URL)
+		if err != nil || string(data) != "ok" {
+			t.Fatalf("getBody() after %d responses = %q, %v, want ok", status, data, err)
+		}
+		if n := requests.Load(); n != 3 {
+			t.Errorf("Server received %d requests after %d responses, want 3", n, status)
+		}
+	}
+}
+
+func TestHTTPFetcherGivesUp(t *testing.T) {
+	server, requests := flakyServer(t, 100, http.StatusBadGateway, "")
+	f := httpFetcher{client: DefaultHTTPClient(), retry: testRetryPolicy}
+
+	if _, err := f.getBody(context.Background(), server.URL); err == nil {
+		t.Fatal("getBody() succeeded against a failing server")
+	}
+	if n := requests.Load(); n != int32(testRetryPolicy.MaxAttempts) {
+		t.Errorf("Server received %d requests, want %d", n, testRetryPolicy.MaxAttempts)
+	}
+
+	// Client errors are not retried
+	notFound, requests := flakyServer(t, 100, http.StatusNotFound, "")
+	if _, err := f.getBody(context.Background(), notFound.URL); err == nil {
+		t.Fatal("getBody() succeeded against a 404")
+	}
+	if n := requests.Load(); n != 1 {
+		t.Errorf("Server received %d requests for a 404, want 1", n)
+	}
+}
+
+func TestHTTPFetcherStopsOnCancel(t *testing.T) {
+	server, _ := flakyServer(t, 100, http.StatusServiceUnavailable, "")
+	f := httpFetcher{client: DefaultHTTPClient(), retry: RetryPolicy{MaxAttempts: 10, BaseDelay: time.Hour, MaxDelay: time.Hour}}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if _, err := f.getBody(ctx, server.URL); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("getBody() error = %v, want context.DeadlineExceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("getBody() returned after %v, want it to stop waiting at the deadline", elapsed)
+	}
+}
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	p := RetryPolicy{BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}
+	for n, want := range []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 400 * time.Millisecond, 800 * time.Millisecond, time.Second, time.Second} {
+		for range 20 {
+			if d := p.backoff(n); d < want/2 || d > want {
+				t.Fatalf("backoff(%d) = %v, want between %v and %v", n, d, want/2, want)
+			}
+		}
+	}
+	if d := p.backoff(100); d > time.Second {
+		t.Errorf("backoff(100) = %v, want at most MaxDelay", d)
+	}
+}
+
+// rewriteTransport sends every request to target, counting them
+type rewriteTransport struct {
+	target   *url.URL
+	requests atomic.Int32
+}
+
+func (rt *rewriteTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	rt.requests.Add(1)
+	req = req.Clone(req.Context())
+	req.URL.Scheme, req.URL.Host = rt.target.Scheme, rt.target.Host
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestWithHTTPTransport(t *testing.T) {
+	transport := &rewriteTransport{}
+	cm := newTestChainManager(t, WithHTTPTransport(transport))
+	genesis := cm.GetTip()
+	chain := append([]*BlockHeader{genesis}, extendChain(t, genesis, 10)...)
+
+	target, err := url.Parse(newFakeUpstream(t, chain).URL)
+	if err != nil {
+		t.Fatalf("Failed to parse teranode URL: %v", err)
+	}
+	transport.target = target
+
+	// The host does not exist; only the injected transport can reach the node
+	if err := cm.SyncFromRemoteTip(context.Background(), chain[10].Hash, "http://teranode.invalid"); err != nil {
+		t.Fatalf("SyncFromRemoteTip() error = %v", err)
+	}
+	if tip := cm.GetTip(); tip.Hash != chain[10].Hash {
+		t.Errorf("Tip = height %d, want 10", tip.Height)
+	}
+	if transport.requests.Load() == 0 {
+		t.Error("No requests went through the injected transport")
+	}
+}
+
+func TestNewChainManagerStartupTimeout(t *testing.T) {
+	// A source that accepts connections and never answers
+	hung := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	t.Cleanup(hung.Close)
+
+	start := time.Now()
+	cm, err := NewChainManager("regtest", t.TempDir(), WithBootstrapURL(hung.URL), WithStartupTimeout(200*time.Millisecond))
+	if err != nil {
+		t.Fatalf("NewChainManager() error = %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("NewChainManager() returned after %v, want the startup deadline honored", elapsed)
+	}
+	if status := sourceStatus(t, cm.GetBootstrapStatus(), hung.URL); status.Error == "" {
+		t.Errorf("Source status = %+v, want the deadline recorded", status)
+	}
+}
@@ -17,6 +17,14 @@ type Chaintracks interface {
 	// Start begins the chaintracks service and returns a channel for block notifications
 	Start(ctx context.Context) (<-chan *BlockHeader, error)
 
+	// Subscribe registers a listener for tip and reorg events with its own delivery policy
+	// It can be called any number of times; the channel is closed when ctx is cancelled or unsubscribe is called
+	Subscribe(ctx context.Context, opts SubscribeOptions) (<-chan Event, func())
+
+	// SubscribeReorgs returns a channel that receives an event for every chain reorganization
+	// The channel is closed when ctx is cancelled
+	SubscribeReorgs(ctx context.Context) <-chan *ReorgEvent
+
 	// Stop gracefully shuts down the chaintracks service
 	Stop() error
 
@@ -32,6 +40,19 @@ type Chaintracks interface {
 	// GetHeaderByHash retrieves a block header by its hash
 	GetHeaderByHash(hash *chainhash.Hash) (*BlockHeader, error)
 
+	// GetHeaderByMerkleRoot retrieves the main chain block header with a merkle root
+	GetHeaderByMerkleRoot(root *chainhash.Hash) (*BlockHeader, error)
+
+	// GetMedianTimePast returns the median timestamp of the 11 blocks ending at height
+	GetMedianTimePast(height uint32) (uint32, error)
+
+	// IsValidRootsForHeights validates many merkle roots at once, with one result per check in the same order
+	IsValidRootsForHeights(ctx context.Context, checks []RootCheck) ([]RootResult, error)
+
+	// ValidateRoot reports whether a merkle root is on the main chain and how deeply confirmed, on a known fork,
+	// or beyond the tip and so not yet judgeable
+	ValidateRoot(ctx context.Context, root *chainhash.Hash, height uint32) (RootStatus, error)
+
 	// GetNetwork returns the network name (mainnet, testnet, etc.)
 	GetNetwork() (string, error)
 }
@@ -1,92 +1,52 @@
 package chaintracks
 
 import (
-	"encoding/json"
 	"fmt"
 	"log"
 	"math/big"
-	"os"
-	"path/filepath"
 	"time"
 
-	"github.com/bsv-blockchain/go-sdk/block"
 	"github.com/bsv-blockchain/go-sdk/chainhash"
 )
 
-// loadHeadersFromFile reads a binary .headers file and returns a slice of headers
-// This function performs no validation - just parsing
-func loadHeadersFromFile(path string) ([]*block.Header, error) {
-	data, err := os.ReadFile(path)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read file: %w", err)
-	}
-
-	if len(data)%80 != 0 {
-		return nil, fmt.Errorf("invalid file size: %d bytes (not multiple of 80)", len(data))
-	}
-
-	headerCount := len(data) / 80
-	headers := make([]*block.Header, 0, headerCount)
-
-	for i := 0; i < headerCount; i++ {
-		headerBytes := data[i*80 : (i+1)*80]
-		header, err := block.NewHeaderFromBytes(headerBytes)
-		if err != nil {
-			return nil, fmt.Errorf("failed to parse header at index %d: %w", i, err)
+// loadFromStore restores the chain from the header store
+// Unless a verify mode is set, no validation is performed - we trust our own checkpoint and exported files
+func (cm *ChainManager) loadFromStore() error {
+	if recoverable, ok := cm.store.(RecoverableStore); ok {
+		if err := recoverable.Recover(); err != nil {
+			return fmt.Errorf("failed to recover header store: %w", err)
 		}
-		headers = append(headers, header)
 	}
 
-	return headers, nil
-}
-
-// parseMetadata reads and parses the metadata JSON file
-func parseMetadata(path string) (*CDNMetadata, error) {
-	data, err := os.ReadFile(path)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read metadata: %w", err)
+	if err := cm.verifyStore(); err != nil {
+		return err
 	}
 
-	var metadata CDNMetadata
-	if err := json.Unmarshal(data, &metadata); err != nil {
-		return nil, fmt.Errorf("failed to parse metadata JSON: %w", err)
+	metadata, err := cm.store.ReadMetadata()
+	if err != nil {
+		return fmt.Errorf("failed to read store metadata: %w", err)
 	}
-
-	return &metadata, nil
-}
-
-// loadFromLocalFiles restores the chain from local header files
-// No validation is performed - we trust our own checkpoint and exported files
-func (cm *ChainManager) loadFromLocalFiles() error {
-	metadataPath := filepath.Join(cm.localStoragePath, cm.network+"NetBlockHeaders.json")
-	log.Printf("Loading checkpoint metadata from: %s", metadataPath)
-
-	if _, err := os.Stat(metadataPath); os.IsNotExist(err) {
-		log.Printf("No checkpoint files found at %s, starting with empty chain", metadataPath)
+	if metadata == nil {
+		log.Printf("No stored headers found, starting with empty chain")
 		return nil
 	}
 
-	metadata, err := parseMetadata(metadataPath)
-	if err != nil {
-		return fmt.Errorf("failed to parse local metadata: %w", err)
-	}
-
 	log.Printf("Found %d checkpoint files to load", len(metadata.Files))
 
 	for _, fileEntry := range metadata.Files {
-		filePath := filepath.Join(cm.localStoragePath, fileEntry.FileName)
-		headers, err := loadHeadersFromFile(filePath)
+		if fileEntry.Count == 0 {
+			continue
+		}
+
+		lastHeight := fileEntry.FirstHeight + uint32(fileEntry.Count) - 1
+		blockHeaders, err := cm.store.LoadRange(fileEntry.FirstHeight, lastHeight)
 		if err != nil {
 			return fmt.Errorf("failed to load file %s: %w", fileEntry.FileName, err)
 		}
 
-		blockHeaders := make([]*BlockHeader, 0, len(headers))
-
-		// Calculate chainwork incrementally
+		// Calculate chainwork incrementally unless the store persisted it
 		var prevChainWork *big.Int
-		if fileEntry.FirstHeight == 0 {
-			prevChainWork = big.NewInt(0)
-		} else {
+		if fileEntry.FirstHeight > 0 {
 			// Get the chainwork from the previous block (last block of previous file)
 			prevHeader, err := cm.GetHeaderByHeight(fileEntry.FirstHeight - 1)
 			if err != nil {
@@ -95,69 +55,145 @@ func (cm *ChainManager) loadFromLocalFiles() error {
 			prevChainWork = prevHeader.ChainWork
 		}
 
-		for i, header := range headers {
-			height := fileEntry.FirstHeight + uint32(i)
-
-			var chainWork *big.Int
-			if height == 0 {
-				chainWork = big.NewInt(0)
-			} else {
-				work := CalculateWork(header.Bits)
-				chainWork = new(big.Int).Add(prevChainWork, work)
-				prevChainWork = chainWork
+		for _, header := range blockHeaders {
+			if header.ChainWork == nil {
+				header.ChainWork = chainWorkAt(prevChainWork, header.Height, header.Bits)
 			}
+			prevChainWork = header.ChainWork
+		}
 
-			blockHeader := &BlockHeader{
-				Header:    header,
-				Height:    height,
-				Hash:      header.Hash(),
-				ChainWork: chainWork,
-			}
+		cm.applyBranch(blockHeaders)
+	}
 
-			blockHeaders = append(blockHeaders, blockHeader)
-		}
+	return cm.loadForkHeaders()
+}
 
-		if err := cm.SetChainTip(blockHeaders); err != nil {
-			return fmt.Errorf("failed to set chain tip for file %s: %w", fileEntry.FileName, err)
-		}
+// loadForkHeaders restores recent side chain headers from stores that persist them
+func (cm *ChainManager) loadForkHeaders() error {
+	forkStore, ok := cm.store.(ForkStore)
+	if !ok {
+		return nil
 	}
 
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+
+	if cm.tip == nil {
+		return nil
+	}
+
+	// Match the orphan retention of pruneOrphans
+	forks, err := forkStore.LoadForkHeaders(orphanHorizon(cm.tip.Height))
+	if err != nil {
+		return fmt.Errorf("failed to load fork headers: %w", err)
+	}
+	for _, header := range forks {
+		cm.byHash[header.Hash] = header
+		cm.indexSideHeader(header)
+	}
+
+	if len(forks) > 0 {
+		log.Printf("Restored %d fork headers", len(forks))
+	}
 	return nil
 }
 
 // SetChainTip updates the chain tip with a new branch of headers
 // branchHeaders should be ordered from oldest to newest
 // The parent of branchHeaders[0] must exist in our current chain
+// Every header is validated before the branch is applied
 func (cm *ChainManager) SetChainTip(branchHeaders []*BlockHeader) error {
+	if err := cm.validateHeaders(branchHeaders, "SetChainTip"); err != nil {
+		return err
+	}
+
+	return cm.setChainTip(branchHeaders)
+}
+
+// setChainTip applies an already validated branch of headers and persists it. A branch without more work than
+// the current tip, which a concurrent import may have moved since the caller checked, is skipped.
+func (cm *ChainManager) setChainTip(branchHeaders []*BlockHeader) error {
 	if len(branchHeaders) == 0 {
 		return nil
 	}
 
+	cm.importMu.Lock()
+	defer cm.importMu.Unlock()
+
+	connected := cm.applyBranch(branchHeaders)
+	if connected == nil {
+		last := branchHeaders[len(branchHeaders)-1]
+		log.Printf("Skipping branch to %s at height %d: the chain tip has at least as much work", last.Hash, last.Height)
+		return nil
+	}
+	cm.noteHeaderReceived()
+
+	// Persist the connected branch so the store follows the main chain
+	startStore := time.Now()
+	if err := cm.store.PutBranch(connected); err != nil {
+		return fmt.Errorf("failed to store headers: %w", err)
+	}
+
+	// Stored fork headers are pruned on the same horizon as the in-memory ones
+	if forkStore, ok := cm.store.(ForkStore); ok {
+		if err := forkStore.PruneForkHeaders(orphanHorizon(connected[len(connected)-1].Height)); err != nil {
+			return fmt.Errorf("failed to prune fork headers: %w", err)
+		}
+	}
+	storeDuration := time.Since(startStore)
+
+	if storeDuration > 100*time.Millisecond {
+		log.Printf("SetChainTip timing: store=%v", storeDuration)
+	}
+
+	return nil
+}
+
+// applyBranch makes a branch of headers the in-memory main chain and publishes the tip change.
+// It returns the branch extended with any side chain ancestors that were connected, or nil if the branch
+// does not have more work than the current tip.
+func (cm *ChainManager) applyBranch(branchHeaders []*BlockHeader) []*BlockHeader {
 	// Update in-memory chain
 	cm.mu.Lock()
 
+	if last := branchHeaders[len(branchHeaders)-1]; cm.tip != nil && last.ChainWork.Cmp(cm.tip.ChainWork) <= 0 {
+		cm.mu.Unlock()
+		return nil
+	}
+
+	// Include any stored side chain ancestors so the branch connects to the main chain
+	branchHeaders = cm.connectBranch(branchHeaders)
+	reorg := cm.detectReorg(branchHeaders)
+
 	// Update byHeight for all blocks in the new branch
 	for _, header := range branchHeaders {
-		// hash := header.Hash()
-
 		// Ensure slice is large enough
 		for uint32(len(cm.byHeight)) <= header.Height {
 			cm.byHeight = append(cm.byHeight, chainhash.Hash{})
 		}
 
-		// Update byHeight and byHash
+		// Update byHeight, byHash and the merkle root index; a displaced header stays as a side chain header
+		if cm.byHeight[header.Height] != header.Hash {
+			cm.unindexMerkleRoot(header.Height)
+			cm.displaceHeader(header.Height)
+		}
 		cm.byHeight[header.Height] = header.Hash
 		cm.byHash[header.Hash] = header
+		cm.indexMerkleRoot(header)
 	}
 
 	// Clear any blocks after the new tip (handles reorg to shorter chain)
-	newTipHeight := branchHeaders[len(branchHeaders)-1].Height
-	if uint32(len(cm.byHeight)) > newTipHeight+1 {
-		cm.byHeight = cm.byHeight[:newTipHeight+1]
+	newTip := branchHeaders[len(branchHeaders)-1]
+	if uint32(len(cm.byHeight)) > newTip.Height+1 {
+		for height := newTip.Height + 1; height < uint32(len(cm.byHeight)); height++ {
+			cm.unindexMerkleRoot(height)
+			cm.displaceHeader(height)
+		}
+		cm.byHeight = cm.byHeight[:newTip.Height+1]
 	}
 
-	// Always set tip to the last header in the branch
-	cm.tip = branchHeaders[len(branchHeaders)-1]
+	// The branch has more work, so its last header is the new tip
+	cm.tip = newTip
 
 	// Prune orphaned headers older than 100 blocks
 	cm.pruneOrphans()
@@ -166,6 +202,14 @@ func (cm *ChainManager) SetChainTip(branchHeaders []*BlockHeader) error {
 	msgChan := cm.msgChan
 	cm.mu.Unlock()
 
+	// Publish the reorg before the new tip so subscribers can roll back first
+	if reorg != nil {
+		log.Printf("Chain reorg at height %d: %d blocks disconnected, %d connected",
+			reorg.Disconnected[0].Height, len(reorg.Disconnected), len(reorg.Connected))
+		cm.events.publish(Event{Type: EventReorg, Reorg: reorg})
+	}
+	cm.events.publish(Event{Type: EventTip, Tip: newTip})
+
 	// Publish tip change event outside the lock (non-blocking)
 	if msgChan != nil {
 		// Drain any old tip (we only care about the latest)
@@ -176,162 +220,11 @@ func (cm *ChainManager) SetChainTip(branchHeaders []*BlockHeader) error {
 
 		// Send the new tip (non-blocking)
 		select {
-		case msgChan <- cm.tip:
+		case msgChan <- newTip:
 		default:
 			// Channel full after drain shouldn't happen, but skip if it does
 		}
 	}
 
-	// Write headers to files
-	startWrite := time.Now()
-	if err := cm.writeHeadersToFiles(branchHeaders); err != nil {
-		return fmt.Errorf("failed to write headers to files: %w", err)
-	}
-	writeDuration := time.Since(startWrite)
-
-	// Update metadata
-	startMeta := time.Now()
-	if err := cm.updateMetadataForTip(); err != nil {
-		return fmt.Errorf("failed to update metadata: %w", err)
-	}
-	metaDuration := time.Since(startMeta)
-
-	if writeDuration > 100*time.Millisecond || metaDuration > 100*time.Millisecond {
-		log.Printf("SetChainTip timing: write=%v meta=%v", writeDuration, metaDuration)
-	}
-
-	return nil
-}
-
-// writeHeadersToFiles writes headers to the appropriate .headers files
-func (cm *ChainManager) writeHeadersToFiles(headers []*BlockHeader) error {
-	if cm.localStoragePath == "" {
-		return nil
-	}
-
-	if err := os.MkdirAll(cm.localStoragePath, 0755); err != nil {
-		return fmt.Errorf("failed to create storage directory: %w", err)
-	}
-
-	// Group headers by file
-	fileHeaders := make(map[uint32][]*BlockHeader)
-	for _, header := range headers {
-		fileIndex := header.Height / 100000
-		fileHeaders[fileIndex] = append(fileHeaders[fileIndex], header)
-	}
-
-	// Write to each file
-	for fileIndex, hdrs := range fileHeaders {
-		fileName := fmt.Sprintf("%sNet_%d.headers", cm.network, fileIndex)
-		filePath := filepath.Join(cm.localStoragePath, fileName)
-
-		// Open file for read/write (create if doesn't exist)
-		f, err := os.OpenFile(filePath, os.O_RDWR|os.O_CREATE, 0644)
-		if err != nil {
-			return fmt.Errorf("failed to open file %s: %w", fileName, err)
-		}
-
-		// Write each header at its position
-		for _, header := range hdrs {
-			positionInFile := (header.Height % 100000) * 80
-			if _, err := f.Seek(int64(positionInFile), 0); err != nil {
-				f.Close()
-				return fmt.Errorf("failed to seek in file: %w", err)
-			}
-
-			headerBytes := header.Header.Bytes()
-			if _, err := f.Write(headerBytes); err != nil {
-				f.Close()
-				return fmt.Errorf("failed to write header: %w", err)
-			}
-		}
-
-		f.Close()
-	}
-
-	return nil
-}
-
-// updateMetadataForTip updates the metadata JSON with current chain tip info
-func (cm *ChainManager) updateMetadataForTip() error {
-	if cm.localStoragePath == "" {
-		return nil
-	}
-
-	metadataPath := filepath.Join(cm.localStoragePath, cm.network+"NetBlockHeaders.json")
-
-	// Read existing metadata or create new
-	var metadata *CDNMetadata
-	if _, err := os.Stat(metadataPath); os.IsNotExist(err) {
-		metadata = &CDNMetadata{
-			RootFolder:     "",
-			JSONFilename:   cm.network + "NetBlockHeaders.json",
-			HeadersPerFile: 100000,
-			Files:          []CDNFileEntry{},
-		}
-	} else {
-		metadata, err = parseMetadata(metadataPath)
-		if err != nil {
-			return fmt.Errorf("failed to parse existing metadata: %w", err)
-		}
-	}
-
-	// Update file entries based on current chain
-	tip := cm.GetTip()
-	if tip == nil {
-		return nil
-	}
-
-	fileIndex := tip.Height / 100000
-
-	// Ensure we have entries for all files up to the current tip
-	for i := uint32(len(metadata.Files)); i <= fileIndex; i++ {
-		metadata.Files = append(metadata.Files, CDNFileEntry{
-			Chain:         cm.network,
-			Count:         0,
-			FileHash:      "",
-			FileName:      fmt.Sprintf("%sNet_%d.headers", cm.network, i),
-			FirstHeight:   i * 100000,
-			LastChainWork: "0000000000000000000000000000000000000000000000000000000000000000",
-			PrevChainWork: "0000000000000000000000000000000000000000000000000000000000000000",
-			SourceURL:     "",
-		})
-	}
-
-	// Update the last file entry with current tip info
-	lastFileEntry := &metadata.Files[fileIndex]
-	lastFileEntry.Count = int((tip.Height % 100000) + 1)
-	lastFileEntry.LastChainWork = ChainWorkToHex(tip.ChainWork)
-	lastFileEntry.LastHash = tip.Hash
-
-	// Get previous header for prevChainWork and prevHash
-	if tip.Height > 0 {
-		prevHeader, err := cm.GetHeaderByHeight(tip.Height - 1)
-		if err == nil {
-			lastFileEntry.PrevChainWork = ChainWorkToHex(prevHeader.ChainWork)
-			lastFileEntry.PrevHash = prevHeader.Hash
-		}
-	}
-
-	// Write updated metadata
-	return cm.writeLocalMetadata(metadata)
-}
-
-// writeLocalMetadata writes the metadata JSON to local storage
-func (cm *ChainManager) writeLocalMetadata(metadata *CDNMetadata) error {
-	if cm.localStoragePath == "" {
-		return nil
-	}
-
-	data, err := json.MarshalIndent(metadata, "", "  ")
-	if err != nil {
-		return fmt.Errorf("failed to marshal metadata: %w", err)
-	}
-
-	metadataPath := filepath.Join(cm.localStoragePath, cm.network+"NetBlockHeaders.json")
-	if err := os.WriteFile(metadataPath, data, 0644); err != nil {
-		return fmt.Errorf("failed to write metadata: %w", err)
-	}
-
-	return nil
+	return branchHeaders
 }
@@ -0,0 +1,92 @@
+package chaintracks
+
+import (
+	"fmt"
+	"sync"
+)
+
+// MemoryStore keeps headers in memory only, for tests and ephemeral deployments
+type MemoryStore struct {
+	mu      sync.RWMutex
+	network string
+	headers []*BlockHeader // Main chain headers indexed by height
+}
+
+// NewMemoryStore creates an empty in-memory store for a network
+func NewMemoryStore(network string) *MemoryStore {
+	return &MemoryStore{network: network}
+}
+
+// PutBranch stores headers at their heights and discards anything above the last one
+func (ms *MemoryStore) PutBranch(headers []*BlockHeader) error {
+	if len(headers) == 0 {
+		return nil
+	}
+
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	first := headers[0].Height
+	if first > uint32(len(ms.headers)) {
+		return fmt.Errorf("%w: branch starts at height %d but store ends at %d", ErrBrokenChain, first, len(ms.headers))
+	}
+
+	ms.headers = append(ms.headers[:first], headers...)
+	return nil
+}
+
+// TruncateAbove discards all headers above height
+func (ms *MemoryStore) TruncateAbove(height uint32) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	if uint32(len(ms.headers)) > height+1 {
+		ms.headers = ms.headers[:height+1]
+	}
+	return nil
+}
+
+// LoadRange returns headers from height from to height to (inclusive)
+func (ms *MemoryStore) LoadRange(from, to uint32) ([]*BlockHeader, error) {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+
+	if to < from {
+		return nil, nil
+	}
+	if to >= uint32(len(ms.headers)) {
+		return nil, fmt.Errorf("%w: height %d is above the stored tip", ErrHeaderNotFound, to)
+	}
+
+	return append([]*BlockHeader(nil), ms.headers[from:to+1]...), nil
+}
+
+// ReadMetadata describes the stored headers in the CDN layout, or returns nil if the store is empty
+func (ms *MemoryStore) ReadMetadata() (*CDNMetadata, error) {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+
+	if len(ms.headers) == 0 {
+		return nil, nil
+	}
+
+	metadata := &CDNMetadata{
+		JSONFilename:   ms.network + "NetBlockHeaders.json",
+		HeadersPerFile: headersPerFile,
+	}
+
+	for first := 0; first < len(ms.headers); first += headersPerFile {
+		last := ms.headers[min(first+headersPerFile, len(ms.headers))-1]
+		metadata.Files = append(metadata.Files, CDNFileEntry{
+			Chain:         ms.network,
+			Count:         int(last.Height) - first + 1,
+			FileName:      fileNameForIndex(ms.network, uint32(first/headersPerFile)),
+			FirstHeight:   uint32(first),
+			LastChainWork: ChainWorkToHex(last.ChainWork),
+			LastHash:      last.Hash,
+		})
+	}
+	linkMetadataFiles(metadata)
+
+	return metadata, nil
+}
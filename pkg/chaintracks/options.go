@@ -0,0 +1,144 @@
+package chaintracks
+
+import (
+	"net/http"
+	"time"
+)
+
+// Option configures optional ChainManager behavior
+type Option func(*ChainManager)
+
+// WithBootstrapURL syncs from a remote teranode before NewChainManager returns.
+// An empty URL is ignored.
+func WithBootstrapURL(url string) Option {
+	return func(cm *ChainManager) {
+		cm.bootstrapURL = url
+	}
+}
+
+// WithBootstrapCDN bulk imports headers from a Chaintracks CDN before NewChainManager returns.
+// It runs before the WithBootstrapURL sync. An empty URL is ignored.
+func WithBootstrapCDN(url string) Option {
+	return func(cm *ChainManager) {
+		cm.bootstrapCDN = url
+	}
+}
+
+// WithUnhashedCDNFiles accepts CDN manifest entries without a FileHash, checking only how their headers link.
+// By default such entries are refused, since nothing then ties a file's content to the manifest.
+func WithUnhashedCDNFiles(allow bool) Option {
+	return func(cm *ChainManager) {
+		cm.unhashedCDNFiles = allow
+	}
+}
+
+// WithBootstrapSources syncs from several upstreams before NewChainManager returns, choosing the chain with the
+// most verified chainwork and flagging sources that disagree with it. Sources set with WithBootstrapCDN and
+// WithBootstrapURL are added to the list.
+func WithBootstrapSources(sources ...BootstrapSource) Option {
+	return func(cm *ChainManager) {
+		cm.extraSources = append(cm.extraSources, sources...)
+	}
+}
+
+// WithForwardSync syncs from the bootstrap URL forward by height, with up to window batch requests in parallel,
+// instead of walking back from its tip. The bootstrap node must serve the Chaintracks v2 API.
+func WithForwardSync(window int) Option {
+	return func(cm *ChainManager) {
+		cm.syncWindow = window
+	}
+}
+
+// WithNodePeers follows SV Nodes over the legacy Bitcoin P2P protocol once Start is called, alongside the
+// Teranode P2P topic. Each address is host:port, with the network's default port used if none is given.
+func WithNodePeers(addrs ...string) Option {
+	return func(cm *ChainManager) {
+		cm.nodePeers = append(cm.nodePeers, addrs...)
+	}
+}
+
+// WithResync checks the bootstrap sources and the best block announced by P2P peers every interval once Start is
+// called, and syncs any headers that were missed. Zero disables the check.
+func WithResync(interval time.Duration) Option {
+	return func(cm *ChainManager) {
+		cm.resyncInterval = interval
+	}
+}
+
+// WithDataHubPolicy replaces DefaultDataHubPolicy for the DataHub URLs that P2P peers announce with blocks.
+// Configured bootstrap sources are trusted and not subject to it.
+func WithDataHubPolicy(policy DataHubPolicy) Option {
+	return func(cm *ChainManager) {
+		cm.dataHubPolicy = policy
+	}
+}
+
+// WithPeerBan ignores block announcements from a peer for duration once its penalties, net of credit for good
+// announcements, reach threshold. Zero threshold disables banning; scores are still tracked.
+func WithPeerBan(threshold int, duration time.Duration) Option {
+	return func(cm *ChainManager) {
+		cm.peerBook = newPeerBook(threshold, duration)
+	}
+}
+
+// WithCrawlWorkers caps how many crawl-backs for P2P announcements with unknown parents run at once.
+// Announcements of a block or its descendants while a crawl-back to it is in flight wait for that one.
+func WithCrawlWorkers(n int) Option {
+	return func(cm *ChainManager) {
+		cm.crawlWorkers = n
+	}
+}
+
+// WithHTTPClient sets the client for requests to bootstrap sources, replacing DefaultHTTPClient.
+// DataHub URLs announced by P2P peers are fetched with the DataHub policy's client instead.
+func WithHTTPClient(client *http.Client) Option {
+	return func(cm *ChainManager) {
+		cm.httpClient = client
+	}
+}
+
+// WithHTTPTransport sends requests to bootstrap sources through transport, for tests or proxies.
+// It applies to a copy of the client from WithHTTPClient or DefaultHTTPClient.
+func WithHTTPTransport(transport http.RoundTripper) Option {
+	return func(cm *ChainManager) {
+		cm.httpTransport = transport
+	}
+}
+
+// WithRetryPolicy replaces DefaultRetryPolicy for outbound sync requests, including crawl-backs
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(cm *ChainManager) {
+		cm.retryPolicy = policy
+	}
+}
+
+// WithStartupTimeout bounds how long NewChainManager spends syncing from bootstrap sources. At the deadline it
+// returns with the headers synced so far. Zero waits as long as the sources take.
+func WithStartupTimeout(timeout time.Duration) Option {
+	return func(cm *ChainManager) {
+		cm.startupTimeout = timeout
+	}
+}
+
+// WithCheckpoints adds operator-supplied checkpoints on top of the network's built-in table.
+// A checkpoint at the same height as a built-in one replaces it.
+func WithCheckpoints(checkpoints ...Checkpoint) Option {
+	return func(cm *ChainManager) {
+		cm.extraCheckpoints = append(cm.extraCheckpoints, checkpoints...)
+	}
+}
+
+// WithVerify checks the header store on startup and handles corruption according to mode.
+// VerifyRedownload requires at least one bootstrap source.
+func WithVerify(mode VerifyMode) Option {
+	return func(cm *ChainManager) {
+		cm.verifyMode = mode
+	}
+}
+
+// WithHeaderStore replaces the default FileStore with a custom header store
+func WithHeaderStore(store HeaderStore) Option {
+	return func(cm *ChainManager) {
+		cm.store = store
+	}
+}
@@ -12,6 +12,7 @@ import (
 
 	p2p "github.com/bsv-blockchain/go-p2p-message-bus"
 	"github.com/bsv-blockchain/go-sdk/block"
+	"github.com/bsv-blockchain/go-sdk/chainhash"
 	"github.com/libp2p/go-libp2p/core/crypto"
 )
 
@@ -52,6 +53,16 @@ func (cm *ChainManager) Start(ctx context.Context) (<-chan *BlockHeader, error)
 
 	msgChan := client.Subscribe(topic)
 
+	for _, addr := range cm.nodePeers {
+		log.Printf("Following SV Node peer: %s", addr)
+		go cm.FollowNode(ctx, addr)
+	}
+
+	if cm.resyncInterval > 0 {
+		log.Printf("Checking upstreams for missed headers every %v", cm.resyncInterval)
+		go cm.runResync(ctx, cm.resyncInterval)
+	}
+
 	// Start message handler goroutine
 	go func() {
 		for {
@@ -60,7 +71,7 @@ func (cm *ChainManager) Start(ctx context.Context) (<-chan *BlockHeader, error)
 				close(cm.msgChan)
 				return
 			case msg := <-msgChan:
-				if err := cm.handleBlockMessage(ctx, msg.Data); err != nil {
+				if err := cm.handleBlockMessage(ctx, msg.FromID, msg.Data); err != nil {
 					log.Printf("Error handling block message: %v", err)
 				}
 			}
@@ -84,7 +95,7 @@ func (cm *ChainManager) Stop() error {
 	return err
 }
 
-// GetPeers returns information about connected P2P peers
+// GetPeers returns information about connected P2P peers, with the reputation of those that announced blocks
 // Returns empty slice if P2P is not running
 func (cm *ChainManager) GetPeers() []PeerInfo {
 	cm.mu.RLock()
@@ -95,6 +106,7 @@ func (cm *ChainManager) GetPeers() []PeerInfo {
 	}
 
 	p2pPeers := cm.p2pClient.GetPeers()
+	scores := cm.peerBook.snapshot()
 	peers := make([]PeerInfo, len(p2pPeers))
 	for i, p := range p2pPeers {
 		peers[i] = PeerInfo{
@@ -102,51 +114,102 @@ func (cm *ChainManager) GetPeers() []PeerInfo {
 			Name:  p.Name,
 			Addrs: p.Addrs,
 		}
+		if score, ok := scores[p.ID]; ok {
+			peers[i].Reputation = &score
+		}
 	}
 	return peers
 }
 
-// handleBlockMessage processes a received block message
-func (cm *ChainManager) handleBlockMessage(ctx context.Context, data []byte) error {
+// handleBlockMessage processes a received block message and scores the announcing peer.
+// from is the P2P sender; a message whose PeerID names another peer is dropped and charged to the sender.
+func (cm *ChainManager) handleBlockMessage(ctx context.Context, from string, data []byte) error {
 	log.Printf("Raw block message: %s", string(data))
 
 	var blockMsg BlockMessage
 	if err := json.Unmarshal(data, &blockMsg); err != nil {
-		return fmt.Errorf("failed to unmarshal block message: %w", err)
+		cm.peerBook.penalize(from, OffenseMalformed)
+		return fmt.Errorf("%w: failed to unmarshal: %v", errMalformedMessage, err)
+	}
+
+	// Scores are keyed on the PeerID, so it must not let a sender evade its own ban or frame another peer
+	peer := blockMsg.PeerID
+	if peer == "" {
+		peer = from
+	} else if peer != from {
+		cm.peerBook.penalize(from, OffenseMalformed)
+		return fmt.Errorf("%w: peer ID %s does not match sender %s", errMalformedMessage, peer, from)
+	}
+	if !cm.peerBook.allow(peer) {
+		return nil
+	}
+
+	// Announcements queued for a crawl-back are scored once it finishes
+	queued, err := cm.processBlockMessage(ctx, &blockMsg, peer)
+	if !queued {
+		cm.scoreAnnouncement(peer, err)
 	}
+	return err
+}
 
-	log.Printf("Received block: height=%d hash=%s from=%s datahub=%s", blockMsg.Height, blockMsg.Hash, blockMsg.PeerID, blockMsg.DataHubURL)
+// processBlockMessage imports the announced header. If its parent is unknown, the header is queued for a crawl-back
+// through the peer's DataHub and queued is true.
+func (cm *ChainManager) processBlockMessage(ctx context.Context, blockMsg *BlockMessage, peer string) (queued bool, err error) {
+	log.Printf("Received block: height=%d hash=%s from=%s datahub=%s", blockMsg.Height, blockMsg.Hash, peer, blockMsg.DataHubURL)
 
 	// Decode header from hex
 	headerBytes, err := hex.DecodeString(blockMsg.Header)
 	if err != nil {
-		return fmt.Errorf("failed to decode header hex: %w", err)
+		return false, fmt.Errorf("%w: failed to decode header hex: %v", errMalformedMessage, err)
 	}
 
 	if len(headerBytes) != 80 {
-		return fmt.Errorf("invalid header size: %d bytes", len(headerBytes))
+		return false, fmt.Errorf("%w: invalid header size: %d bytes", errMalformedMessage, len(headerBytes))
 	}
 
 	header, err := block.NewHeaderFromBytes(headerBytes)
 	if err != nil {
-		return fmt.Errorf("failed to parse header: %w", err)
+		return false, fmt.Errorf("%w: failed to parse header: %v", errMalformedMessage, err)
+	}
+
+	// Repeat announcements of a known block cost nothing
+	hash := header.Hash()
+	if _, err := cm.GetHeaderByHash(&hash); err == nil {
+		return false, nil
 	}
 
 	// Check if parent exists in our chain
 	parentHash := header.PrevHash
-	_, err = cm.GetHeaderByHash(&parentHash)
+	parent, err := cm.GetHeaderByHash(&parentHash)
 	if err == nil {
-		// Parent exists - simple case
-		return cm.addBlockToChain(header, blockMsg.Height)
+		return false, cm.addAnnouncedBlock(header, parent, blockMsg.Height, peer)
 	}
 
 	// Parent doesn't exist - need to crawl back
 	log.Printf("Parent not found for block %s, crawling back...", blockMsg.Hash)
-	return cm.crawlBackAndMerge(ctx, header, blockMsg.Height, blockMsg.DataHubURL)
+	err = cm.queueAnnouncement(ctx, &announcedBlock{
+		header:     header,
+		height:     blockMsg.Height,
+		dataHubURL: blockMsg.DataHubURL,
+		peer:       peer,
+	})
+	return err == nil, err
 }
 
-// addBlockToChain processes a block and evaluates if it becomes the new chain tip
-func (cm *ChainManager) addBlockToChain(header *block.Header, height uint32) error {
+// addAnnouncedBlock adds an announced block whose parent is known. The height follows from the parent, so a peer
+// claiming another one is not trusted.
+func (cm *ChainManager) addAnnouncedBlock(header *block.Header, parent *BlockHeader, height uint32, peer string) error {
+	if height != parent.Height+1 {
+		return fmt.Errorf("%w: block %s announced at height %d by %s, parent is at height %d",
+			ErrHeightMismatch, header.Hash(), height, peer, parent.Height)
+	}
+	cm.noteUpstreamHeight(height)
+	return cm.addBlockToChain(header, peer)
+}
+
+// addBlockToChain processes a block whose parent is known and evaluates if it becomes the new chain tip
+// source identifies the peer that announced the block and is used to attribute rejections
+func (cm *ChainManager) addBlockToChain(header *block.Header, source string) error {
 	// Get parent to calculate chainwork
 	parentHash := header.PrevHash
 	parentHeader, err := cm.GetHeaderByHash(&parentHash)
@@ -161,11 +224,16 @@ func (cm *ChainManager) addBlockToChain(header *block.Header, height uint32) err
 	// Create BlockHeader
 	blockHeader := &BlockHeader{
 		Header:    header,
-		Height:    height,
+		Height:    parentHeader.Height + 1,
 		Hash:      header.Hash(),
 		ChainWork: chainWork,
 	}
 
+	// Reject the header before it is stored if it fails validation
+	if err := cm.validateHeaders([]*BlockHeader{blockHeader}, source); err != nil {
+		return err
+	}
+
 	// Always add the header to byHash first
 	if err := cm.AddHeader(blockHeader); err != nil {
 		return fmt.Errorf("failed to add header: %w", err)
@@ -175,18 +243,41 @@ func (cm *ChainManager) addBlockToChain(header *block.Header, height uint32) err
 	currentTip := cm.GetTip()
 	if currentTip == nil || blockHeader.ChainWork.Cmp(currentTip.ChainWork) > 0 {
 		log.Printf("New tip: height=%d chainwork=%s", blockHeader.Height, blockHeader.ChainWork.String())
-		return cm.SetChainTip([]*BlockHeader{blockHeader})
+		return cm.setChainTip([]*BlockHeader{blockHeader})
 	}
 
 	log.Printf("Block added as orphan/alternate chain: height=%d", blockHeader.Height)
 	return nil
 }
 
-// crawlBackAndMerge fetches missing parents until we find a connection to our chain
-func (cm *ChainManager) crawlBackAndMerge(ctx context.Context, header *block.Header, height uint32, dataHubURL string) error {
+// crawlBackAndMerge fetches missing parents from the announcing peer's DataHub until we find a connection to
+// our chain. The URL must pass the DataHub policy, and the announced height is checked once the block is placed.
+func (cm *ChainManager) crawlBackAndMerge(ctx context.Context, blockHash chainhash.Hash, height uint32, dataHubURL string) error {
+	if err := cm.dataHubPolicy.Check(dataHubURL); err != nil {
+		return err
+	}
+	cm.noteAnnouncement(blockHash, height, dataHubURL)
+
 	// Use the shared sync logic to walk backwards and find common ancestor
-	blockHash := header.Hash()
-	return cm.SyncFromRemoteTip(blockHash, dataHubURL)
+	if err := cm.syncFromRemoteTip(ctx, blockHash, dataHubURL, cm.dataHubFetcher); err != nil {
+		if ctx.Err() != nil {
+			// Shutting down is not the peer's fault
+			return ctx.Err()
+		}
+		return fmt.Errorf("%w from %s: %w", errCrawlFailed, dataHubURL, err)
+	}
+	defer cm.clearAnnouncement(blockHash)
+
+	header, err := cm.GetHeaderByHash(&blockHash)
+	if err != nil {
+		// The local chain had more work and the branch was not imported
+		return nil
+	}
+	if header.Height != height {
+		return fmt.Errorf("%w: block %s announced at height %d, chain places it at height %d", ErrHeightMismatch, blockHash, height, header.Height)
+	}
+	cm.noteUpstreamHeight(height)
+	return nil
 }
 
 // loadOrGeneratePrivateKey loads a private key from file or generates a new one
@@ -0,0 +1,215 @@
+package chaintracks
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// blockMessage encodes a P2P block announcement from peer-1
+func blockMessage(t *testing.T, header *BlockHeader, height uint32, dataHubURL string) []byte {
+	t.Helper()
+	return peerBlockMessage(t, "peer-1", header, height, dataHubURL)
+}
+
+// peerBlockMessage encodes a P2P block announcement from peer
+func peerBlockMessage(t *testing.T, peer string, header *BlockHeader, height uint32, dataHubURL string) []byte {
+	t.Helper()
+
+	data, err := json.Marshal(BlockMessage{
+		PeerID:     peer,
+		DataHubURL: dataHubURL,
+		Hash:       header.Hash,
+		Height:     height,
+		Header:     hex.EncodeToString(header.Header.Bytes()),
+	})
+	if err != nil {
+		t.Fatalf("Failed to encode block message: %v", err)
+	}
+	return data
+}
+
+func TestHandleBlockMessageDerivesHeight(t *testing.T) {
+	cm := newTestChainManager(t)
+	next := extendChain(t, cm.GetTip(), 1)[0]
+
+	err := cm.handleBlockMessage(context.Background(), "peer-1", blockMessage(t, next, 5, ""))
+	if !errors.Is(err, ErrHeightMismatch) {
+		t.Fatalf("handleBlockMessage() with a wrong height error = %v, want ErrHeightMismatch", err)
+	}
+	if _, err := cm.GetHeaderByHash(&next.Hash); err == nil {
+		t.Fatal("Header with a wrong announced height was stored")
+	}
+
+	if err := cm.handleBlockMessage(context.Background(), "peer-1", blockMessage(t, next, 1, "")); err != nil {
+		t.Fatalf("handleBlockMessage() error = %v", err)
+	}
+	if tip := cm.GetTip(); tip.Hash != next.Hash || tip.Height != 1 {
+		t.Errorf("Tip = height %d %s, want height 1 %s", tip.Height, tip.Hash, next.Hash)
+	}
+}
+
+func TestHandleBlockMessageCrawlsBack(t *testing.T) {
+	cm := newTestChainManager(t, WithDataHubPolicy(DataHubPolicy{AllowPrivate: true}))
+	genesis := cm.GetTip()
+	chain := append([]*BlockHeader{genesis}, extendChain(t, genesis, 10)...)
+	teranode := newFakeUpstream(t, chain)
+
+	if err := cm.handleBlockMessage(context.Background(), "peer-1", blockMessage(t, chain[10], 10, teranode.URL)); err != nil {
+		t.Fatalf("handleBlockMessage() error = %v", err)
+	}
+	cm.crawls.wg.Wait()
+	if tip := cm.GetTip(); tip.Hash != chain[10].Hash {
+		t.Fatalf("Tip after crawl-back = height %d, want 10", tip.Height)
+	}
+	if status := cm.GetSyncStatus(); status.UpstreamHeight != 10 || status.BlocksBehind != 0 {
+		t.Errorf("Status after crawl-back = %+v, want upstream height 10", status)
+	}
+}
+
+func TestHandleBlockMessageRejectsCrawledHeightMismatch(t *testing.T) {
+	cm := newTestChainManager(t, WithDataHubPolicy(DataHubPolicy{AllowPrivate: true}))
+	genesis := cm.GetTip()
+	chain := append([]*BlockHeader{genesis}, extendChain(t, genesis, 10)...)
+	teranode := newFakeUpstream(t, chain)
+
+	if err := cm.handleBlockMessage(context.Background(), "peer-1", blockMessage(t, chain[10], 500000, teranode.URL)); err != nil {
+		t.Fatalf("handleBlockMessage() error = %v", err)
+	}
+	cm.crawls.wg.Wait()
+	if score := cm.GetPeerScores()["peer-1"]; score.LastOffense != OffenseHeightMismatch {
+		t.Errorf("Peer score = %+v, want a height mismatch", score)
+	}
+	if status := cm.GetSyncStatus(); status.UpstreamHeight != 0 {
+		t.Errorf("UpstreamHeight = %d, want the claimed height to be discarded", status.UpstreamHeight)
+	}
+}
+
+func TestHandleBlockMessageEnforcesDataHubPolicy(t *testing.T) {
+	cm := newTestChainManager(t)
+	genesis := cm.GetTip()
+	chain := append([]*BlockHeader{genesis}, extendChain(t, genesis, 10)...)
+
+	var requests atomic.Int32
+	internal := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		http.NotFound(w, r)
+	}))
+	t.Cleanup(internal.Close)
+
+	// The default policy refuses a peer pointing crawl-back at a loopback address
+	err := cm.handleBlockMessage(context.Background(), "peer-1", blockMessage(t, chain[10], 10, internal.URL))
+	if !errors.Is(err, ErrDataHubDenied) {
+		t.Fatalf("handleBlockMessage() error = %v, want ErrDataHubDenied", err)
+	}
+	if n := requests.Load(); n != 0 {
+		t.Errorf("%d requests reached the denied DataHub", n)
+	}
+	if status := cm.GetSyncStatus(); status.UpstreamHeight != 0 {
+		t.Errorf("UpstreamHeight = %d, want denied announcements ignored", status.UpstreamHeight)
+	}
+	if score := cm.GetPeerScores()["peer-1"]; score.LastOffense != OffenseDataHubDenied {
+		t.Errorf("Peer score = %+v, want a DataHub denial", score)
+	}
+}
+
+func TestHandleBlockMessageBansMisbehavingPeer(t *testing.T) {
+	cm := newTestChainManager(t, WithPeerBan(100, time.Hour))
+	next := extendChain(t, cm.GetTip(), 1)[0]
+
+	// Four wrong heights at 25 each reach the threshold
+	for i := 0; i < 4; i++ {
+		if err := cm.handleBlockMessage(context.Background(), "peer-1", blockMessage(t, next, 7, "")); !errors.Is(err, ErrHeightMismatch) {
+			t.Fatalf("handleBlockMessage() error = %v, want ErrHeightMismatch", err)
+		}
+	}
+
+	// Even a valid announcement is ignored while banned
+	if err := cm.handleBlockMessage(context.Background(), "peer-1", blockMessage(t, next, 1, "")); err != nil {
+		t.Fatalf("handleBlockMessage() from a banned peer error = %v, want it ignored", err)
+	}
+	if tip := cm.GetTip(); tip.Hash == next.Hash {
+		t.Error("Announcement from a banned peer was imported")
+	}
+
+	score := cm.GetPeerScores()["peer-1"]
+	if !score.Banned(time.Now()) || score.Bad != 4 || score.Ignored != 1 || score.LastOffense != OffenseHeightMismatch {
+		t.Errorf("Peer score = %+v, want banned after 4 offenses", score)
+	}
+}
+
+func TestHandleBlockMessageScoresPeers(t *testing.T) {
+	cm := newTestChainManager(t)
+	chain := extendChain(t, cm.GetTip(), 2)
+
+	for i, header := range chain {
+		if err := cm.handleBlockMessage(context.Background(), "peer-1", blockMessage(t, header, uint32(i+1), "")); err != nil {
+			t.Fatalf("handleBlockMessage() error = %v", err)
+		}
+	}
+
+	// Messages that cannot be decoded are charged to the P2P sender
+	if err := cm.handleBlockMessage(context.Background(), "relay-1", []byte("{not json")); !errors.Is(err, errMalformedMessage) {
+		t.Fatalf("handleBlockMessage() error = %v, want a malformed message", err)
+	}
+
+	scores := cm.GetPeerScores()
+	if score := scores["peer-1"]; score.Good != 2 || score.Bad != 0 || score.Score != 2*goodAnnouncementCredit {
+		t.Errorf("Announcing peer score = %+v, want 2 good announcements", score)
+	}
+	if score := scores["relay-1"]; score.Bad != 1 || score.LastOffense != OffenseMalformed {
+		t.Errorf("Sender score = %+v, want a malformed message", score)
+	}
+}
+
+func TestHandleBlockMessageRejectsSpoofedPeerID(t *testing.T) {
+	cm := newTestChainManager(t, WithPeerBan(100, time.Hour))
+	next := extendChain(t, cm.GetTip(), 1)[0]
+
+	// Four wrong heights at 25 each ban peer-1
+	for i := 0; i < 4; i++ {
+		if err := cm.handleBlockMessage(context.Background(), "peer-1", blockMessage(t, next, 7, "")); !errors.Is(err, ErrHeightMismatch) {
+			t.Fatalf("handleBlockMessage() error = %v, want ErrHeightMismatch", err)
+		}
+	}
+
+	// A banned peer claiming another PeerID does not get its announcement through
+	if err := cm.handleBlockMessage(context.Background(), "peer-1", peerBlockMessage(t, "peer-2", next, 1, "")); !errors.Is(err, errMalformedMessage) {
+		t.Fatalf("handleBlockMessage() with a spoofed PeerID error = %v, want a malformed message", err)
+	}
+	if tip := cm.GetTip(); tip.Hash == next.Hash {
+		t.Error("Announcement from a banned peer under another PeerID was imported")
+	}
+
+	// Bad announcements under another peer's PeerID are charged to the sender, not to that peer
+	for i := 0; i < 4; i++ {
+		if err := cm.handleBlockMessage(context.Background(), "peer-3", peerBlockMessage(t, "peer-4", next, 7, "")); !errors.Is(err, errMalformedMessage) {
+			t.Fatalf("handleBlockMessage() with a spoofed PeerID error = %v, want a malformed message", err)
+		}
+	}
+
+	scores := cm.GetPeerScores()
+	if score, ok := scores["peer-2"]; ok {
+		t.Errorf("Spoofed peer-2 score = %+v, want none", score)
+	}
+	if score, ok := scores["peer-4"]; ok {
+		t.Errorf("Framed peer-4 score = %+v, want none", score)
+	}
+	if score := scores["peer-3"]; score.Bad != 4 || score.LastOffense != OffenseMalformed {
+		t.Errorf("Spoofing sender score = %+v, want 4 malformed messages", score)
+	}
+
+	// The innocent peer's own announcements are still accepted
+	if err := cm.handleBlockMessage(context.Background(), "peer-4", peerBlockMessage(t, "peer-4", next, 1, "")); err != nil {
+		t.Fatalf("handleBlockMessage() from peer-4 error = %v", err)
+	}
+	if tip := cm.GetTip(); tip.Hash != next.Hash {
+		t.Error("Announcement from the framed peer was not imported")
+	}
+}
@@ -0,0 +1,122 @@
+package chaintracks
+
+import (
+	"math/big"
+)
+
+// NetworkParams holds the consensus parameters needed to validate headers on a network
+type NetworkParams struct {
+	Name string
+
+	// PowLimit is the highest allowed proof-of-work target, PowLimitBits its compact form
+	PowLimit     *big.Int
+	PowLimitBits uint32
+
+	// TargetTimespan is the period covered by one original-style retarget (seconds)
+	TargetTimespan int64
+	// TargetSpacing is the desired time between blocks (seconds)
+	TargetSpacing int64
+
+	// ReduceMinDifficulty allows a minimum-difficulty block when no block
+	// has been found for twice the target spacing (testnet rule)
+	ReduceMinDifficulty bool
+	// NoRetargeting disables difficulty adjustment entirely (regtest rule)
+	NoRetargeting bool
+
+	// UAHFHeight is the last block before the August 2017 fork that enabled the Emergency Difficulty Adjustment
+	UAHFHeight uint32
+	// DAAHeight is the height from which a block's parent uses the cw-144 difficulty adjustment algorithm
+	DAAHeight uint32
+
+	// Checkpoints are built-in known-good blocks that pin the main chain
+	Checkpoints []Checkpoint
+
+	// WireMagic is the message start value of the legacy P2P protocol, DefaultPort its listening port
+	// (zero if SV Node peers do not run on the network)
+	WireMagic   uint32
+	DefaultPort string
+}
+
+// RetargetInterval returns the number of blocks between original-style retargets (2016 on all networks)
+func (p *NetworkParams) RetargetInterval() uint32 {
+	return uint32(p.TargetTimespan / p.TargetSpacing)
+}
+
+// mainPowLimit is the highest proof-of-work target for main and test networks (2^224 - 1)
+var mainPowLimit = new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 224), big.NewInt(1))
+
+// MainNetParams are the consensus parameters for BSV mainnet
+var MainNetParams = NetworkParams{
+	Name:           "main",
+	PowLimit:       mainPowLimit,
+	PowLimitBits:   0x1d00ffff,
+	TargetTimespan: 14 * 24 * 60 * 60,
+	TargetSpacing:  10 * 60,
+	UAHFHeight:     478558,
+	DAAHeight:      504031,
+	WireMagic:      0xe8f3e1e3,
+	DefaultPort:    "8333",
+	Checkpoints: []Checkpoint{
+		newCheckpoint(0, "000000000019d6689c085ae165831e934ff763ae46a2a6c172b3f1b60a8ce26f"),
+		newCheckpoint(99999, "000000000002d01c1fccc21636b607dfd930d31d01c3a62104612a1719011250"),
+		newCheckpoint(199999, "00000000000003a20def7a05a77361b9657ff954b2f2080e135ea6f5970da215"),
+		newCheckpoint(299999, "000000000000000067ecc744b5ae34eebbde14d21ca4db51652e4d67e155f07e"),
+		newCheckpoint(399999, "0000000000000000030034b661aed920a9bdf6bbfa6d2e7a021f78481882fa39"),
+		newCheckpoint(478558, "0000000000000000011865af4122fe3b144e2cbeea86142e8ff2fb4107352d43"), // UAHF
+		newCheckpoint(499999, "0000000000000000043831d6ebb013716f0580287ee5e5687e27d0ed72e6e523"),
+		newCheckpoint(504031, "0000000000000000011ebf65b60d0a3de80b8175be709d653b4c1a1beeb6ab9c"), // DAA activation
+		newCheckpoint(599999, "0000000000000000078f57b9a986b53b73f007c6b27b6f16409ca4eda83034e8"),
+		newCheckpoint(699999, "000000000000000013abf3ab026610ed70e023476db8ce96f68637acdcbcf3cb"),
+		newCheckpoint(799999, "00000000000000000b6ae23bbe9f549844c20943d8c20b8ceedbae8aa1dde8e0"),
+		newCheckpoint(899999, "00000000000000000e7dcc27c06ee353bd37260b2e7e664314c204f0324a5087"),
+		newCheckpoint(910000, "00000000000000000158a6ac55d1bd5901582df94467c6f065a93fe5f56bc15f"),
+	},
+}
+
+// TestNetParams are the consensus parameters for BSV testnet
+var TestNetParams = NetworkParams{
+	Name:                "test",
+	PowLimit:            mainPowLimit,
+	PowLimitBits:        0x1d00ffff,
+	TargetTimespan:      14 * 24 * 60 * 60,
+	TargetSpacing:       10 * 60,
+	ReduceMinDifficulty: true,
+	UAHFHeight:          1155875,
+	DAAHeight:           1188697,
+	WireMagic:           0xf4f3e5f4,
+	DefaultPort:         "18333",
+	Checkpoints: []Checkpoint{
+		newCheckpoint(0, "000000000933ea01ad0ee984209779baaec3ced90fa3f408719526f8d77f4943"),
+		newCheckpoint(546, "000000002a936ca763904c3c35fce2f3556c559c0214345d31b1bcebf76acb70"),
+	},
+}
+
+// TeraTestNetParams are the consensus parameters for the Teranode test network,
+// which runs testnet rules with the DAA active from its first block
+var TeraTestNetParams = NetworkParams{
+	Name:                "teratest",
+	PowLimit:            mainPowLimit,
+	PowLimitBits:        0x1d00ffff,
+	TargetTimespan:      14 * 24 * 60 * 60,
+	TargetSpacing:       10 * 60,
+	ReduceMinDifficulty: true,
+	UAHFHeight:          1,
+	DAAHeight:           1,
+	Checkpoints: []Checkpoint{
+		newCheckpoint(0, "000000000499eabba0a88f5b3747231c74b9191c1a4a04b2c2ea817976b7776d"),
+	},
+}
+
+// GetNetworkParams returns the consensus parameters for a network name
+func GetNetworkParams(network string) (*NetworkParams, bool) {
+	switch network {
+	case "main":
+		return &MainNetParams, true
+	case "test":
+		return &TestNetParams, true
+	case "teratest":
+		return &TeraTestNetParams, true
+	default:
+		return nil, false
+	}
+}
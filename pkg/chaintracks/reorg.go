@@ -0,0 +1,85 @@
+package chaintracks
+
+import (
+	"context"
+)
+
+// reorgBufferSize is the number of reorg events buffered per subscriber
+const reorgBufferSize = 16
+
+// SubscribeReorgs returns a channel that receives an event for every chain reorganization.
+// The channel is closed when ctx is cancelled.
+func (cm *ChainManager) SubscribeReorgs(ctx context.Context) <-chan *ReorgEvent {
+	events, unsubscribe := cm.Subscribe(ctx, SubscribeOptions{Policy: PolicyLossless, BufferSize: reorgBufferSize})
+	return reorgsOnly(ctx, events, unsubscribe)
+}
+
+// connectBranch prepends side chain ancestors that are already stored in byHash,
+// so the returned branch starts directly above a main chain header (must be called with lock held)
+func (cm *ChainManager) connectBranch(branch []*BlockHeader) []*BlockHeader {
+	var ancestors []*BlockHeader
+	for first := branch[0]; first.Height > 0; {
+		parentHeight := first.Height - 1
+		if parentHeight < uint32(len(cm.byHeight)) && cm.byHeight[parentHeight] == first.PrevHash {
+			break
+		}
+
+		parent, ok := cm.byHash[first.PrevHash]
+		if !ok {
+			break
+		}
+		ancestors = append(ancestors, parent)
+		first = parent
+	}
+
+	if len(ancestors) == 0 {
+		return branch
+	}
+
+	// Ancestors were collected newest first
+	connected := make([]*BlockHeader, 0, len(ancestors)+len(branch))
+	for i := len(ancestors) - 1; i >= 0; i-- {
+		connected = append(connected, ancestors[i])
+	}
+	return append(connected, branch...)
+}
+
+// detectReorg compares a connected branch against the current main chain and returns
+// the reorg it causes, or nil if it only extends the chain (must be called with lock held)
+func (cm *ChainManager) detectReorg(branch []*BlockHeader) *ReorgEvent {
+	// Skip headers that are already on the main chain
+	start := 0
+	for start < len(branch) {
+		header := branch[start]
+		if header.Height >= uint32(len(cm.byHeight)) || cm.byHeight[header.Height] != header.Hash {
+			break
+		}
+		start++
+	}
+
+	forkHeight := branch[len(branch)-1].Height + 1
+	if start < len(branch) {
+		forkHeight = branch[start].Height
+	}
+
+	var disconnected []*BlockHeader
+	for height := forkHeight; height < uint32(len(cm.byHeight)); height++ {
+		if header, ok := cm.byHash[cm.byHeight[height]]; ok {
+			disconnected = append(disconnected, header)
+		}
+	}
+
+	if len(disconnected) == 0 {
+		return nil
+	}
+
+	event := &ReorgEvent{
+		Disconnected: disconnected,
+		Connected:    append([]*BlockHeader(nil), branch[start:]...),
+	}
+	if forkHeight > 0 {
+		event.CommonAncestor = cm.byHash[cm.byHeight[forkHeight-1]]
+	}
+
+	return event
+}
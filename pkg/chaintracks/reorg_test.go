@@ -0,0 +1,279 @@
+package chaintracks
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bsv-blockchain/go-sdk/chainhash"
+)
+
+// receiveReorg waits for a reorg event on ch
+func receiveReorg(t *testing.T, ch <-chan *ReorgEvent) *ReorgEvent {
+	t.Helper()
+
+	select {
+	case event, ok := <-ch:
+		if !ok {
+			t.Fatal("Reorg channel closed")
+		}
+		return event
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for reorg event")
+		return nil
+	}
+}
+
+func TestSetChainTipPublishesReorg(t *testing.T) {
+	cm := newTestChainManager(t)
+	genesis := cm.GetTip()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	reorgs := cm.SubscribeReorgs(ctx)
+
+	mainChain := extendChain(t, genesis, 3)
+	if err := cm.SetChainTip(mainChain); err != nil {
+		t.Fatalf("SetChainTip() main chain error = %v", err)
+	}
+
+	select {
+	case event := <-reorgs:
+		t.Fatalf("Unexpected reorg when extending the chain: %+v", event)
+	default:
+	}
+
+	// A longer branch forking after the first block replaces blocks 2 and 3
+	branch := []*BlockHeader{mineHeader(t, mainChain[0], easyBits, mainChain[0].Timestamp+300)}
+	branch = append(branch, extendChain(t, branch[0], 2)...)
+	if err := cm.SetChainTip(branch); err != nil {
+		t.Fatalf("SetChainTip() fork error = %v", err)
+	}
+
+	event := receiveReorg(t, reorgs)
+	if event.CommonAncestor == nil || event.CommonAncestor.Hash != mainChain[0].Hash {
+		t.Errorf("CommonAncestor = %v, want %s", event.CommonAncestor, mainChain[0].Hash)
+	}
+	if len(event.Disconnected) != 2 || event.Disconnected[0].Hash != mainChain[1].Hash || event.Disconnected[1].Hash != mainChain[2].Hash {
+		t.Errorf("Disconnected = %d headers, want blocks 2 and 3 of the old chain", len(event.Disconnected))
+	}
+	if len(event.Connected) != 3 || event.Connected[2].Hash != branch[2].Hash {
+		t.Errorf("Connected = %d headers, want the 3 fork headers", len(event.Connected))
+	}
+}
+
+func TestAddBlockToChainReorgConnectsSideChain(t *testing.T) {
+	cm := newTestChainManager(t)
+	genesis := cm.GetTip()
+
+	mainChain := extendChain(t, genesis, 2)
+	if err := cm.SetChainTip(mainChain); err != nil {
+		t.Fatalf("SetChainTip() error = %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	reorgs := cm.SubscribeReorgs(ctx)
+
+	// Announce a competing branch one block at a time; the third block overtakes the main chain
+	side := []*BlockHeader{mineHeader(t, genesis, easyBits, genesis.Timestamp+300)}
+	side = append(side, extendChain(t, side[0], 2)...)
+	for _, header := range side {
+		if err := cm.addBlockToChain(header.Header, "peer-1"); err != nil {
+			t.Fatalf("addBlockToChain() height %d error = %v", header.Height, err)
+		}
+	}
+
+	for i, header := range side {
+		got, err := cm.GetHeaderByHeight(header.Height)
+		if err != nil {
+			t.Fatalf("GetHeaderByHeight(%d) error = %v", header.Height, err)
+		}
+		if got.Hash != header.Hash {
+			t.Errorf("Main chain height %d = %s, want side chain block %d", header.Height, got.Hash, i)
+		}
+	}
+
+	event := receiveReorg(t, reorgs)
+	if event.CommonAncestor == nil || event.CommonAncestor.Hash != genesis.Hash {
+		t.Errorf("CommonAncestor = %v, want genesis", event.CommonAncestor)
+	}
+	if len(event.Disconnected) != 2 {
+		t.Errorf("Disconnected = %d headers, want 2", len(event.Disconnected))
+	}
+	if len(event.Connected) != 3 {
+		t.Errorf("Connected = %d headers, want 3", len(event.Connected))
+	}
+}
+
+func TestClientSubscribeReorgs(t *testing.T) {
+	cm := newTestChainManager(t)
+	genesis := cm.GetTip()
+	oldChain := extendChain(t, genesis, 1)
+	newChain := extendChain(t, genesis, 2)
+
+	reorg := &ReorgEvent{CommonAncestor: genesis, Disconnected: oldChain, Connected: newChain}
+	reorgData, err := json.Marshal(reorg)
+	if err != nil {
+		t.Fatalf("Failed to marshal reorg: %v", err)
+	}
+	tipData, err := json.Marshal(newChain[1])
+	if err != nil {
+		t.Fatalf("Failed to marshal tip: %v", err)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprintf(w, "event: reorg\ndata: %s\n\n", reorgData)
+		fmt.Fprintf(w, "data: %s\n\n", tipData)
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	client := NewClient(server.URL)
+	reorgs := client.SubscribeReorgs(ctx)
+	events, unsubscribe := client.Subscribe(ctx, SubscribeOptions{Policy: PolicyLossless})
+	defer unsubscribe()
+
+	tips, err := client.Start(ctx)
+	if err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	defer client.Stop()
+
+	event := receiveReorg(t, reorgs)
+	if event.CommonAncestor.Hash != genesis.Hash || len(event.Disconnected) != 1 || len(event.Connected) != 2 {
+		t.Errorf("Unexpected reorg event: ancestor=%s disconnected=%d connected=%d",
+			event.CommonAncestor.Hash, len(event.Disconnected), len(event.Connected))
+	}
+
+	for _, want := range []EventType{EventReorg, EventTip} {
+		select {
+		case event := <-events:
+			if event.Type != want {
+				t.Errorf("Event type = %s, want %s", event.Type, want)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("Timed out waiting for %s event", want)
+		}
+	}
+
+	select {
+	case tip := <-tips:
+		if tip.Hash != newChain[1].Hash {
+			t.Errorf("Tip = %s, want %s", tip.Hash, newChain[1].Hash)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for tip")
+	}
+}
+
+// mineHeaderWithRoot mines a header with a merkle root, so blocks can be told apart by root
+func mineHeaderWithRoot(t *testing.T, parent *BlockHeader, root chainhash.Hash) *BlockHeader {
+	t.Helper()
+
+	header := mineHeader(t, parent, easyBits, parent.Timestamp+600)
+	header.MerkleRoot = root
+	target := CompactToBig(easyBits)
+	for nonce := uint32(0); nonce < 1<<20; nonce++ {
+		header.Nonce = nonce
+		if hash := header.Header.Hash(); HashToBig(&hash).Cmp(target) <= 0 {
+			header.Hash = hash
+			return header
+		}
+	}
+
+	t.Fatal("failed to mine header")
+	return nil
+}
+
+func TestMerkleRootIndexFollowsReorg(t *testing.T) {
+	cm := newTestChainManager(t)
+	genesis := cm.GetTip()
+
+	var mainChain []*BlockHeader
+	for parent, i := genesis, byte(1); i <= 3; i++ {
+		parent = mineHeaderWithRoot(t, parent, chainhash.Hash{'m', i})
+		mainChain = append(mainChain, parent)
+	}
+	if err := cm.SetChainTip(mainChain); err != nil {
+		t.Fatalf("SetChainTip() main chain error = %v", err)
+	}
+	for _, header := range mainChain {
+		got, err := cm.GetHeaderByMerkleRoot(&header.MerkleRoot)
+		if err != nil || got.Hash != header.Hash || got.Height != header.Height {
+			t.Fatalf("GetHeaderByMerkleRoot(%s) = %v, %v, want height %d", header.MerkleRoot, got, err, header.Height)
+		}
+	}
+
+	// A longer fork after the first block replaces blocks 2 and 3
+	var branch []*BlockHeader
+	for parent, i := mainChain[0], byte(2); i <= 4; i++ {
+		parent = mineHeaderWithRoot(t, parent, chainhash.Hash{'f', i})
+		branch = append(branch, parent)
+	}
+	if err := cm.SetChainTip(branch); err != nil {
+		t.Fatalf("SetChainTip() fork error = %v", err)
+	}
+
+	if got, err := cm.GetHeaderByMerkleRoot(&mainChain[0].MerkleRoot); err != nil || got.Hash != mainChain[0].Hash {
+		t.Errorf("Common block lookup = %v, %v, want height 1", got, err)
+	}
+	for _, header := range mainChain[1:] {
+		if got, err := cm.GetHeaderByMerkleRoot(&header.MerkleRoot); err == nil {
+			t.Errorf("Disconnected block at height %d still found by merkle root: %v", header.Height, got)
+		}
+	}
+	for _, header := range branch {
+		if got, err := cm.GetHeaderByMerkleRoot(&header.MerkleRoot); err != nil || got.Hash != header.Hash {
+			t.Errorf("GetHeaderByMerkleRoot(%s) = %v, %v, want the fork block at height %d", header.MerkleRoot, got, err, header.Height)
+		}
+	}
+}
+
+func TestSetChainTipConcurrentBranches(t *testing.T) {
+	for i := 0; i < 10; i++ {
+		cm := newTestChainManager(t)
+		genesis := cm.GetTip()
+
+		// Two branches from genesis; the longer one has more work and must win whatever the order
+		short := extendChain(t, genesis, 5)
+		fork := mineHeader(t, genesis, easyBits, genesis.Timestamp+300)
+		long := append([]*BlockHeader{fork}, extendChain(t, fork, 7)...)
+
+		var wg sync.WaitGroup
+		for _, branch := range [][]*BlockHeader{short, long} {
+			wg.Add(1)
+			go func(branch []*BlockHeader) {
+				defer wg.Done()
+				if err := cm.SetChainTip(branch); err != nil {
+					t.Errorf("SetChainTip() error = %v", err)
+				}
+			}(branch)
+		}
+		wg.Wait()
+
+		want := long[len(long)-1]
+		if tip := cm.GetTip(); tip.Hash != want.Hash {
+			t.Fatalf("Tip after concurrent imports = height %d %s, want the branch with more work", tip.Height, tip.Hash)
+		}
+
+		// The store followed the same order as the in-memory chain
+		reloaded, err := NewChainManager("regtest", cm.localStoragePath)
+		if err != nil {
+			t.Fatalf("NewChainManager() error = %v", err)
+		}
+		if tip := reloaded.GetTip(); tip.Hash != want.Hash {
+			t.Fatalf("Reloaded tip = height %d %s, want the branch with more work", tip.Height, tip.Hash)
+		}
+	}
+}
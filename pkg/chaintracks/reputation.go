@@ -0,0 +1,216 @@
+package chaintracks
+
+import (
+	"errors"
+	"log"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultPeerBanThreshold is the penalty total at which a peer's announcements are ignored
+	DefaultPeerBanThreshold = 100
+	// DefaultPeerBanDuration is how long a peer stays banned
+	DefaultPeerBanDuration = time.Hour
+
+	// maxPeerCredit caps the credit good announcements earn, so a peer cannot bank enough to misbehave freely
+	maxPeerCredit = 50
+	// maxTrackedPeers bounds the reputation table; peer IDs come from unauthenticated messages
+	maxTrackedPeers = 1000
+)
+
+// PeerOffense is a kind of misbehaviour by a peer announcing blocks
+type PeerOffense string
+
+const (
+	// OffenseMalformed is a message that cannot be decoded or holds an unparseable header
+	OffenseMalformed PeerOffense = "malformed message"
+	// OffenseInvalidHeader is a header that fails proof of work, difficulty, timestamp or checkpoint validation
+	OffenseInvalidHeader PeerOffense = "invalid header"
+	// OffenseHeightMismatch is an announced height that disagrees with the block's parent
+	OffenseHeightMismatch PeerOffense = "height mismatch"
+	// OffenseDataHubDenied is a DataHub URL refused by the DataHub policy
+	OffenseDataHubDenied PeerOffense = "DataHub URL denied"
+	// OffenseCrawlFailed is a crawl-back through the peer's DataHub URL that could not complete
+	OffenseCrawlFailed PeerOffense = "crawl-back failed"
+)
+
+// offensePenalty is the score each offense costs
+var offensePenalty = map[PeerOffense]int{
+	OffenseMalformed:      25,
+	OffenseInvalidHeader:  50,
+	OffenseHeightMismatch: 25,
+	OffenseDataHubDenied:  25,
+	OffenseCrawlFailed:    20,
+}
+
+// goodAnnouncementCredit is the score a valid announcement earns
+const goodAnnouncementCredit = 5
+
+// PeerScore is the reputation of a peer that has announced blocks
+type PeerScore struct {
+	Score       int         `json:"score"` // Credit from good announcements minus penalties; banned at -threshold
+	Good        uint64      `json:"good"`
+	Bad         uint64      `json:"bad"`
+	Ignored     uint64      `json:"ignored"` // Announcements dropped while banned
+	Bans        uint64      `json:"bans"`
+	BannedUntil time.Time   `json:"bannedUntil,omitempty"`
+	LastOffense PeerOffense `json:"lastOffense,omitempty"`
+	LastSeen    time.Time   `json:"lastSeen"`
+}
+
+// Banned reports whether the peer is banned at now
+func (s PeerScore) Banned(now time.Time) bool {
+	return now.Before(s.BannedUntil)
+}
+
+// peerBook tracks the reputation of announcing peers
+type peerBook struct {
+	mu          sync.Mutex
+	peers       map[string]*PeerScore
+	threshold   int
+	banDuration time.Duration
+}
+
+// newPeerBook creates a peerBook that bans peers whose score falls to -threshold
+func newPeerBook(threshold int, banDuration time.Duration) *peerBook {
+	return &peerBook{
+		peers:       make(map[string]*PeerScore),
+		threshold:   threshold,
+		banDuration: banDuration,
+	}
+}
+
+// entry returns the score of a peer, evicting the least recently seen unbanned peer if the table is full.
+// The caller must hold mu.
+func (b *peerBook) entry(peer string, now time.Time) *PeerScore {
+	score, ok := b.peers[peer]
+	if !ok {
+		if len(b.peers) >= maxTrackedPeers {
+			b.evict(now)
+		}
+		score = &PeerScore{}
+		b.peers[peer] = score
+	}
+	score.LastSeen = now
+	return score
+}
+
+// evict drops the least recently seen peer that is not banned. The caller must hold mu.
+func (b *peerBook) evict(now time.Time) {
+	var oldest string
+	for peer, score := range b.peers {
+		if score.Banned(now) {
+			continue
+		}
+		if oldest == "" || score.LastSeen.Before(b.peers[oldest].LastSeen) {
+			oldest = peer
+		}
+	}
+	delete(b.peers, oldest)
+}
+
+// allow reports whether announcements from peer should be processed, counting those that are not
+func (b *peerBook) allow(peer string) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	score, ok := b.peers[peer]
+	if !ok || !score.Banned(time.Now()) {
+		return true
+	}
+	score.Ignored++
+	return false
+}
+
+// good credits a valid announcement
+func (b *peerBook) good(peer string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	score := b.entry(peer, time.Now())
+	score.Good++
+	score.Score = min(score.Score+goodAnnouncementCredit, maxPeerCredit)
+}
+
+// penalize charges an offense and bans the peer once its score reaches -threshold.
+// A ban starts the peer over at zero, so it is banned again quickly if it carries on.
+func (b *peerBook) penalize(peer string, offense PeerOffense) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	score := b.entry(peer, now)
+	score.Bad++
+	score.LastOffense = offense
+	score.Score -= offensePenalty[offense]
+
+	if b.threshold > 0 && score.Score <= -b.threshold {
+		score.Bans++
+		score.BannedUntil = now.Add(b.banDuration)
+		score.Score = 0
+		log.Printf("Banning peer %s until %s after %d bad announcements (last: %s)", peer, score.BannedUntil.Format(time.RFC3339), score.Bad, offense)
+	}
+}
+
+// snapshot returns a copy of every tracked score
+func (b *peerBook) snapshot() map[string]PeerScore {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	scores := make(map[string]PeerScore, len(b.peers))
+	for peer, score := range b.peers {
+		scores[peer] = *score
+	}
+	return scores
+}
+
+// GetPeerScores returns the reputation of every peer that has announced blocks, keyed by peer ID
+func (cm *ChainManager) GetPeerScores() map[string]PeerScore {
+	return cm.peerBook.snapshot()
+}
+
+// scoreAnnouncement credits peer for an announcement that was handled, or charges it for the offense err represents.
+// Errors that are not the peer's fault are not scored.
+func (cm *ChainManager) scoreAnnouncement(peer string, err error) {
+	if err == nil {
+		cm.peerBook.good(peer)
+		return
+	}
+	if offense := offenseFor(err); offense != "" {
+		cm.peerBook.penalize(peer, offense)
+	}
+}
+
+// errMalformedMessage marks announcements that cannot be decoded
+var errMalformedMessage = errors.New("malformed block message")
+
+// errCrawlFailed marks crawl-backs that failed for reasons other than invalid headers
+var errCrawlFailed = errors.New("crawl-back failed")
+
+// validationErrors are the errors validateHeaders returns for headers that break consensus rules
+var validationErrors = []error{
+	ErrInvalidHeader, ErrInsufficientPoW, ErrInvalidDifficulty, ErrBrokenChain, ErrInvalidTimestamp, ErrCheckpointMismatch,
+}
+
+// offenseFor returns the offense an error from processing a peer's announcement represents,
+// or "" if the error is not the peer's fault
+func offenseFor(err error) PeerOffense {
+	for _, target := range validationErrors {
+		if errors.Is(err, target) {
+			return OffenseInvalidHeader
+		}
+	}
+	switch {
+	case errors.Is(err, ErrHeightMismatch):
+		return OffenseHeightMismatch
+	case errors.Is(err, ErrDataHubDenied):
+		return OffenseDataHubDenied
+	case errors.Is(err, errCrawlFailed):
+		return OffenseCrawlFailed
+	case errors.Is(err, errMalformedMessage):
+		return OffenseMalformed
+	default:
+		return ""
+	}
+}
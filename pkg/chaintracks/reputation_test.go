@@ -0,0 +1,102 @@
+package chaintracks
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestPeerBookBansAfterThreshold(t *testing.T) {
+	book := newPeerBook(100, time.Hour)
+
+	// Credit from good announcements is capped
+	for i := 0; i < 20; i++ {
+		book.good("peer-1")
+	}
+	if score := book.snapshot()["peer-1"]; score.Score != maxPeerCredit || score.Good != 20 {
+		t.Fatalf("Score after good announcements = %+v, want capped at %d", score, maxPeerCredit)
+	}
+
+	// 50 credit + 3 x 50 penalty reaches -100
+	for i := 0; i < 2; i++ {
+		book.penalize("peer-1", OffenseInvalidHeader)
+		if !book.allow("peer-1") {
+			t.Fatalf("Peer banned after %d offenses", i+1)
+		}
+	}
+	book.penalize("peer-1", OffenseInvalidHeader)
+	if book.allow("peer-1") {
+		t.Fatal("Peer not banned after crossing the threshold")
+	}
+	if !book.allow("peer-2") {
+		t.Error("Unrelated peer was banned")
+	}
+
+	score := book.snapshot()["peer-1"]
+	if !score.Banned(time.Now()) || score.Bans != 1 || score.Bad != 3 || score.Ignored != 1 || score.LastOffense != OffenseInvalidHeader {
+		t.Errorf("Score after ban = %+v", score)
+	}
+}
+
+func TestPeerBookBanExpires(t *testing.T) {
+	book := newPeerBook(20, 20*time.Millisecond)
+	book.penalize("peer-1", OffenseCrawlFailed)
+	if book.allow("peer-1") {
+		t.Fatal("Peer not banned")
+	}
+
+	time.Sleep(30 * time.Millisecond)
+	if !book.allow("peer-1") {
+		t.Error("Peer still banned after the ban duration")
+	}
+}
+
+func TestPeerBookBanningDisabled(t *testing.T) {
+	book := newPeerBook(0, time.Hour)
+	for i := 0; i < 10; i++ {
+		book.penalize("peer-1", OffenseInvalidHeader)
+	}
+	if !book.allow("peer-1") {
+		t.Error("Peer banned with a zero threshold")
+	}
+	if score := book.snapshot()["peer-1"]; score.Score != -500 {
+		t.Errorf("Score = %d, want -500", score.Score)
+	}
+}
+
+func TestPeerBookEvictsLeastRecentlySeen(t *testing.T) {
+	book := newPeerBook(100, time.Hour)
+	for i := 0; i < maxTrackedPeers; i++ {
+		book.good(fmt.Sprintf("peer-%d", i))
+	}
+	book.good("peer-new")
+
+	scores := book.snapshot()
+	if len(scores) != maxTrackedPeers {
+		t.Errorf("Tracking %d peers, want at most %d", len(scores), maxTrackedPeers)
+	}
+	if _, ok := scores["peer-new"]; !ok {
+		t.Error("Newest peer was not tracked")
+	}
+}
+
+func TestOffenseFor(t *testing.T) {
+	tests := []struct {
+		err  error
+		want PeerOffense
+	}{
+		{err: fmt.Errorf("%w: bad json", errMalformedMessage), want: OffenseMalformed},
+		{err: fmt.Errorf("header: %w", ErrInsufficientPoW), want: OffenseInvalidHeader},
+		{err: fmt.Errorf("%w from x: remote branch failed validation: %w", errCrawlFailed, ErrInvalidDifficulty), want: OffenseInvalidHeader},
+		{err: fmt.Errorf("%w from x: connection refused", errCrawlFailed), want: OffenseCrawlFailed},
+		{err: fmt.Errorf("%w: height", ErrHeightMismatch), want: OffenseHeightMismatch},
+		{err: fmt.Errorf("%w: private", ErrDataHubDenied), want: OffenseDataHubDenied},
+		{err: fmt.Errorf("failed to store headers: disk full"), want: ""},
+	}
+
+	for _, tt := range tests {
+		if got := offenseFor(tt.err); got != tt.want {
+			t.Errorf("offenseFor(%v) = %q, want %q", tt.err, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,169 @@
+package chaintracks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/bsv-blockchain/go-sdk/chainhash"
+)
+
+// DefaultResyncInterval is how often the server checks its upstreams for headers it missed
+const DefaultResyncInterval = time.Minute
+
+// SyncStatus describes how fresh the local tip is
+type SyncStatus struct {
+	TipHeight      uint32    `json:"tipHeight"`
+	TipTime        time.Time `json:"tipTime"`                  // Timestamp in the tip header
+	LastHeaderAt   time.Time `json:"lastHeaderAt"`             // When the tip last changed, zero if not since startup
+	SecondsBehind  int64     `json:"secondsBehind"`            // Seconds between the tip's timestamp and now
+	UpstreamHeight uint32    `json:"upstreamHeight"`           // Highest height reported by an upstream or peer
+	BlocksBehind   uint32    `json:"blocksBehind"`             // How far the tip is below UpstreamHeight
+	LastCheckAt    time.Time `json:"lastCheckAt"`              // When Resync last ran, zero if never
+	LastCheckError string    `json:"lastCheckError,omitempty"` // Error from the last Resync
+}
+
+// announcement is the best block reported by a peer that may still be missing locally
+type announcement struct {
+	hash       chainhash.Hash
+	height     uint32
+	dataHubURL string
+}
+
+// GetSyncStatus returns how fresh the local tip is compared with the clock and the upstreams
+func (cm *ChainManager) GetSyncStatus() SyncStatus {
+	cm.syncMu.Lock()
+	status := SyncStatus{
+		LastHeaderAt:   cm.lastHeaderAt,
+		UpstreamHeight: cm.upstreamHeight,
+		LastCheckAt:    cm.lastCheckAt,
+		LastCheckError: cm.lastCheckError,
+	}
+	if cm.bestAnnounced != nil {
+		status.UpstreamHeight = max(status.UpstreamHeight, cm.bestAnnounced.height)
+	}
+	cm.syncMu.Unlock()
+
+	if tip := cm.GetTip(); tip != nil {
+		status.TipHeight = tip.Height
+		status.TipTime = time.Unix(int64(tip.Timestamp), 0).UTC()
+		status.SecondsBehind = max(0, int64(time.Since(status.TipTime).Seconds()))
+	}
+	if status.UpstreamHeight > status.TipHeight {
+		status.BlocksBehind = status.UpstreamHeight - status.TipHeight
+	}
+	return status
+}
+
+// noteHeaderReceived records that the main chain tip changed
+func (cm *ChainManager) noteHeaderReceived() {
+	cm.syncMu.Lock()
+	cm.lastHeaderAt = time.Now()
+	cm.syncMu.Unlock()
+}
+
+// noteUpstreamHeight records a chain height reported by an upstream or peer
+func (cm *ChainManager) noteUpstreamHeight(height uint32) {
+	cm.syncMu.Lock()
+	cm.upstreamHeight = max(cm.upstreamHeight, height)
+	cm.syncMu.Unlock()
+}
+
+// noteAnnouncement records a block announced over P2P with an unknown parent, so Resync can fetch it if crawling
+// back fails. Its height is unverified until then, so it only counts towards UpstreamHeight while pending.
+func (cm *ChainManager) noteAnnouncement(hash chainhash.Hash, height uint32, dataHubURL string) {
+	cm.syncMu.Lock()
+	defer cm.syncMu.Unlock()
+
+	if cm.bestAnnounced == nil || height > cm.bestAnnounced.height {
+		cm.bestAnnounced = &announcement{hash: hash, height: height, dataHubURL: dataHubURL}
+	}
+}
+
+// clearAnnouncement forgets a pending announcement once crawling back from it has finished
+func (cm *ChainManager) clearAnnouncement(hash chainhash.Hash) {
+	cm.syncMu.Lock()
+	defer cm.syncMu.Unlock()
+
+	if cm.bestAnnounced != nil && cm.bestAnnounced.hash == hash {
+		cm.bestAnnounced = nil
+	}
+}
+
+// Resync checks every bootstrap source and the best pending block announced by P2P peers against the local chain,
+// and syncs from any that has a tip we do not know. It catches up after missed or failed announcements.
+func (cm *ChainManager) Resync(ctx context.Context) error {
+	var errs []error
+	for _, source := range cm.bootstrapSources() {
+		status := &SourceStatus{Source: source}
+		if err := cm.fetchSourceTip(ctx, status); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", source, err))
+			continue
+		}
+		if status.HeightKnown {
+			cm.noteUpstreamHeight(status.TipHeight)
+		}
+		if _, err := cm.GetHeaderByHash(&status.TipHash); err == nil {
+			continue
+		}
+
+		log.Printf("Resync: %s has tip %s%s which we do not have, syncing", source, status.TipHash, heightSuffix(status))
+		if err := cm.syncFromSource(ctx, status); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", source, err))
+			continue
+		}
+		if header, err := cm.GetHeaderByHash(&status.TipHash); err == nil {
+			cm.noteUpstreamHeight(header.Height)
+		}
+	}
+
+	cm.syncMu.Lock()
+	best := cm.bestAnnounced
+	cm.syncMu.Unlock()
+	if best != nil {
+		if _, err := cm.GetHeaderByHash(&best.hash); err != nil {
+			log.Printf("Resync: announced block %s at height %d is missing, fetching from %s", best.hash, best.height, best.dataHubURL)
+			job, err := cm.crawlBack(ctx, best.hash, best.height, best.dataHubURL)
+			if err == nil {
+				err = job.wait(ctx)
+			}
+			if err != nil {
+				errs = append(errs, fmt.Errorf("announced block %s: %w", best.hash, err))
+			}
+		} else {
+			cm.clearAnnouncement(best.hash)
+		}
+	}
+
+	err := errors.Join(errs...)
+	cm.syncMu.Lock()
+	cm.lastCheckAt = time.Now()
+	cm.lastCheckError = ""
+	if err != nil {
+		cm.lastCheckError = err.Error()
+	}
+	cm.syncMu.Unlock()
+	return err
+}
+
+// runResync calls Resync every interval until ctx is cancelled
+func (cm *ChainManager) runResync(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := cm.Resync(ctx); err != nil {
+				log.Printf("Resync failed: %v", err)
+			}
+			if status := cm.GetSyncStatus(); status.BlocksBehind > 0 {
+				log.Printf("WARNING: tip at height %d is %d blocks behind upstream height %d", status.TipHeight, status.BlocksBehind, status.UpstreamHeight)
+			}
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package chaintracks
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestResyncCatchesUpFromSource(t *testing.T) {
+	cm := newTestChainManager(t)
+	genesis := cm.GetTip()
+	chain := append([]*BlockHeader{genesis}, extendChain(t, genesis, 30)...)
+	if err := cm.SetChainTip(chain[1:11]); err != nil {
+		t.Fatalf("SetChainTip() error = %v", err)
+	}
+
+	// The source moved on while no announcement arrived
+	teranode := newFakeUpstream(t, chain)
+	cm.extraSources = []BootstrapSource{{Kind: SourceTeranode, URL: teranode.URL}}
+
+	before := time.Now()
+	if err := cm.Resync(context.Background()); err != nil {
+		t.Fatalf("Resync() error = %v", err)
+	}
+	if tip := cm.GetTip(); tip.Hash != chain[30].Hash {
+		t.Fatalf("Tip after resync = height %d, want 30", tip.Height)
+	}
+
+	status := cm.GetSyncStatus()
+	if status.TipHeight != 30 || status.UpstreamHeight != 30 || status.BlocksBehind != 0 {
+		t.Errorf("Status after resync = %+v, want tip and upstream at height 30", status)
+	}
+	if status.LastHeaderAt.Before(before) || status.LastCheckAt.Before(before) || status.LastCheckError != "" {
+		t.Errorf("Status after resync = %+v, want a header and a successful check since %v", status, before)
+	}
+	if !status.TipTime.Equal(time.Unix(int64(chain[30].Timestamp), 0)) || status.SecondsBehind <= 0 {
+		t.Errorf("TipTime = %v, SecondsBehind = %d, want the tip header timestamp", status.TipTime, status.SecondsBehind)
+	}
+}
+
+func TestResyncFetchesMissedAnnouncement(t *testing.T) {
+	cm := newTestChainManager(t, WithDataHubPolicy(DataHubPolicy{AllowPrivate: true}))
+	genesis := cm.GetTip()
+	chain := append([]*BlockHeader{genesis}, extendChain(t, genesis, 12)...)
+	teranode := newFakeUpstream(t, chain)
+
+	// An announcement whose import failed leaves the tip behind
+	cm.noteAnnouncement(chain[12].Hash, 12, teranode.URL)
+	if status := cm.GetSyncStatus(); status.BlocksBehind != 12 {
+		t.Fatalf("BlocksBehind before resync = %d, want 12", status.BlocksBehind)
+	}
+
+	if err := cm.Resync(context.Background()); err != nil {
+		t.Fatalf("Resync() error = %v", err)
+	}
+	if tip := cm.GetTip(); tip.Hash != chain[12].Hash {
+		t.Fatalf("Tip after resync = height %d, want 12", tip.Height)
+	}
+	if status := cm.GetSyncStatus(); status.BlocksBehind != 0 {
+		t.Errorf("BlocksBehind after resync = %d, want 0", status.BlocksBehind)
+	}
+}
+
+func TestResyncRecordsUnreachableSource(t *testing.T) {
+	cm := newTestChainManager(t)
+	down := httptest.NewServer(http.NotFoundHandler())
+	down.Close()
+	cm.extraSources = []BootstrapSource{{Kind: SourceTeranode, URL: down.URL}}
+
+	if err := cm.Resync(context.Background()); err == nil {
+		t.Fatal("Resync() succeeded with an unreachable source")
+	}
+	if status := cm.GetSyncStatus(); status.LastCheckAt.IsZero() || status.LastCheckError == "" {
+		t.Errorf("Status after failed resync = %+v, want the check and its error recorded", status)
+	}
+}
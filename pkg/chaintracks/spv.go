@@ -0,0 +1,109 @@
+package chaintracks
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/bsv-blockchain/go-sdk/chainhash"
+	"github.com/bsv-blockchain/go-sdk/transaction"
+)
+
+// VerifyMerklePath computes the merkle root that path proves for txid and checks it against the main chain
+func (cm *ChainManager) VerifyMerklePath(ctx context.Context, path *transaction.MerklePath, txid *chainhash.Hash) (*ProofResult, error) {
+	root, err := computeRoot(path, txid)
+	if err != nil {
+		return nil, err
+	}
+
+	proofs := []ProofResult{{TxID: *txid, BlockHeight: path.BlockHeight, MerkleRoot: *root}}
+	if err := cm.checkProofs(ctx, proofs); err != nil {
+		return nil, err
+	}
+	return &proofs[0], nil
+}
+
+// VerifyBeef checks the merkle proof of every transaction in a BEEF that carries one, ordered by block height.
+// Transactions without a proof of their own are proven through their ancestors and are not reported.
+func (cm *ChainManager) VerifyBeef(ctx context.Context, beef []byte) ([]ProofResult, error) {
+	parsed, err := transaction.NewBeefFromBytes(beef)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidProof, err)
+	}
+
+	var proofs []ProofResult
+	for txid, tx := range parsed.Transactions {
+		if tx.DataFormat != transaction.RawTxAndBumpIndex {
+			continue
+		}
+		if tx.BumpIndex < 0 || tx.BumpIndex >= len(parsed.BUMPs) {
+			return nil, fmt.Errorf("%w: transaction %s references missing BUMP %d", ErrInvalidProof, txid, tx.BumpIndex)
+		}
+
+		bump := parsed.BUMPs[tx.BumpIndex]
+		root, err := computeRoot(bump, &txid)
+		if err != nil {
+			return nil, fmt.Errorf("transaction %s: %w", txid, err)
+		}
+		proofs = append(proofs, ProofResult{TxID: txid, BlockHeight: bump.BlockHeight, MerkleRoot: *root})
+	}
+	if len(proofs) == 0 {
+		return nil, fmt.Errorf("%w: BEEF carries no merkle proofs", ErrInvalidProof)
+	}
+
+	sort.Slice(proofs, func(i, j int) bool {
+		if proofs[i].BlockHeight != proofs[j].BlockHeight {
+			return proofs[i].BlockHeight < proofs[j].BlockHeight
+		}
+		return proofs[i].TxID.String() < proofs[j].TxID.String()
+	})
+
+	if err := cm.checkProofs(ctx, proofs); err != nil {
+		return nil, err
+	}
+	return proofs, nil
+}
+
+// computeRoot computes the merkle root that path proves for txid. go-sdk takes a single-leaf path as proving
+// whatever txid it is given, so the txid must also appear at the bottom of the path.
+func computeRoot(path *transaction.MerklePath, txid *chainhash.Hash) (*chainhash.Hash, error) {
+	if len(path.Path) == 0 {
+		return nil, fmt.Errorf("%w: empty merkle path", ErrInvalidProof)
+	}
+
+	found := false
+	for _, leaf := range path.Path[0] {
+		if leaf.Hash != nil && leaf.Hash.IsEqual(txid) {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("%w: merkle path does not contain %s", ErrInvalidProof, txid)
+	}
+
+	root, err := path.ComputeRoot(txid)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidProof, err)
+	}
+	return root, nil
+}
+
+// checkProofs fills in the result and confirmation depth of each proof against one view of the main chain
+func (cm *ChainManager) checkProofs(ctx context.Context, proofs []ProofResult) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
+
+	for i := range proofs {
+		proof := &proofs[i]
+		proof.Result = cm.rootValidity(&proof.MerkleRoot, proof.BlockHeight)
+		if proof.Result == RootValid && cm.tip != nil {
+			proof.Confirmations = cm.tip.Height - proof.BlockHeight + 1
+		}
+	}
+	return nil
+}
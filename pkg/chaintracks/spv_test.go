@@ -0,0 +1,88 @@
+package chaintracks
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/bsv-blockchain/go-sdk/chainhash"
+	"github.com/bsv-blockchain/go-sdk/transaction"
+)
+
+// provenTx returns a transaction with a two-leaf merkle path at height and the root that path proves
+func provenTx(t *testing.T, height uint32) (*transaction.Transaction, chainhash.Hash) {
+	t.Helper()
+
+	tx := transaction.NewTransaction()
+	tx.LockTime = height
+	isTxid := true
+	sibling := chainhash.Hash{'s'}
+	tx.MerklePath = transaction.NewMerklePath(height, [][]*transaction.PathElement{{
+		{Offset: 0, Hash: tx.TxID(), Txid: &isTxid},
+		{Offset: 1, Hash: &sibling},
+	}})
+
+	root, err := tx.MerklePath.ComputeRoot(tx.TxID())
+	if err != nil {
+		t.Fatalf("ComputeRoot() error = %v", err)
+	}
+	return tx, *root
+}
+
+func TestVerifyMerklePath(t *testing.T) {
+	cm := newTestChainManager(t)
+	tx, root := provenTx(t, 1)
+	block := mineHeaderWithRoot(t, cm.GetTip(), root)
+	if err := cm.SetChainTip(append([]*BlockHeader{block}, extendChain(t, block, 2)...)); err != nil {
+		t.Fatalf("SetChainTip() error = %v", err)
+	}
+
+	result, err := cm.VerifyMerklePath(context.Background(), tx.MerklePath, tx.TxID())
+	if err != nil {
+		t.Fatalf("VerifyMerklePath() error = %v", err)
+	}
+	if result.Result != RootValid || result.Confirmations != 3 || result.MerkleRoot != root {
+		t.Errorf("VerifyMerklePath() = %+v, want valid with 3 confirmations", result)
+	}
+
+	// The same proof claimed at another height does not match the main chain
+	tx.MerklePath.BlockHeight = 2
+	if result, err := cm.VerifyMerklePath(context.Background(), tx.MerklePath, tx.TxID()); err != nil || result.Result != RootInvalid || result.Confirmations != 0 {
+		t.Errorf("VerifyMerklePath() at wrong height = %+v, %v, want invalid", result, err)
+	}
+	tx.MerklePath.BlockHeight = 10
+	if result, err := cm.VerifyMerklePath(context.Background(), tx.MerklePath, tx.TxID()); err != nil || result.Result != RootUnknown {
+		t.Errorf("VerifyMerklePath() beyond tip = %+v, %v, want unknown", result, err)
+	}
+
+	other := chainhash.Hash{'o'}
+	if _, err := cm.VerifyMerklePath(context.Background(), tx.MerklePath, &other); !errors.Is(err, ErrInvalidProof) {
+		t.Errorf("VerifyMerklePath() for txid not in path error = %v, want ErrInvalidProof", err)
+	}
+}
+
+func TestVerifyBeef(t *testing.T) {
+	cm := newTestChainManager(t)
+	tx, root := provenTx(t, 1)
+	block := mineHeaderWithRoot(t, cm.GetTip(), root)
+	if err := cm.SetChainTip([]*BlockHeader{block}); err != nil {
+		t.Fatalf("SetChainTip() error = %v", err)
+	}
+
+	beef, err := tx.BEEF()
+	if err != nil {
+		t.Fatalf("BEEF() error = %v", err)
+	}
+
+	results, err := cm.VerifyBeef(context.Background(), beef)
+	if err != nil {
+		t.Fatalf("VerifyBeef() error = %v", err)
+	}
+	if len(results) != 1 || results[0].TxID != *tx.TxID() || results[0].Result != RootValid || results[0].Confirmations != 1 {
+		t.Errorf("VerifyBeef() = %+v, want tx %s valid with 1 confirmation", results, tx.TxID())
+	}
+
+	if _, err := cm.VerifyBeef(context.Background(), []byte("not a beef")); !errors.Is(err, ErrInvalidProof) {
+		t.Errorf("VerifyBeef() of garbage error = %v, want ErrInvalidProof", err)
+	}
+}
@@ -0,0 +1,119 @@
+package chaintracks
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"math/big"
+)
+
+// headersPerFile is the number of headers in each CDN-style file
+const headersPerFile = 100000
+
+// HeaderStore persists the main chain of headers for a ChainManager
+type HeaderStore interface {
+	// PutBranch stores a branch of main chain headers ordered from oldest to newest.
+	// Headers at existing heights are overwritten and the last header becomes the stored tip,
+	// so anything above it is discarded.
+	PutBranch(headers []*BlockHeader) error
+
+	// TruncateAbove discards all stored headers above height
+	TruncateAbove(height uint32) error
+
+	// LoadRange returns the stored headers from height from to height to (inclusive).
+	// Height and Hash are always set; ChainWork is set only if the store persists it.
+	LoadRange(from, to uint32) ([]*BlockHeader, error)
+
+	// ReadMetadata returns CDN-style metadata describing the stored headers, or nil if the store is empty
+	ReadMetadata() (*CDNMetadata, error)
+}
+
+// ForkStore is implemented by header stores that also persist headers off the main chain
+type ForkStore interface {
+	// PutHeader stores a single header without changing the main chain
+	PutHeader(header *BlockHeader) error
+
+	// LoadForkHeaders returns stored headers off the main chain at or above minHeight
+	LoadForkHeaders(minHeight uint32) ([]*BlockHeader, error)
+
+	// PruneForkHeaders deletes stored headers off the main chain below height
+	PruneForkHeaders(height uint32) error
+}
+
+// RecoverableStore is implemented by header stores that must repair an interrupted write on startup
+type RecoverableStore interface {
+	// Recover brings the store back to its last consistent state
+	Recover() error
+}
+
+// copyBatchSize is the number of headers moved per batch by CopyHeaders
+const copyBatchSize = 10000
+
+// CopyHeaders copies the main chain from src into dst, computing chainwork where src does not store it.
+// It is used to migrate between store backends.
+func CopyHeaders(dst, src HeaderStore) error {
+	metadata, err := src.ReadMetadata()
+	if err != nil {
+		return fmt.Errorf("failed to read source metadata: %w", err)
+	}
+	if metadata == nil {
+		return nil
+	}
+
+	tipHeight, ok := metadataTipHeight(metadata)
+	if !ok {
+		return nil
+	}
+
+	var prevChainWork *big.Int
+	for from := uint32(0); from <= tipHeight; from += copyBatchSize {
+		to := min(tipHeight, from+copyBatchSize-1)
+		headers, err := src.LoadRange(from, to)
+		if err != nil {
+			return fmt.Errorf("failed to load headers %d-%d: %w", from, to, err)
+		}
+
+		for _, header := range headers {
+			if header.ChainWork == nil {
+				header.ChainWork = chainWorkAt(prevChainWork, header.Height, header.Bits)
+			}
+			prevChainWork = header.ChainWork
+		}
+
+		if err := dst.PutBranch(headers); err != nil {
+			return fmt.Errorf("failed to store headers %d-%d: %w", from, to, err)
+		}
+	}
+
+	return nil
+}
+
+// metadataTipHeight returns the height of the last header described by metadata
+func metadataTipHeight(metadata *CDNMetadata) (uint32, bool) {
+	for i := len(metadata.Files) - 1; i >= 0; i-- {
+		if entry := metadata.Files[i]; entry.Count > 0 {
+			return entry.FirstHeight + uint32(entry.Count) - 1, true
+		}
+	}
+	return 0, false
+}
+
+// fileNameForIndex returns the CDN file name holding the headers of a file index
+func fileNameForIndex(network string, fileIndex uint32) string {
+	return fmt.Sprintf("%sNet_%d.headers", network, fileIndex)
+}
+
+// computeFileHash returns the CDN fileHash of a file holding headers
+func computeFileHash(headers []*BlockHeader) string {
+	h := sha256.New()
+	for _, header := range headers {
+		h.Write(header.Header.Bytes())
+	}
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+// hashFileData returns the CDN fileHash of raw file contents: base64 of their SHA-256
+func hashFileData(data []byte) string {
+	sum := sha256.Sum256(data)
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
@@ -0,0 +1,488 @@
+package chaintracks
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// testStores returns a fresh instance of every HeaderStore implementation
+func testStores(t *testing.T) map[string]HeaderStore {
+	return map[string]HeaderStore{
+		"file":   NewFileStore(t.TempDir(), "regtest"),
+		"memory": NewMemoryStore("regtest"),
+		"bolt":   newTestBoltStore(t),
+	}
+}
+
+// newTestBoltStore opens a BoltStore in a temp directory that is closed when the test ends
+func newTestBoltStore(t *testing.T) *BoltStore {
+	t.Helper()
+
+	store, err := NewBoltStore(filepath.Join(t.TempDir(), "headers.db"), "regtest")
+	if err != nil {
+		t.Fatalf("NewBoltStore() error = %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+func TestHeaderStorePutBranchAndLoadRange(t *testing.T) {
+	genesis := mineHeader(t, nil, easyBits, 1700000000)
+	chain := append([]*BlockHeader{genesis}, extendChain(t, genesis, 5)...)
+
+	for name, store := range testStores(t) {
+		t.Run(name, func(t *testing.T) {
+			if metadata, err := store.ReadMetadata(); err != nil || metadata != nil {
+				t.Fatalf("ReadMetadata() on empty store = %v, %v, want nil", metadata, err)
+			}
+
+			if err := store.PutBranch(chain); err != nil {
+				t.Fatalf("PutBranch() error = %v", err)
+			}
+
+			loaded, err := store.LoadRange(2, 4)
+			if err != nil {
+				t.Fatalf("LoadRange() error = %v", err)
+			}
+			if len(loaded) != 3 {
+				t.Fatalf("LoadRange() returned %d headers, want 3", len(loaded))
+			}
+			for i, header := range loaded {
+				want := chain[2+i]
+				if header.Height != want.Height || header.Hash != want.Hash {
+					t.Errorf("LoadRange()[%d] = height %d %s, want height %d %s", i, header.Height, header.Hash, want.Height, want.Hash)
+				}
+			}
+
+			metadata, err := store.ReadMetadata()
+			if err != nil {
+				t.Fatalf("ReadMetadata() error = %v", err)
+			}
+			if len(metadata.Files) != 1 {
+				t.Fatalf("ReadMetadata() has %d files, want 1", len(metadata.Files))
+			}
+			entry := metadata.Files[0]
+			if entry.Count != len(chain) || entry.LastHash != chain[5].Hash || entry.LastChainWork != ChainWorkToHex(chain[5].ChainWork) {
+				t.Errorf("Metadata entry = count %d last %s work %s, want the tip", entry.Count, entry.LastHash, entry.LastChainWork)
+			}
+		})
+	}
+}
+
+func TestHeaderStoreReplaceAndTruncate(t *testing.T) {
+	genesis := mineHeader(t, nil, easyBits, 1700000000)
+	chain := append([]*BlockHeader{genesis}, extendChain(t, genesis, 5)...)
+	fork := []*BlockHeader{mineHeader(t, chain[2], easyBits, chain[2].Timestamp+300)}
+
+	for name, store := range testStores(t) {
+		t.Run(name, func(t *testing.T) {
+			if err := store.PutBranch(chain); err != nil {
+				t.Fatalf("PutBranch() error = %v", err)
+			}
+
+			// A shorter branch replaces height 3 and discards everything above it
+			if err := store.PutBranch(fork); err != nil {
+				t.Fatalf("PutBranch() fork error = %v", err)
+			}
+			metadata, err := store.ReadMetadata()
+			if err != nil {
+				t.Fatalf("ReadMetadata() error = %v", err)
+			}
+			if tipHeight, _ := metadataTipHeight(metadata); tipHeight != 3 || metadata.Files[0].LastHash != fork[0].Hash {
+				t.Errorf("Stored tip = height %d %s, want the fork header at height 3", tipHeight, metadata.Files[0].LastHash)
+			}
+			if _, err := store.LoadRange(4, 4); err == nil {
+				t.Error("LoadRange() above the stored tip succeeded")
+			}
+
+			if err := store.TruncateAbove(1); err != nil {
+				t.Fatalf("TruncateAbove() error = %v", err)
+			}
+			metadata, err = store.ReadMetadata()
+			if err != nil {
+				t.Fatalf("ReadMetadata() error = %v", err)
+			}
+			entry := metadata.Files[0]
+			if entry.Count != 2 || entry.LastHash != chain[1].Hash || entry.LastChainWork != ChainWorkToHex(chain[1].ChainWork) {
+				t.Errorf("Metadata after truncate = count %d last %s work %s, want height 1", entry.Count, entry.LastHash, entry.LastChainWork)
+			}
+		})
+	}
+}
+
+func TestFileStoreTruncatesHeaderFile(t *testing.T) {
+	dir := t.TempDir()
+	store := NewFileStore(dir, "regtest")
+
+	genesis := mineHeader(t, nil, easyBits, 1700000000)
+	chain := append([]*BlockHeader{genesis}, extendChain(t, genesis, 3)...)
+	if err := store.PutBranch(chain); err != nil {
+		t.Fatalf("PutBranch() error = %v", err)
+	}
+	if err := store.TruncateAbove(1); err != nil {
+		t.Fatalf("TruncateAbove() error = %v", err)
+	}
+
+	info, err := os.Stat(filepath.Join(dir, "regtestNet_0.headers"))
+	if err != nil {
+		t.Fatalf("Stat() error = %v", err)
+	}
+	if info.Size() != 2*headerSize {
+		t.Errorf("Header file size = %d, want %d", info.Size(), 2*headerSize)
+	}
+}
+
+func TestChainManagerReloadsFromStore(t *testing.T) {
+	for name, store := range testStores(t) {
+		t.Run(name, func(t *testing.T) {
+			cm := newTestChainManager(t, WithHeaderStore(store))
+			headers := extendChain(t, cm.GetTip(), 4)
+			if err := cm.SetChainTip(headers); err != nil {
+				t.Fatalf("SetChainTip() error = %v", err)
+			}
+
+			reloaded, err := NewChainManager("regtest", t.TempDir(), WithHeaderStore(store))
+			if err != nil {
+				t.Fatalf("NewChainManager() error = %v", err)
+			}
+
+			tip := reloaded.GetTip()
+			if tip == nil || tip.Hash != headers[3].Hash {
+				t.Fatalf("Reloaded tip = %v, want %s", tip, headers[3].Hash)
+			}
+			if tip.ChainWork.Cmp(headers[3].ChainWork) != 0 {
+				t.Errorf("Reloaded chainwork = %s, want %s", tip.ChainWork, headers[3].ChainWork)
+			}
+			// Genesis counts no work, as in stores and manifests written before the HeaderStore interface
+			if genesis, err := reloaded.GetHeaderByHeight(0); err != nil || genesis.ChainWork.Sign() != 0 {
+				t.Errorf("Reloaded genesis = %v, %v, want zero chainwork", genesis, err)
+			}
+		})
+	}
+}
+
+func TestBoltStoreLockTimeout(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "headers.db")
+	store, err := NewBoltStore(path, "regtest")
+	if err != nil {
+		t.Fatalf("NewBoltStore() error = %v", err)
+	}
+	defer store.Close()
+
+	defer func(timeout time.Duration) { boltLockTimeout = timeout }(boltLockTimeout)
+	boltLockTimeout = 50 * time.Millisecond
+
+	// A second open of the same database gives up instead of waiting for the lock forever
+	if _, err := NewBoltStore(path, "regtest"); !errors.Is(err, bolt.ErrTimeout) {
+		t.Fatalf("Second NewBoltStore() error = %v, want bolt.ErrTimeout", err)
+	}
+}
+
+func TestBoltStoreKeepsForkHeaders(t *testing.T) {
+	store := newTestBoltStore(t)
+	cm := newTestChainManager(t, WithHeaderStore(store))
+	genesis := cm.GetTip()
+
+	mainChain := extendChain(t, genesis, 3)
+	if err := cm.SetChainTip(mainChain); err != nil {
+		t.Fatalf("SetChainTip() error = %v", err)
+	}
+
+	// A side chain block announced over P2P, plus a reorg that displaces the old tip
+	side := mineHeader(t, mainChain[0], easyBits, mainChain[0].Timestamp+300)
+	if err := cm.addBlockToChain(side.Header, "peer-1"); err != nil {
+		t.Fatalf("addBlockToChain() error = %v", err)
+	}
+	branch := []*BlockHeader{mineHeader(t, mainChain[1], easyBits, mainChain[1].Timestamp+300)}
+	branch = append(branch, extendChain(t, branch[0], 1)...)
+	if err := cm.SetChainTip(branch); err != nil {
+		t.Fatalf("SetChainTip() fork error = %v", err)
+	}
+
+	reloaded, err := NewChainManager("regtest", t.TempDir(), WithHeaderStore(store))
+	if err != nil {
+		t.Fatalf("NewChainManager() error = %v", err)
+	}
+
+	if tip := reloaded.GetTip(); tip == nil || tip.Hash != branch[1].Hash {
+		t.Fatalf("Reloaded tip = %v, want %s", tip, branch[1].Hash)
+	}
+	for _, fork := range []*BlockHeader{side, mainChain[2]} {
+		got, err := reloaded.GetHeaderByHash(&fork.Hash)
+		if err != nil {
+			t.Errorf("Fork header at height %d missing after reload: %v", fork.Height, err)
+			continue
+		}
+		if got.ChainWork.Cmp(fork.ChainWork) != 0 {
+			t.Errorf("Fork header chainwork = %s, want %s", got.ChainWork, fork.ChainWork)
+		}
+	}
+}
+
+func TestBoltStorePrunesForkHeaders(t *testing.T) {
+	store := newTestBoltStore(t)
+	cm := newTestChainManager(t, WithHeaderStore(store))
+	genesis := cm.GetTip()
+
+	mainChain := extendChain(t, genesis, 10)
+	if err := cm.SetChainTip(mainChain); err != nil {
+		t.Fatalf("SetChainTip() error = %v", err)
+	}
+	side := mineHeader(t, mainChain[4], easyBits, mainChain[4].Timestamp+300)
+	if err := cm.AddHeader(side); err != nil {
+		t.Fatalf("AddHeader() error = %v", err)
+	}
+
+	// Once the side header falls more than orphanRetention blocks behind the tip it is deleted from the store
+	if err := cm.SetChainTip(extendChain(t, mainChain[9], orphanRetention)); err != nil {
+		t.Fatalf("SetChainTip() error = %v", err)
+	}
+	forks, err := store.LoadForkHeaders(0)
+	if err != nil {
+		t.Fatalf("LoadForkHeaders() error = %v", err)
+	}
+	if len(forks) != 0 {
+		t.Errorf("LoadForkHeaders() returned %d headers after pruning, want 0", len(forks))
+	}
+	if _, err := cm.GetHeaderByHash(&side.Hash); !errors.Is(err, ErrHeaderNotFound) {
+		t.Errorf("Pruned side header still indexed: %v", err)
+	}
+
+	// Main chain headers are kept
+	headers, err := store.LoadRange(0, cm.GetHeight())
+	if err != nil || len(headers) != int(cm.GetHeight())+1 {
+		t.Errorf("LoadRange() = %d headers, %v, want the whole main chain", len(headers), err)
+	}
+}
+
+func TestCopyHeaders(t *testing.T) {
+	src := NewFileStore(t.TempDir(), "regtest")
+	genesis := mineHeader(t, nil, easyBits, 1700000000)
+	chain := append([]*BlockHeader{genesis}, extendChain(t, genesis, 4)...)
+	if err := src.PutBranch(chain); err != nil {
+		t.Fatalf("PutBranch() error = %v", err)
+	}
+
+	dst := newTestBoltStore(t)
+	if err := CopyHeaders(dst, src); err != nil {
+		t.Fatalf("CopyHeaders() error = %v", err)
+	}
+
+	copied, err := dst.LoadRange(0, 4)
+	if err != nil {
+		t.Fatalf("LoadRange() error = %v", err)
+	}
+	for i, header := range copied {
+		if header.Hash != chain[i].Hash || header.ChainWork.Cmp(chain[i].ChainWork) != 0 {
+			t.Errorf("Copied header %d = %s work %s, want %s work %s", i, header.Hash, header.ChainWork, chain[i].Hash, chain[i].ChainWork)
+		}
+	}
+}
+
+func TestFileStoreRecoverDiscardsUncommittedData(t *testing.T) {
+	dir := t.TempDir()
+	store := NewFileStore(dir, "regtest")
+
+	genesis := mineHeader(t, nil, easyBits, 1700000000)
+	chain := append([]*BlockHeader{genesis}, extendChain(t, genesis, 3)...)
+	if err := store.PutBranch(chain); err != nil {
+		t.Fatalf("PutBranch() error = %v", err)
+	}
+
+	// Simulate a crash after header data and a temp metadata file were written but before the rename
+	headerPath := filepath.Join(dir, "regtestNet_0.headers")
+	f, err := os.OpenFile(headerPath, os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		t.Fatalf("OpenFile() error = %v", err)
+	}
+	if _, err := f.Write(make([]byte, headerSize+17)); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	f.Close()
+	if err := os.WriteFile(store.metadataPath()+tempSuffix, []byte("{partial"), 0644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	if err := store.Recover(); err != nil {
+		t.Fatalf("Recover() error = %v", err)
+	}
+
+	if _, err := os.Stat(store.metadataPath() + tempSuffix); !os.IsNotExist(err) {
+		t.Errorf("Temp metadata file still exists after Recover(): %v", err)
+	}
+	info, err := os.Stat(headerPath)
+	if err != nil {
+		t.Fatalf("Stat() error = %v", err)
+	}
+	if info.Size() != int64(len(chain))*headerSize {
+		t.Errorf("Header file size = %d, want %d", info.Size(), int64(len(chain))*headerSize)
+	}
+}
+
+func TestFileStoreRecoverTruncatesBrokenChain(t *testing.T) {
+	dir := t.TempDir()
+	store := NewFileStore(dir, "regtest")
+
+	genesis := mineHeader(t, nil, easyBits, 1700000000)
+	chain := append([]*BlockHeader{genesis}, extendChain(t, genesis, 5)...)
+	if err := store.PutBranch(chain); err != nil {
+		t.Fatalf("PutBranch() error = %v", err)
+	}
+
+	// Simulate a reorg that overwrote height 3 in place but crashed before committing the metadata,
+	// leaving heights 4 and 5 pointing at a header that is no longer there
+	fork := mineHeader(t, chain[2], easyBits, chain[2].Timestamp+300)
+	f, err := os.OpenFile(filepath.Join(dir, "regtestNet_0.headers"), os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatalf("OpenFile() error = %v", err)
+	}
+	if _, err := f.WriteAt(fork.Header.Bytes(), 3*headerSize); err != nil {
+		t.Fatalf("WriteAt() error = %v", err)
+	}
+	f.Close()
+
+	if err := store.Recover(); err != nil {
+		t.Fatalf("Recover() error = %v", err)
+	}
+
+	metadata, err := store.ReadMetadata()
+	if err != nil {
+		t.Fatalf("ReadMetadata() error = %v", err)
+	}
+	// Nothing links to the fork header, so it cannot be told apart from a corrupt one and is dropped as well
+	entry := metadata.Files[0]
+	if entry.Count != 3 || entry.LastHash != chain[2].Hash || entry.LastChainWork != ChainWorkToHex(chain[2].ChainWork) {
+		t.Errorf("Metadata after recovery = count %d last %s work %s, want height 2", entry.Count, entry.LastHash, entry.LastChainWork)
+	}
+	if _, err := store.LoadRange(3, 3); err == nil {
+		t.Error("LoadRange() above the recovered tip succeeded")
+	}
+}
+
+func TestWriteFileAtomicReplacesContents(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "metadata.json")
+	for _, contents := range []string{"first version", "second"} {
+		if err := writeFileAtomic(path, []byte(contents), 0644); err != nil {
+			t.Fatalf("writeFileAtomic() error = %v", err)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("ReadFile() error = %v", err)
+		}
+		if string(data) != contents {
+			t.Errorf("File contents = %q, want %q", data, contents)
+		}
+	}
+	if _, err := os.Stat(path + tempSuffix); !os.IsNotExist(err) {
+		t.Errorf("Temp file left behind: %v", err)
+	}
+}
+
+func TestFileStoreRecoverLeavesMissingDataAlone(t *testing.T) {
+	dir := t.TempDir()
+	store := NewFileStore(dir, "regtest")
+
+	genesis := mineHeader(t, nil, easyBits, 1700000000)
+	chain := append([]*BlockHeader{genesis}, extendChain(t, genesis, 3)...)
+	if err := store.PutBranch(chain); err != nil {
+		t.Fatalf("PutBranch() error = %v", err)
+	}
+	if err := os.Truncate(filepath.Join(dir, "regtestNet_0.headers"), 2*headerSize); err != nil {
+		t.Fatalf("Truncate() error = %v", err)
+	}
+
+	if err := store.Recover(); err != nil {
+		t.Fatalf("Recover() error = %v", err)
+	}
+
+	// Lost committed data cannot come from an interrupted write, so it is reported by verification instead
+	metadata, err := store.ReadMetadata()
+	if err != nil {
+		t.Fatalf("ReadMetadata() error = %v", err)
+	}
+	if metadata.Files[0].Count != len(chain) {
+		t.Errorf("Metadata count after Recover() = %d, want %d", metadata.Files[0].Count, len(chain))
+	}
+	requireIntegrityError(t, VerifyStore(store), 0)
+}
+
+func TestFileStoreComputesFileHash(t *testing.T) {
+	dir := t.TempDir()
+	store := NewFileStore(dir, "regtest")
+
+	genesis := mineHeader(t, nil, easyBits, 1700000000)
+	chain := append([]*BlockHeader{genesis}, extendChain(t, genesis, 4)...)
+	if err := store.PutBranch(chain[:3]); err != nil {
+		t.Fatalf("PutBranch() error = %v", err)
+	}
+	if err := store.PutBranch(chain[3:]); err != nil {
+		t.Fatalf("PutBranch() error = %v", err)
+	}
+
+	metadata, err := store.ReadMetadata()
+	if err != nil {
+		t.Fatalf("ReadMetadata() error = %v", err)
+	}
+	if metadata.Files[0].FileHash != "" {
+		t.Errorf("FileHash of the open file after PutBranch() = %q, want it left for serving", metadata.Files[0].FileHash)
+	}
+
+	// The open file is hashed when served, and the hash is dropped again once more headers are written
+	if _, err := store.CDNMetadata(); err != nil {
+		t.Fatalf("CDNMetadata() error = %v", err)
+	}
+	if err := store.PutBranch(chain[3:]); err != nil {
+		t.Fatalf("PutBranch() error = %v", err)
+	}
+	if metadata, _ = store.ReadMetadata(); metadata.Files[0].FileHash != "" {
+		t.Errorf("FileHash after rewriting the open file = %q, want it dropped", metadata.Files[0].FileHash)
+	}
+
+	cdnMetadata, err := store.CDNMetadata()
+	if err != nil {
+		t.Fatalf("CDNMetadata() error = %v", err)
+	}
+	if cdnMetadata.Files[0].FileHash != computeFileHash(chain) {
+		t.Errorf("CDNMetadata() FileHash = %q, want %q", cdnMetadata.Files[0].FileHash, computeFileHash(chain))
+	}
+	if metadata, _ = store.ReadMetadata(); metadata.Files[0].FileHash != computeFileHash(chain) {
+		t.Error("FileHash computed for serving was not persisted")
+	}
+}
+
+func TestFileStoreReadCDNFileIgnoresUncommittedData(t *testing.T) {
+	dir := t.TempDir()
+	store := NewFileStore(dir, "regtest")
+
+	genesis := mineHeader(t, nil, easyBits, 1700000000)
+	chain := append([]*BlockHeader{genesis}, extendChain(t, genesis, 3)...)
+	if err := store.PutBranch(chain); err != nil {
+		t.Fatalf("PutBranch() error = %v", err)
+	}
+
+	f, err := os.OpenFile(filepath.Join(dir, "regtestNet_0.headers"), os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		t.Fatalf("OpenFile() error = %v", err)
+	}
+	if _, err := f.Write(make([]byte, headerSize+17)); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	f.Close()
+
+	entry, data, err := store.ReadCDNFile("regtestNet_0.headers")
+	if err != nil {
+		t.Fatalf("ReadCDNFile() error = %v", err)
+	}
+	if len(data) != len(chain)*headerSize || hashFileData(data) != entry.FileHash {
+		t.Errorf("ReadCDNFile() returned %d bytes with hash %q, want %d bytes with hash %q", len(data), hashFileData(data), len(chain)*headerSize, entry.FileHash)
+	}
+
+	if _, _, err := store.ReadCDNFile("regtestNet_1.headers"); !errors.Is(err, ErrHeaderNotFound) {
+		t.Errorf("ReadCDNFile() for an unknown file error = %v, want ErrHeaderNotFound", err)
+	}
+}
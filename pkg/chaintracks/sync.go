@@ -1,6 +1,7 @@
 package chaintracks
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"log"
@@ -20,7 +21,12 @@ const (
 // SyncFromRemoteTip walks backwards from a remote tip to find common ancestor,
 // then imports the entire branch in one operation. This is used for both
 // bootstrap sync and P2P block messages with unknown parents.
-func (cm *ChainManager) SyncFromRemoteTip(remoteTipHash chainhash.Hash, baseURL string) error {
+func (cm *ChainManager) SyncFromRemoteTip(ctx context.Context, remoteTipHash chainhash.Hash, baseURL string) error {
+	return cm.syncFromRemoteTip(ctx, remoteTipHash, baseURL, cm.fetcher)
+}
+
+// syncFromRemoteTip is SyncFromRemoteTip with the fetcher used to request headers
+func (cm *ChainManager) syncFromRemoteTip(ctx context.Context, remoteTipHash chainhash.Hash, baseURL string, f httpFetcher) error {
 	// Check if we already have the remote tip
 	if _, err := cm.GetHeaderByHash(&remoteTipHash); err == nil {
 		log.Printf("Already have block %s", remoteTipHash.String())
@@ -33,6 +39,10 @@ func (cm *ChainManager) SyncFromRemoteTip(remoteTipHash chainhash.Hash, baseURL
 	currentHash := remoteTipHash
 	var commonAncestor *BlockHeader
 
+	cm.mu.RLock()
+	limit, checkpoint := cm.walkLimit(time.Now())
+	cm.mu.RUnlock()
+
 	startTime := time.Now()
 	for {
 		// Check if we have this block in our chain
@@ -44,9 +54,13 @@ func (cm *ChainManager) SyncFromRemoteTip(remoteTipHash chainhash.Hash, baseURL
 			break
 		}
 
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
 		// Fetch batch of headers walking backwards
 		startFetch := time.Now()
-		headers, err := fetchHeadersBackward(baseURL, currentHash.String(), maxHeadersPerRequest)
+		headers, err := f.fetchHeadersBackward(ctx, baseURL, currentHash.String(), maxHeadersPerRequest)
 		fetchDuration := time.Since(startFetch)
 		if err != nil {
 			return fmt.Errorf("failed to fetch headers walking backward from %s: %w", currentHash.String(), err)
@@ -80,6 +94,12 @@ func (cm *ChainManager) SyncFromRemoteTip(remoteTipHash chainhash.Hash, baseURL
 			break
 		}
 
+		// A branch this long that has not linked yet would fork at or below the last checkpoint
+		if limit > 0 && len(branch) > limit {
+			return fmt.Errorf("refusing remote branch from %s: %w: no link to the chain within %d headers, which passes checkpoint at height %d",
+				baseURL, ErrCheckpointMismatch, len(branch), checkpoint.Height)
+		}
+
 		// Continue from the last header's parent
 		currentHash = headers[len(headers)-1].PrevHash
 	}
@@ -93,6 +113,14 @@ func (cm *ChainManager) SyncFromRemoteTip(remoteTipHash chainhash.Hash, baseURL
 		return nil
 	}
 
+	// Refuse branches that would rewrite history below the last checkpoint
+	cm.mu.RLock()
+	err := cm.checkForkPoint(commonAncestor.Height + 1)
+	cm.mu.RUnlock()
+	if err != nil {
+		return fmt.Errorf("refusing remote branch from %s: %w", baseURL, err)
+	}
+
 	log.Printf("Found %d new headers to import", len(branch))
 
 	// Reverse branch (it's currently newest to oldest, we need oldest to newest)
@@ -120,9 +148,20 @@ func (cm *ChainManager) SyncFromRemoteTip(remoteTipHash chainhash.Hash, baseURL
 	}
 	log.Printf("Calculated chainwork for %d headers in %v", len(blockHeaders), time.Since(startConvert))
 
+	// Never trade local headers for a remote branch with less work
+	if tip := cm.GetTip(); tip != nil && tip.Height > commonAncestor.Height && tip.ChainWork.Cmp(blockHeaders[len(blockHeaders)-1].ChainWork) >= 0 {
+		log.Printf("Local chain at height %d has at least as much work as the remote branch at height %d, keeping it", tip.Height, blockHeaders[len(blockHeaders)-1].Height)
+		return nil
+	}
+
+	// Validate the branch before anything is stored
+	if err := cm.validateHeaders(blockHeaders, baseURL); err != nil {
+		return fmt.Errorf("remote branch failed validation: %w", err)
+	}
+
 	// Import entire branch in one operation
 	startSetTip := time.Now()
-	if err := cm.SetChainTip(blockHeaders); err != nil {
+	if err := cm.setChainTip(blockHeaders); err != nil {
 		return fmt.Errorf("failed to set chain tip: %w", err)
 	}
 	log.Printf("SetChainTip took %v", time.Since(startSetTip))
@@ -134,9 +173,15 @@ func (cm *ChainManager) SyncFromRemoteTip(remoteTipHash chainhash.Hash, baseURL
 	return nil
 }
 
-// FetchLatestBlock gets the latest block hash from the node's bestblockheader endpoint
-func FetchLatestBlock(baseURL string) (chainhash.Hash, error) {
-	resp, err := http.Get(fmt.Sprintf("%s/bestblockheader", baseURL))
+// FetchLatestBlock gets the latest block hash from the node's bestblockheader endpoint, using DefaultHTTPClient and
+// DefaultRetryPolicy
+func FetchLatestBlock(ctx context.Context, baseURL string) (chainhash.Hash, error) {
+	return defaultFetcher().fetchLatestBlock(ctx, baseURL)
+}
+
+// fetchLatestBlock is FetchLatestBlock with the fetcher's client and retry policy
+func (f httpFetcher) fetchLatestBlock(ctx context.Context, baseURL string) (chainhash.Hash, error) {
+	resp, err := f.get(ctx, fmt.Sprintf("%s/bestblockheader", baseURL))
 	if err != nil {
 		return chainhash.Hash{}, fmt.Errorf("failed to fetch best block header: %w", err)
 	}
@@ -166,11 +211,11 @@ func FetchLatestBlock(baseURL string) (chainhash.Hash, error) {
 // fetchHeadersBackward fetches headers walking backwards from a starting hash
 // Uses the /headers/:hash endpoint which traverses backwards (child -> parent)
 // Returns headers in reverse chronological order (newest first)
-func fetchHeadersBackward(baseURL, startHash string, count int) ([]*block.Header, error) {
+func (f httpFetcher) fetchHeadersBackward(ctx context.Context, baseURL, startHash string, count int) ([]*block.Header, error) {
 	// Use binary endpoint for efficiency (80 bytes per header vs 160 for hex)
 	url := fmt.Sprintf("%s/headers/%s?n=%d", baseURL, startHash, count)
 
-	resp, err := http.Get(url)
+	resp, err := f.get(ctx, url)
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch headers: %w", err)
 	}
@@ -15,6 +15,59 @@ type BlockHeader struct {
 	ChainWork *big.Int       `json:"-"` // Cumulative chain work up to and including this block
 }
 
+// ReorgEvent describes a switch of the main chain from one branch to another
+type ReorgEvent struct {
+	CommonAncestor *BlockHeader   `json:"commonAncestor"` // Last block shared by both branches
+	Disconnected   []*BlockHeader `json:"disconnected"`   // Old main chain blocks removed, oldest first
+	Connected      []*BlockHeader `json:"connected"`      // New main chain blocks added, oldest first
+}
+
+// RootCheck is a merkle root to validate against the main chain header at a height
+type RootCheck struct {
+	Height     uint32         `json:"height"`
+	MerkleRoot chainhash.Hash `json:"merkleRoot"`
+}
+
+// RootValidity is the outcome of validating a merkle root
+type RootValidity string
+
+const (
+	// RootValid means the main chain header at the height has the merkle root
+	RootValid RootValidity = "valid"
+	// RootInvalid means the main chain header at the height has a different merkle root
+	RootInvalid RootValidity = "invalid"
+	// RootUnknown means the height is beyond the local tip, so the root cannot be judged yet
+	RootUnknown RootValidity = "unknown"
+)
+
+// RootResult is the outcome of one RootCheck
+type RootResult struct {
+	Height     uint32         `json:"height"`
+	MerkleRoot chainhash.Hash `json:"merkleRoot"`
+	Result     RootValidity   `json:"result"`
+}
+
+// RootStatus is how a merkle root at a height relates to the local chain
+type RootStatus struct {
+	Height        uint32          `json:"height"`
+	MerkleRoot    chainhash.Hash  `json:"merkleRoot"`
+	TipHeight     uint32          `json:"tipHeight"`
+	OnMainChain   bool            `json:"onMainChain"`         // The main chain header at the height has the root
+	Confirmations uint32          `json:"confirmations"`       // 1 in the tip block, 0 unless OnMainChain
+	OnFork        bool            `json:"onFork"`              // A known side chain header at the height has the root instead
+	BeyondTip     bool            `json:"beyondTip"`           // The height is above the tip, so the root cannot be judged until synced
+	BlockHash     *chainhash.Hash `json:"blockHash,omitempty"` // Header with the root, on the main chain or a fork
+}
+
+// ProofResult is the outcome of checking one transaction's merkle proof against the main chain
+type ProofResult struct {
+	TxID          chainhash.Hash `json:"txid"`
+	BlockHeight   uint32         `json:"blockHeight"`
+	MerkleRoot    chainhash.Hash `json:"merkleRoot"` // Root computed from the proof
+	Result        RootValidity   `json:"result"`
+	Confirmations uint32         `json:"confirmations"` // 1 in the tip block, 0 unless Result is valid
+}
+
 // CDNMetadata represents the JSON metadata file structure
 type CDNMetadata struct {
 	RootFolder     string         `json:"rootFolder"`
@@ -50,7 +103,8 @@ type BlockMessage struct {
 
 // PeerInfo contains information about a connected peer
 type PeerInfo struct {
-	ID    string
-	Name  string
-	Addrs []string
+	ID         string
+	Name       string
+	Addrs      []string
+	Reputation *PeerScore // nil if the peer has not announced blocks
 }
@@ -0,0 +1,308 @@
+package chaintracks
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/bsv-blockchain/go-sdk/chainhash"
+)
+
+// fakeUpstream serves a chain the way every upstream ChainManager syncs from does: the Teranode endpoints
+// SyncFromRemoteTip and crawl-backs use, the Chaintracks v2 endpoints SyncForward uses and, once wireAddr is
+// called, the legacy wire protocol of an SV Node
+type fakeUpstream struct {
+	*httptest.Server
+	t *testing.T
+
+	gate       chan struct{} // HTTP requests wait for it to close when set
+	release    func()        // Lets held requests through
+	failHeight uint32        // /v2/headers requests starting here fail when non-zero
+	tip        *BlockHeader  // Tip advertised instead of the last chain header when set
+
+	mu    sync.Mutex
+	chain []*BlockHeader
+	index map[chainhash.Hash]int
+
+	requests    atomic.Int32
+	inFlight    atomic.Int32
+	maxInFlight atomic.Int32
+	getHeaders  atomic.Int32 // getheaders messages received over the wire protocol
+
+	// Legacy wire protocol
+	listenOnce sync.Once
+	listener   net.Listener
+	conns      []net.Conn
+	conn       net.Conn // Most recent handshaken connection
+	ready      chan struct{}
+	writeMu    sync.Mutex
+}
+
+// upstreamOption configures a fakeUpstream before it serves its first request
+type upstreamOption func(*fakeUpstream)
+
+// withGate holds every HTTP request until release is called
+func withGate() upstreamOption {
+	return func(u *fakeUpstream) {
+		u.gate = make(chan struct{})
+		var once sync.Once
+		u.release = func() { once.Do(func() { close(u.gate) }) }
+	}
+}
+
+// withFailingBatch fails /v2/headers requests starting at height
+func withFailingBatch(height uint32) upstreamOption {
+	return func(u *fakeUpstream) {
+		u.failHeight = height
+	}
+}
+
+// withAdvertisedTip advertises tip, which the upstream does not serve the headers of
+func withAdvertisedTip(tip *BlockHeader) upstreamOption {
+	return func(u *fakeUpstream) {
+		u.tip = tip
+	}
+}
+
+func newFakeUpstream(t *testing.T, chain []*BlockHeader, opts ...upstreamOption) *fakeUpstream {
+	t.Helper()
+
+	u := &fakeUpstream{t: t, release: func() {}, ready: make(chan struct{})}
+	for _, opt := range opts {
+		opt(u)
+	}
+	u.extendChain(chain)
+
+	u.Server = httptest.NewServer(http.HandlerFunc(u.serveHTTP))
+	t.Cleanup(u.Close)
+	t.Cleanup(func() { u.release() }) // Runs first, so held requests can finish before the server closes
+	return u
+}
+
+// extendChain appends headers to the chain served, returning the connection to announce them on, if any
+func (u *fakeUpstream) extendChain(headers []*BlockHeader) net.Conn {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	if u.index == nil {
+		u.index = make(map[chainhash.Hash]int)
+	}
+	for _, header := range headers {
+		u.index[header.Hash] = len(u.chain)
+		u.chain = append(u.chain, header)
+	}
+	return u.conn
+}
+
+// snapshot returns the chain served and its advertised tip
+func (u *fakeUpstream) snapshot() ([]*BlockHeader, *BlockHeader) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	if u.tip != nil {
+		return u.chain, u.tip
+	}
+	return u.chain, u.chain[len(u.chain)-1]
+}
+
+func (u *fakeUpstream) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	u.requests.Add(1)
+	n := u.inFlight.Add(1)
+	defer u.inFlight.Add(-1)
+	for {
+		m := u.maxInFlight.Load()
+		if n <= m || u.maxInFlight.CompareAndSwap(m, n) {
+			break
+		}
+	}
+
+	if u.gate != nil {
+		select {
+		case <-u.gate:
+		case <-r.Context().Done():
+			return
+		}
+	}
+
+	chain, tip := u.snapshot()
+	writeValue := func(value any) {
+		json.NewEncoder(w).Encode(map[string]any{"status": "success", "value": value})
+	}
+	writeHeader := func(header *BlockHeader) {
+		writeValue(map[string]any{"height": header.Height, "hash": header.Hash})
+	}
+
+	switch {
+	case r.URL.Path == "/bestblockheader":
+		w.Write(tip.Header.Bytes())
+
+	case strings.HasPrefix(r.URL.Path, "/headers/"):
+		hash, err := chainhash.NewHashFromHex(strings.TrimPrefix(r.URL.Path, "/headers/"))
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		u.mu.Lock()
+		i, ok := u.index[*hash]
+		u.mu.Unlock()
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		n, _ := strconv.Atoi(r.URL.Query().Get("n"))
+		for ; i >= 0 && n > 0; i, n = i-1, n-1 {
+			w.Write(chain[i].Header.Bytes())
+		}
+
+	case r.URL.Path == "/v2/tip/header":
+		writeHeader(tip)
+
+	case strings.HasPrefix(r.URL.Path, "/v2/header/height/"):
+		height, _ := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/v2/header/height/"))
+		if height >= len(chain) {
+			http.NotFound(w, r)
+			return
+		}
+		writeHeader(chain[height])
+
+	case r.URL.Path == "/v2/headers":
+		height, _ := strconv.Atoi(r.URL.Query().Get("height"))
+		count, _ := strconv.Atoi(r.URL.Query().Get("count"))
+
+		// Give other batch requests a chance to overlap
+		time.Sleep(10 * time.Millisecond)
+		if u.failHeight != 0 && uint32(height) == u.failHeight {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+
+		var data []byte
+		for _, header := range chain[min(height, len(chain)):min(height+count, len(chain))] {
+			data = append(data, header.Header.Bytes()...)
+		}
+		writeValue(hex.EncodeToString(data))
+
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// waitForRequests waits until the upstream has received want HTTP requests
+func (u *fakeUpstream) waitForRequests(t *testing.T, want int32) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for u.requests.Load() < want {
+		if time.Now().After(deadline) {
+			t.Fatalf("Upstream received %d requests, want %d", u.requests.Load(), want)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// wireAddr starts serving the chain over the legacy wire protocol, returning the address to connect to
+func (u *fakeUpstream) wireAddr() string {
+	u.listenOnce.Do(func() {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			u.t.Fatalf("Failed to listen: %v", err)
+		}
+		u.listener = listener
+		u.t.Cleanup(func() {
+			listener.Close()
+			u.mu.Lock()
+			defer u.mu.Unlock()
+			for _, conn := range u.conns {
+				conn.Close()
+			}
+		})
+
+		go func() {
+			for {
+				conn, err := listener.Accept()
+				if err != nil {
+					return
+				}
+				u.mu.Lock()
+				u.conns = append(u.conns, conn)
+				u.mu.Unlock()
+				go u.serveWire(conn)
+			}
+		}()
+	})
+	return u.listener.Addr().String()
+}
+
+func (u *fakeUpstream) send(conn net.Conn, command string, payload []byte) {
+	u.writeMu.Lock()
+	defer u.writeMu.Unlock()
+	writeWireMessage(conn, testParams.WireMagic, command, payload)
+}
+
+func (u *fakeUpstream) serveWire(conn net.Conn) {
+	for {
+		command, payload, err := readWireMessage(conn, testParams.WireMagic)
+		if err != nil {
+			return
+		}
+
+		switch command {
+		case wireCmdVersion:
+			_, tip := u.snapshot()
+			u.send(conn, wireCmdVersion, encodeVersion(&wireVersion{ProtocolVersion: wireProtocolVersion, Nonce: 42, UserAgent: "/Bitcoin SV:1.1.0/", StartHeight: int32(tip.Height)}, time.Now()))
+			u.send(conn, wireCmdVerAck, nil)
+
+		case wireCmdVerAck:
+			u.mu.Lock()
+			u.conn = conn
+			close(u.ready)
+			u.mu.Unlock()
+
+		case wireCmdGetHeaders:
+			u.getHeaders.Add(1)
+			locator, _, err := decodeGetHeaders(payload)
+			if err != nil {
+				u.t.Errorf("Fake node received a bad getheaders: %v", err)
+				return
+			}
+			u.send(conn, wireCmdHeaders, encodeHeaders(u.headersAfter(locator)))
+		}
+	}
+}
+
+// headersAfter returns up to wireMaxHeadersPerMsg headers following the first locator hash on the chain
+func (u *fakeUpstream) headersAfter(locator []chainhash.Hash) []*BlockHeader {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	start := 0
+	for _, hash := range locator {
+		if i, ok := u.index[hash]; ok {
+			start = i + 1
+			break
+		}
+	}
+	return u.chain[start:min(start+wireMaxHeadersPerMsg, len(u.chain))]
+}
+
+// extend adds headers to the chain and announces them to the connected wire peer.
+// The last header is announced with a headers message, or an inv if useInv is set.
+func (u *fakeUpstream) extend(headers []*BlockHeader, useInv bool) {
+	<-u.ready
+	conn := u.extendChain(headers)
+
+	last := headers[len(headers)-1]
+	if useInv {
+		u.send(conn, wireCmdInv, encodeInv([]chainhash.Hash{last.Hash}))
+	} else {
+		u.send(conn, wireCmdHeaders, encodeHeaders([]*BlockHeader{last}))
+	}
+}
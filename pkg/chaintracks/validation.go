@@ -0,0 +1,189 @@
+package chaintracks
+
+import (
+	"fmt"
+	"log"
+	"math/big"
+	"time"
+
+	"github.com/bsv-blockchain/go-sdk/chainhash"
+)
+
+// maxFutureBlockTime is how far ahead of local time a header's timestamp may be
+const maxFutureBlockTime = 2 * time.Hour
+
+// HashToBig converts a block hash to a big.Int so it can be compared against a target.
+// Hashes are stored little-endian, so the bytes are reversed before conversion.
+func HashToBig(hash *chainhash.Hash) *big.Int {
+	buf := *hash
+	for i := 0; i < chainhash.HashSize/2; i++ {
+		buf[i], buf[chainhash.HashSize-1-i] = buf[chainhash.HashSize-1-i], buf[i]
+	}
+	return new(big.Int).SetBytes(buf[:])
+}
+
+// checkProofOfWork verifies that the header hash does not exceed the target encoded in its Bits field.
+// If powLimit is non-nil, the target itself must not exceed it. The hash must be the hash of the header fields,
+// not just the value the sender supplied.
+func checkProofOfWork(header *BlockHeader, powLimit *big.Int) error {
+	target := CompactToBig(header.Bits)
+	if target.Sign() <= 0 {
+		return fmt.Errorf("%w: target for bits %08x is not positive", ErrInsufficientPoW, header.Bits)
+	}
+
+	if powLimit != nil && target.Cmp(powLimit) > 0 {
+		return fmt.Errorf("%w: target for bits %08x is above the network limit", ErrInsufficientPoW, header.Bits)
+	}
+
+	if hash := header.Header.Hash(); hash != header.Hash {
+		return fmt.Errorf("%w: hash %s does not match the header, which hashes to %s", ErrInvalidHeader, header.Hash.String(), hash.String())
+	}
+
+	if HashToBig(&header.Hash).Cmp(target) > 0 {
+		return fmt.Errorf("%w: hash %s is above target for bits %08x", ErrInsufficientPoW, header.Hash.String(), header.Bits)
+	}
+
+	return nil
+}
+
+// validateHeaders checks every header in a branch before it is stored or considered as a tip.
+// headers should be ordered from oldest to newest. source identifies where the headers came
+// from (P2P peer ID or bootstrap URL) and is used to attribute rejections.
+func (cm *ChainManager) validateHeaders(headers []*BlockHeader, source string) error {
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
+
+	if len(headers) > 0 {
+		if err := cm.checkForkPoint(headers[0].Height); err != nil {
+			cm.recordRejectedHeader(source, headers[0], err)
+			return err
+		}
+	}
+
+	cv := &chainView{
+		cm:      cm,
+		pending: make(map[chainhash.Hash]*BlockHeader, len(headers)),
+	}
+
+	for _, header := range headers {
+		if err := cm.validateHeader(cv, header); err != nil {
+			cm.recordRejectedHeader(source, header, err)
+			return err
+		}
+		cv.pending[header.Hash] = header
+	}
+
+	return nil
+}
+
+// validateHeader runs all checks for a single header against its ancestry (must be called with lock held)
+func (cm *ChainManager) validateHeader(cv *chainView, header *BlockHeader) error {
+	var powLimit *big.Int
+	if cm.params != nil {
+		powLimit = cm.params.PowLimit
+	}
+
+	if err := checkProofOfWork(header, powLimit); err != nil {
+		return err
+	}
+
+	if err := cm.checkCheckpoint(header); err != nil {
+		return err
+	}
+
+	if maxTime := time.Now().Add(maxFutureBlockTime); int64(header.Timestamp) > maxTime.Unix() {
+		return fmt.Errorf("%w: header %s timestamp %d is more than %v in the future", ErrInvalidTimestamp, header.Hash.String(), header.Timestamp, maxFutureBlockTime)
+	}
+
+	// Genesis has no ancestry to check against
+	if header.Height == 0 {
+		return cm.checkGenesis(header)
+	}
+
+	parent := cv.lookup(&header.PrevHash)
+	if parent == nil {
+		return fmt.Errorf("%w: parent %s of header %s is unknown", ErrBrokenChain, header.PrevHash.String(), header.Hash.String())
+	}
+	if parent.Height+1 != header.Height {
+		return fmt.Errorf("%w: header %s claims height %d but parent is at height %d", ErrBrokenChain, header.Hash.String(), header.Height, parent.Height)
+	}
+
+	// Tip selection compares chainwork, so it must be the parent's plus this header's
+	if want := AddWork(parent.ChainWork, header.Bits); header.ChainWork == nil || header.ChainWork.Cmp(want) != 0 {
+		return fmt.Errorf("%w: header %s has chainwork %v, expected %s", ErrInvalidHeader, header.Hash.String(), header.ChainWork, want)
+	}
+
+	// Timestamp must be after the median of the previous 11 blocks
+	if mtp, ok := cv.medianTimePast(parent); ok && header.Timestamp <= mtp {
+		return fmt.Errorf("%w: header %s timestamp %d is not after median time past %d", ErrInvalidTimestamp, header.Hash.String(), header.Timestamp, mtp)
+	}
+
+	if cm.params == nil {
+		return nil
+	}
+
+	if required, ok := cv.nextRequiredBits(cm.params, parent, header.Timestamp); ok && header.Bits != required {
+		return fmt.Errorf("%w: header %s at height %d has bits %08x, expected %08x", ErrInvalidDifficulty, header.Hash.String(), header.Height, header.Bits, required)
+	}
+
+	return nil
+}
+
+// checkGenesis verifies that a header at height 0 is the network's genesis: the height 0 checkpoint if the network
+// has one, otherwise the genesis already stored. The first genesis of a network without one is accepted.
+// Must be called with lock held.
+func (cm *ChainManager) checkGenesis(header *BlockHeader) error {
+	if header.PrevHash != (chainhash.Hash{}) {
+		return fmt.Errorf("%w: genesis %s has previous hash %s", ErrBrokenChain, header.Hash.String(), header.PrevHash.String())
+	}
+	if header.ChainWork == nil || header.ChainWork.Sign() != 0 {
+		return fmt.Errorf("%w: genesis %s has chainwork %v, expected 0", ErrInvalidHeader, header.Hash.String(), header.ChainWork)
+	}
+
+	expected, ok := cm.checkpoints[0]
+	if !ok && len(cm.byHeight) > 0 {
+		expected, ok = cm.byHeight[0], true
+	}
+	if ok && header.Hash != expected {
+		return fmt.Errorf("%w: header %s at height 0 is not the genesis %s", ErrInvalidHeader, header.Hash.String(), expected.String())
+	}
+	return nil
+}
+
+// recordRejectedHeader counts and logs a header that failed validation
+func (cm *ChainManager) recordRejectedHeader(source string, header *BlockHeader, reason error) {
+	cm.rejectedMu.Lock()
+	if _, ok := cm.rejectedHeaders[source]; !ok && len(cm.rejectedHeaders) >= maxTrackedPeers {
+		cm.evictRejectedSource()
+	}
+	cm.rejectedHeaders[source]++
+	count := cm.rejectedHeaders[source]
+	cm.rejectedMu.Unlock()
+
+	log.Printf("Rejected header %s at height %d from %s (%d rejected from this source): %v",
+		header.Hash.String(), header.Height, source, count, reason)
+}
+
+// evictRejectedSource drops the source with the fewest rejected headers, so the table stays bounded when peer IDs
+// rotate while the worst offenders remain visible. The caller must hold rejectedMu.
+func (cm *ChainManager) evictRejectedSource() {
+	var fewest string
+	for source, count := range cm.rejectedHeaders {
+		if fewest == "" || count < cm.rejectedHeaders[fewest] {
+			fewest = source
+		}
+	}
+	delete(cm.rejectedHeaders, fewest)
+}
+
+// GetRejectedHeaderCounts returns the number of headers rejected by validation, keyed by source
+func (cm *ChainManager) GetRejectedHeaderCounts() map[string]uint64 {
+	cm.rejectedMu.Lock()
+	defer cm.rejectedMu.Unlock()
+
+	counts := make(map[string]uint64, len(cm.rejectedHeaders))
+	for source, count := range cm.rejectedHeaders {
+		counts[source] = count
+	}
+	return counts
+}
@@ -0,0 +1,314 @@
+package chaintracks
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/bsv-blockchain/go-sdk/block"
+	"github.com/bsv-blockchain/go-sdk/chainhash"
+)
+
+// easyBits is the regtest difficulty, where roughly every other nonce yields a valid hash
+const easyBits = 0x207fffff
+
+// testParams mirrors regtest: an easy proof-of-work limit and no retargeting
+var testParams = NetworkParams{
+	Name:           "regtest",
+	PowLimit:       new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 255), big.NewInt(1)),
+	PowLimitBits:   easyBits,
+	TargetTimespan: 14 * 24 * 60 * 60,
+	TargetSpacing:  10 * 60,
+	NoRetargeting:  true,
+	WireMagic:      0xfabfb5da,
+	DefaultPort:    "18444",
+}
+
+// mineHeader builds a child of parent (or a genesis header when parent is nil)
+// and searches nonces until the hash satisfies the target for bits
+func mineHeader(t *testing.T, parent *BlockHeader, bits, timestamp uint32) *BlockHeader {
+	t.Helper()
+
+	header := &block.Header{
+		Version:   1,
+		Timestamp: timestamp,
+		Bits:      bits,
+	}
+
+	height := uint32(0)
+	chainWork := big.NewInt(0)
+	if parent != nil {
+		header.PrevHash = parent.Hash
+		height = parent.Height + 1
+		chainWork = AddWork(parent.ChainWork, bits)
+	}
+
+	target := CompactToBig(bits)
+	for nonce := uint32(0); nonce < 1<<20; nonce++ {
+		header.Nonce = nonce
+		hash := header.Hash()
+		if HashToBig(&hash).Cmp(target) <= 0 {
+			return &BlockHeader{
+				Header:    header,
+				Height:    height,
+				Hash:      hash,
+				ChainWork: chainWork,
+			}
+		}
+	}
+
+	t.Fatalf("failed to mine header for bits %08x", bits)
+	return nil
+}
+
+// newTestChainManager returns a ChainManager backed by a temp directory with a mined genesis header
+func newTestChainManager(t *testing.T, opts ...Option) *ChainManager {
+	t.Helper()
+
+	// Retry failed requests without making tests wait; options from the caller take precedence
+	opts = append([]Option{WithRetryPolicy(testRetryPolicy)}, opts...)
+	cm, err := NewChainManager("regtest", t.TempDir(), opts...)
+	if err != nil {
+		t.Fatalf("Failed to create ChainManager: %v", err)
+	}
+	cm.params = &testParams
+
+	genesis := mineHeader(t, nil, easyBits, 1700000000)
+	if err := cm.SetChainTip([]*BlockHeader{genesis}); err != nil {
+		t.Fatalf("Failed to set genesis: %v", err)
+	}
+
+	return cm
+}
+
+func TestCheckProofOfWork(t *testing.T) {
+	valid := mineHeader(t, nil, easyBits, 1700000000)
+	if err := checkProofOfWork(valid, nil); err != nil {
+		t.Errorf("checkProofOfWork() rejected a mined header: %v", err)
+	}
+
+	// Claim mainnet genesis difficulty without doing the work
+	forged := &block.Header{
+		Version:   valid.Version,
+		Timestamp: valid.Timestamp,
+		Bits:      0x1d00ffff,
+		Nonce:     valid.Nonce,
+	}
+	invalid := &BlockHeader{Header: forged, Hash: forged.Hash()}
+	if err := checkProofOfWork(invalid, nil); !errors.Is(err, ErrInsufficientPoW) {
+		t.Errorf("checkProofOfWork() error = %v, want ErrInsufficientPoW", err)
+	}
+
+	negative := &BlockHeader{Header: &block.Header{Bits: 0x01810000}, Hash: chainhash.Hash{}}
+	if err := checkProofOfWork(negative, nil); !errors.Is(err, ErrInsufficientPoW) {
+		t.Errorf("checkProofOfWork() with negative target error = %v, want ErrInsufficientPoW", err)
+	}
+}
+
+func TestSetChainTipRejectsInsufficientPoW(t *testing.T) {
+	cm := newTestChainManager(t)
+	genesis := cm.GetTip()
+
+	child := mineHeader(t, genesis, easyBits, genesis.Timestamp+600)
+	child.Header.Bits = 0x1d00ffff
+	child.Hash = child.Header.Hash()
+
+	err := cm.SetChainTip([]*BlockHeader{child})
+	if !errors.Is(err, ErrInsufficientPoW) {
+		t.Fatalf("SetChainTip() error = %v, want ErrInsufficientPoW", err)
+	}
+
+	if cm.GetTip() != genesis {
+		t.Errorf("Tip changed after rejected header")
+	}
+
+	if _, err := cm.GetHeaderByHash(&child.Hash); !errors.Is(err, ErrHeaderNotFound) {
+		t.Errorf("Rejected header was stored")
+	}
+
+	if got := cm.GetRejectedHeaderCounts()["SetChainTip"]; got != 1 {
+		t.Errorf("Expected 1 rejected header for SetChainTip, got %d", got)
+	}
+}
+
+func TestAddBlockToChainRejectsInsufficientPoW(t *testing.T) {
+	cm := newTestChainManager(t)
+	genesis := cm.GetTip()
+
+	child := mineHeader(t, genesis, easyBits, genesis.Timestamp+600)
+	child.Header.Bits = 0x1d00ffff
+
+	err := cm.addBlockToChain(child.Header, "peer-1")
+	if !errors.Is(err, ErrInsufficientPoW) {
+		t.Fatalf("addBlockToChain() error = %v, want ErrInsufficientPoW", err)
+	}
+
+	if got := cm.GetRejectedHeaderCounts()["peer-1"]; got != 1 {
+		t.Errorf("Expected 1 rejected header for peer-1, got %d", got)
+	}
+
+	valid := mineHeader(t, genesis, easyBits, genesis.Timestamp+600)
+	if err := cm.addBlockToChain(valid.Header, "peer-1"); err != nil {
+		t.Fatalf("addBlockToChain() rejected a valid header: %v", err)
+	}
+
+	if cm.GetTip().Hash != valid.Hash {
+		t.Errorf("Valid header did not become the tip")
+	}
+}
+
+func TestCheckProofOfWorkAbovePowLimit(t *testing.T) {
+	header := mineHeader(t, nil, easyBits, 1700000000)
+	if err := checkProofOfWork(header, MainNetParams.PowLimit); !errors.Is(err, ErrInsufficientPoW) {
+		t.Errorf("checkProofOfWork() error = %v, want ErrInsufficientPoW", err)
+	}
+}
+
+func TestRejectedHeaderCountsAreBounded(t *testing.T) {
+	cm := newTestChainManager(t)
+	header := mineHeader(t, cm.GetTip(), easyBits, 1700000600)
+
+	cm.recordRejectedHeader("persistent", header, ErrInvalidHeader)
+	cm.recordRejectedHeader("persistent", header, ErrInvalidHeader)
+	for i := 0; i < 2*maxTrackedPeers; i++ {
+		cm.recordRejectedHeader(fmt.Sprintf("peer-%d", i), header, ErrInvalidHeader)
+	}
+
+	counts := cm.GetRejectedHeaderCounts()
+	if len(counts) != maxTrackedPeers {
+		t.Errorf("Tracked %d sources, want %d", len(counts), maxTrackedPeers)
+	}
+	if counts["persistent"] != 2 {
+		t.Errorf("Source with the most rejections has count %d, want 2", counts["persistent"])
+	}
+}
+
+func TestSetChainTipRejectsWrongDifficulty(t *testing.T) {
+	cm := newTestChainManager(t)
+	genesis := cm.GetTip()
+
+	// Regtest never retargets, so a child must carry its parent's bits
+	child := mineHeader(t, genesis, 0x207ffffe, genesis.Timestamp+600)
+	if err := cm.SetChainTip([]*BlockHeader{child}); !errors.Is(err, ErrInvalidDifficulty) {
+		t.Fatalf("SetChainTip() error = %v, want ErrInvalidDifficulty", err)
+	}
+
+	child = mineHeader(t, genesis, easyBits, genesis.Timestamp+600)
+	if err := cm.SetChainTip([]*BlockHeader{child}); err != nil {
+		t.Fatalf("SetChainTip() rejected a valid header: %v", err)
+	}
+}
+
+func TestSetChainTipRejectsMismatchedHash(t *testing.T) {
+	cm := newTestChainManager(t)
+	genesis := cm.GetTip()
+
+	// Claim the hash of a mined header for different fields
+	mined := mineHeader(t, genesis, easyBits, genesis.Timestamp+600)
+	forged := mineHeader(t, genesis, easyBits, genesis.Timestamp+1200)
+	forged.Hash = mined.Hash
+	if err := cm.SetChainTip([]*BlockHeader{forged}); !errors.Is(err, ErrInvalidHeader) {
+		t.Fatalf("SetChainTip() error = %v, want ErrInvalidHeader", err)
+	}
+}
+
+func TestSetChainTipRejectsWrongChainWork(t *testing.T) {
+	cm := newTestChainManager(t)
+	genesis := cm.GetTip()
+
+	child := mineHeader(t, genesis, easyBits, genesis.Timestamp+600)
+	child.ChainWork = new(big.Int).Lsh(child.ChainWork, 64)
+	if err := cm.SetChainTip([]*BlockHeader{child}); !errors.Is(err, ErrInvalidHeader) {
+		t.Fatalf("SetChainTip() error = %v, want ErrInvalidHeader", err)
+	}
+
+	child.ChainWork = nil
+	if err := cm.SetChainTip([]*BlockHeader{child}); !errors.Is(err, ErrInvalidHeader) {
+		t.Fatalf("SetChainTip() without chainwork error = %v, want ErrInvalidHeader", err)
+	}
+}
+
+func TestSetChainTipRejectsOtherGenesis(t *testing.T) {
+	cm := newTestChainManager(t)
+	genesis := cm.GetTip()
+
+	// A height 0 header replacing the stored genesis would skip every parent check
+	other := mineHeader(t, nil, easyBits, genesis.Timestamp+600)
+	if err := cm.SetChainTip([]*BlockHeader{other}); !errors.Is(err, ErrInvalidHeader) {
+		t.Fatalf("SetChainTip() error = %v, want ErrInvalidHeader", err)
+	}
+	if err := cm.SetChainTip([]*BlockHeader{genesis}); err != nil {
+		t.Errorf("SetChainTip() rejected the stored genesis: %v", err)
+	}
+
+	// Networks with a genesis checkpoint accept only that header
+	fresh, err := NewChainManager("regtest", t.TempDir(), WithCheckpoints(Checkpoint{Height: 0, Hash: genesis.Hash}))
+	if err != nil {
+		t.Fatalf("NewChainManager() error = %v", err)
+	}
+	fresh.params = &testParams
+	if err := fresh.SetChainTip([]*BlockHeader{other}); !errors.Is(err, ErrCheckpointMismatch) {
+		t.Fatalf("SetChainTip() error = %v, want ErrCheckpointMismatch", err)
+	}
+	if err := fresh.SetChainTip([]*BlockHeader{genesis}); err != nil {
+		t.Errorf("SetChainTip() rejected the genesis checkpoint: %v", err)
+	}
+}
+
+func TestSetChainTipRejectsUnknownParent(t *testing.T) {
+	cm := newTestChainManager(t)
+
+	orphanParent := mineHeader(t, nil, easyBits, 1700000600)
+	orphanParent.Height = 5
+	child := mineHeader(t, orphanParent, easyBits, 1700001200)
+
+	if err := cm.SetChainTip([]*BlockHeader{child}); !errors.Is(err, ErrBrokenChain) {
+		t.Fatalf("SetChainTip() error = %v, want ErrBrokenChain", err)
+	}
+}
+
+func TestSetChainTipRejectsInvalidTimestamps(t *testing.T) {
+	cm := newTestChainManager(t)
+	genesis := cm.GetTip()
+
+	branch := []*BlockHeader{genesis}
+	for i := 0; i < 11; i++ {
+		parent := branch[len(branch)-1]
+		branch = append(branch, mineHeader(t, parent, easyBits, parent.Timestamp+600))
+	}
+	if err := cm.SetChainTip(branch[1:]); err != nil {
+		t.Fatalf("SetChainTip() rejected a valid branch: %v", err)
+	}
+
+	tip := cm.GetTip()
+	mtp, err := cm.GetMedianTimePast(tip.Height)
+	if err != nil {
+		t.Fatalf("GetMedianTimePast() error = %v", err)
+	}
+	if expected := branch[len(branch)-6].Timestamp; mtp != expected {
+		t.Errorf("GetMedianTimePast() = %d, expected %d", mtp, expected)
+	}
+
+	// A timestamp equal to median time past is too old
+	stale := mineHeader(t, tip, easyBits, mtp)
+	if err := cm.SetChainTip([]*BlockHeader{stale}); !errors.Is(err, ErrInvalidTimestamp) {
+		t.Errorf("SetChainTip() error = %v, want ErrInvalidTimestamp", err)
+	}
+
+	// Timestamps may run slightly behind the parent as long as they beat median time past
+	early := mineHeader(t, tip, easyBits, mtp+1)
+	if err := cm.SetChainTip([]*BlockHeader{early}); err != nil {
+		t.Errorf("SetChainTip() rejected a timestamp after median time past: %v", err)
+	}
+
+	future := mineHeader(t, cm.GetTip(), easyBits, uint32(time.Now().Add(3*time.Hour).Unix()))
+	if err := cm.SetChainTip([]*BlockHeader{future}); !errors.Is(err, ErrInvalidTimestamp) {
+		t.Errorf("SetChainTip() error = %v, want ErrInvalidTimestamp", err)
+	}
+
+	if _, err := cm.GetMedianTimePast(tip.Height + 100); !errors.Is(err, ErrHeaderNotFound) {
+		t.Errorf("GetMedianTimePast() beyond tip error = %v, want ErrHeaderNotFound", err)
+	}
+}
@@ -0,0 +1,211 @@
+package chaintracks
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+
+	"github.com/bsv-blockchain/go-sdk/chainhash"
+)
+
+// VerifyMode selects whether the header store is checked on startup and what happens when it is corrupt
+type VerifyMode int
+
+const (
+	// VerifyOff trusts the stored headers (default)
+	VerifyOff VerifyMode = iota
+	// VerifyFail refuses to start when the store is corrupt
+	VerifyFail
+	// VerifyTruncate discards everything from the first bad height and starts from the last good header
+	VerifyTruncate
+	// VerifyRedownload truncates like VerifyTruncate and then re-downloads the discarded headers from the bootstrap sources
+	VerifyRedownload
+)
+
+// String returns the mode name used by ParseVerifyMode
+func (m VerifyMode) String() string {
+	switch m {
+	case VerifyOff:
+		return "off"
+	case VerifyFail:
+		return "fail"
+	case VerifyTruncate:
+		return "truncate"
+	case VerifyRedownload:
+		return "redownload"
+	default:
+		return fmt.Sprintf("VerifyMode(%d)", int(m))
+	}
+}
+
+// ParseVerifyMode parses "off", "fail", "truncate" or "redownload"
+func ParseVerifyMode(s string) (VerifyMode, error) {
+	for _, mode := range []VerifyMode{VerifyOff, VerifyFail, VerifyTruncate, VerifyRedownload} {
+		if s == mode.String() {
+			return mode, nil
+		}
+	}
+	return VerifyOff, fmt.Errorf("invalid verify mode %q: expected off, fail, truncate or redownload", s)
+}
+
+// IntegrityError reports the first height at which the header store is inconsistent
+type IntegrityError struct {
+	Height   uint32
+	FileName string
+	Reason   string
+}
+
+// Error implements error
+func (e *IntegrityError) Error() string {
+	return fmt.Sprintf("%s: %s at height %d: %s", ErrCorruptStore, e.FileName, e.Height, e.Reason)
+}
+
+// Unwrap lets errors.Is match ErrCorruptStore
+func (e *IntegrityError) Unwrap() error {
+	return ErrCorruptStore
+}
+
+// VerifyStore checks every stored header and file entry: PrevHash linkage across headers and file boundaries,
+// FileHash when one is recorded, and LastHash, LastChainWork, PrevHash and PrevChainWork against recomputed values.
+// Corruption is returned as an *IntegrityError for the first bad height; other errors are I/O failures.
+func VerifyStore(store HeaderStore) error {
+	metadata, err := store.ReadMetadata()
+	if err != nil {
+		return fmt.Errorf("failed to read store metadata: %w", err)
+	}
+	if metadata == nil {
+		return nil
+	}
+
+	prevHash := chainhash.Hash{}
+	prevWork := new(big.Int)
+	prevFileName := ""
+	nextHeight := uint32(0)
+
+	for _, entry := range metadata.Files {
+		if entry.Count == 0 {
+			continue
+		}
+
+		if entry.FirstHeight != nextHeight {
+			return &IntegrityError{Height: nextHeight, FileName: entry.FileName, Reason: fmt.Sprintf("file starts at height %d", entry.FirstHeight)}
+		}
+
+		lastHeight := entry.FirstHeight + uint32(entry.Count) - 1
+		headers, err := store.LoadRange(entry.FirstHeight, lastHeight)
+		if errors.Is(err, ErrHeaderNotFound) {
+			return &IntegrityError{Height: entry.FirstHeight, FileName: entry.FileName, Reason: "headers missing"}
+		}
+		if err != nil {
+			return fmt.Errorf("failed to load %s: %w", entry.FileName, err)
+		}
+
+		fileBad := func(height uint32, format string, args ...any) error {
+			return &IntegrityError{Height: height, FileName: entry.FileName, Reason: fmt.Sprintf(format, args...)}
+		}
+
+		filePrevHash, filePrevWork := prevHash, prevWork
+		for _, header := range headers {
+			if header.Hash != header.Header.Hash() {
+				return fileBad(header.Height, "stored hash %s does not match header", header.Hash)
+			}
+			if header.PrevHash != prevHash {
+				// A header is only confirmed by the next one linking to it, so either side of a broken link may be corrupt
+				if header.Height == 0 {
+					return fileBad(0, "genesis prevHash %s is not zero", header.PrevHash)
+				}
+				badHeight, fileName := header.Height-1, entry.FileName
+				if header.Height == entry.FirstHeight {
+					fileName = prevFileName
+				}
+				return &IntegrityError{Height: badHeight, FileName: fileName, Reason: fmt.Sprintf("height %d prevHash %s does not link to %s", header.Height, header.PrevHash, prevHash)}
+			}
+
+			work := chainWorkAt(prevWork, header.Height, header.Bits)
+			if header.ChainWork != nil && header.ChainWork.Cmp(work) != 0 {
+				return fileBad(header.Height, "stored chainwork %s, recomputed %s", ChainWorkToHex(header.ChainWork), ChainWorkToHex(work))
+			}
+
+			prevHash, prevWork = header.Hash, work
+		}
+
+		if reason := checkFileEntry(&entry, headers, filePrevHash, filePrevWork, prevWork); reason != "" {
+			return fileBad(lastHeight, "%s", reason)
+		}
+
+		// Checked last because the header checks above pinpoint corruption; a file hash covers the whole file
+		if entry.FileHash != "" && entry.FileHash != computeFileHash(headers) {
+			return fileBad(entry.FirstHeight, "fileHash mismatch")
+		}
+
+		prevFileName = entry.FileName
+		nextHeight = lastHeight + 1
+	}
+
+	return nil
+}
+
+// checkFileEntry compares a file entry's summary fields with the verified headers it describes.
+// Metadata written by earlier versions described the header before the last one in PrevHash/PrevChainWork;
+// that form is accepted so existing stores do not fail verification.
+func checkFileEntry(entry *CDNFileEntry, headers []*BlockHeader, prevHash chainhash.Hash, prevWork, lastWork *big.Int) string {
+	last := headers[len(headers)-1]
+	if entry.LastHash != last.Hash {
+		return fmt.Sprintf("lastHash %s, last header is %s", entry.LastHash, last.Hash)
+	}
+	if !chainWorkMatches(entry.LastChainWork, lastWork) {
+		return fmt.Sprintf("lastChainWork %s, recomputed %s", entry.LastChainWork, ChainWorkToHex(lastWork))
+	}
+
+	if entry.PrevHash == prevHash && chainWorkMatches(entry.PrevChainWork, prevWork) {
+		return ""
+	}
+	if len(headers) > 1 && entry.PrevHash == last.PrevHash {
+		beforeLastWork := new(big.Int).Sub(lastWork, CalculateWork(last.Bits))
+		if chainWorkMatches(entry.PrevChainWork, beforeLastWork) {
+			return ""
+		}
+	}
+	return fmt.Sprintf("prevHash %s / prevChainWork %s do not match the chain", entry.PrevHash, entry.PrevChainWork)
+}
+
+// chainWorkMatches reports whether a stored chainwork hex string equals work
+func chainWorkMatches(stored string, work *big.Int) bool {
+	storedWork, err := ChainWorkFromHex(stored)
+	return err == nil && storedWork.Cmp(work) == 0
+}
+
+// verifyStore runs VerifyStore according to the configured mode, truncating the store if requested
+func (cm *ChainManager) verifyStore() error {
+	if cm.verifyMode == VerifyOff {
+		return nil
+	}
+
+	log.Printf("Verifying header store (mode=%s)", cm.verifyMode)
+	err := VerifyStore(cm.store)
+
+	var integrityErr *IntegrityError
+	if !errors.As(err, &integrityErr) {
+		if err == nil {
+			log.Printf("Header store verified")
+		}
+		return err
+	}
+
+	log.Printf("Header store verification failed: %v", err)
+	if cm.verifyMode == VerifyFail || integrityErr.Height == 0 {
+		return err
+	}
+
+	lastGood := integrityErr.Height - 1
+	if err := cm.store.TruncateAbove(lastGood); err != nil {
+		return fmt.Errorf("failed to truncate header store to height %d: %w", lastGood, err)
+	}
+	log.Printf("Truncated header store to last good height %d", lastGood)
+
+	if cm.verifyMode == VerifyRedownload {
+		log.Printf("Headers above height %d will be re-downloaded from the bootstrap sources", lastGood)
+	}
+	return nil
+}
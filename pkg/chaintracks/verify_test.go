@@ -0,0 +1,239 @@
+package chaintracks
+
+import (
+	"errors"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// newVerifyTestStore returns a FileStore holding genesis plus five headers
+func newVerifyTestStore(t *testing.T) (*FileStore, []*BlockHeader) {
+	t.Helper()
+
+	store := NewFileStore(t.TempDir(), "regtest")
+	genesis := mineHeader(t, nil, easyBits, 1700000000)
+	chain := append([]*BlockHeader{genesis}, extendChain(t, genesis, 5)...)
+	if err := store.PutBranch(chain); err != nil {
+		t.Fatalf("PutBranch() error = %v", err)
+	}
+	return store, chain
+}
+
+// corruptHeader flips a merkle root byte of the stored header at height
+func corruptHeader(t *testing.T, store *FileStore, height uint32) {
+	t.Helper()
+
+	f, err := os.OpenFile(filepath.Join(store.path, "regtestNet_0.headers"), os.O_RDWR, 0644)
+	if err != nil {
+		t.Fatalf("OpenFile() error = %v", err)
+	}
+	defer f.Close()
+
+	b := make([]byte, 1)
+	offset := int64(height)*headerSize + 40
+	if _, err := f.ReadAt(b, offset); err != nil {
+		t.Fatalf("ReadAt() error = %v", err)
+	}
+	b[0] ^= 0xff
+	if _, err := f.WriteAt(b, offset); err != nil {
+		t.Fatalf("WriteAt() error = %v", err)
+	}
+}
+
+// editMetadata rewrites the first file entry of a FileStore's metadata
+func editMetadata(t *testing.T, store *FileStore, edit func(entry *CDNFileEntry)) {
+	t.Helper()
+
+	metadata, err := store.ReadMetadata()
+	if err != nil {
+		t.Fatalf("ReadMetadata() error = %v", err)
+	}
+	edit(&metadata.Files[0])
+	if err := store.writeMetadata(metadata); err != nil {
+		t.Fatalf("writeMetadata() error = %v", err)
+	}
+}
+
+// requireIntegrityError asserts that err reports corruption at height
+func requireIntegrityError(t *testing.T, err error, height uint32) {
+	t.Helper()
+
+	var integrityErr *IntegrityError
+	if !errors.As(err, &integrityErr) {
+		t.Fatalf("VerifyStore() error = %v, want an IntegrityError", err)
+	}
+	if !errors.Is(err, ErrCorruptStore) {
+		t.Errorf("VerifyStore() error does not match ErrCorruptStore")
+	}
+	if integrityErr.Height != height {
+		t.Errorf("IntegrityError height = %d, want %d (%v)", integrityErr.Height, height, err)
+	}
+}
+
+func TestVerifyStoreAcceptsValidStores(t *testing.T) {
+	genesis := mineHeader(t, nil, easyBits, 1700000000)
+	chain := append([]*BlockHeader{genesis}, extendChain(t, genesis, 5)...)
+
+	for name, store := range testStores(t) {
+		t.Run(name, func(t *testing.T) {
+			if err := VerifyStore(store); err != nil {
+				t.Fatalf("VerifyStore() on empty store error = %v", err)
+			}
+			if err := store.PutBranch(chain); err != nil {
+				t.Fatalf("PutBranch() error = %v", err)
+			}
+			if err := VerifyStore(store); err != nil {
+				t.Errorf("VerifyStore() error = %v", err)
+			}
+		})
+	}
+}
+
+func TestVerifyStoreReportsFirstBadHeight(t *testing.T) {
+	tests := []struct {
+		name    string
+		corrupt func(t *testing.T, store *FileStore, chain []*BlockHeader)
+		height  uint32
+	}{
+		{
+			name: "corrupt header",
+			corrupt: func(t *testing.T, store *FileStore, _ []*BlockHeader) {
+				corruptHeader(t, store, 3)
+			},
+			height: 3,
+		},
+		{
+			name: "corrupt tip",
+			corrupt: func(t *testing.T, store *FileStore, _ []*BlockHeader) {
+				corruptHeader(t, store, 5)
+			},
+			height: 5,
+		},
+		{
+			name: "file hash",
+			corrupt: func(t *testing.T, store *FileStore, _ []*BlockHeader) {
+				editMetadata(t, store, func(entry *CDNFileEntry) {
+					entry.FileHash = computeFileHash(nil)
+				})
+			},
+			height: 0,
+		},
+		{
+			name: "last chainwork",
+			corrupt: func(t *testing.T, store *FileStore, _ []*BlockHeader) {
+				editMetadata(t, store, func(entry *CDNFileEntry) {
+					entry.LastChainWork = ChainWorkToHex(big.NewInt(1))
+				})
+			},
+			height: 5,
+		},
+		{
+			name: "prev hash",
+			corrupt: func(t *testing.T, store *FileStore, chain []*BlockHeader) {
+				editMetadata(t, store, func(entry *CDNFileEntry) {
+					entry.PrevHash = chain[1].Hash
+				})
+			},
+			height: 5,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store, chain := newVerifyTestStore(t)
+			tt.corrupt(t, store, chain)
+			requireIntegrityError(t, VerifyStore(store), tt.height)
+		})
+	}
+}
+
+func TestVerifyStoreChecksFileHash(t *testing.T) {
+	store, chain := newVerifyTestStore(t)
+
+	// The open file is hashed when it is first served
+	metadata, err := store.CDNMetadata()
+	if err != nil {
+		t.Fatalf("CDNMetadata() error = %v", err)
+	}
+	if got, want := metadata.Files[0].FileHash, computeFileHash(chain); got != want {
+		t.Fatalf("Stored fileHash = %q, want %q", got, want)
+	}
+	if err := VerifyStore(store); err != nil {
+		t.Fatalf("VerifyStore() with matching fileHash error = %v", err)
+	}
+
+	editMetadata(t, store, func(entry *CDNFileEntry) {
+		entry.FileHash = computeFileHash(chain[:5])
+	})
+	requireIntegrityError(t, VerifyStore(store), 0)
+}
+
+func TestVerifyStoreAcceptsLegacyMetadata(t *testing.T) {
+	store, chain := newVerifyTestStore(t)
+
+	// Earlier versions counted no genesis work and described the header before the tip in the prev fields
+	genesisWork := chain[0].ChainWork
+	editMetadata(t, store, func(entry *CDNFileEntry) {
+		entry.LastChainWork = ChainWorkToHex(new(big.Int).Sub(chain[5].ChainWork, genesisWork))
+		entry.PrevChainWork = ChainWorkToHex(new(big.Int).Sub(chain[4].ChainWork, genesisWork))
+		entry.PrevHash = chain[4].Hash
+	})
+
+	if err := VerifyStore(store); err != nil {
+		t.Errorf("VerifyStore() error = %v", err)
+	}
+}
+
+func TestNewChainManagerVerifyModes(t *testing.T) {
+	// Recover already repairs broken links near the tip, so these use metadata that only verification checks
+	t.Run("fail", func(t *testing.T) {
+		store, _ := newVerifyTestStore(t)
+		editMetadata(t, store, func(entry *CDNFileEntry) {
+			entry.FileHash = computeFileHash(nil)
+		})
+
+		_, err := NewChainManager("regtest", t.TempDir(), WithHeaderStore(store), WithVerify(VerifyFail))
+		if !errors.Is(err, ErrCorruptStore) {
+			t.Errorf("NewChainManager() error = %v, want ErrCorruptStore", err)
+		}
+	})
+
+	t.Run("truncate", func(t *testing.T) {
+		store, chain := newVerifyTestStore(t)
+		editMetadata(t, store, func(entry *CDNFileEntry) {
+			entry.LastChainWork = ChainWorkToHex(big.NewInt(1))
+		})
+
+		cm, err := NewChainManager("regtest", t.TempDir(), WithHeaderStore(store), WithVerify(VerifyTruncate))
+		if err != nil {
+			t.Fatalf("NewChainManager() error = %v", err)
+		}
+		if tip := cm.GetTip(); tip == nil || tip.Hash != chain[4].Hash {
+			t.Errorf("Tip after truncate = %v, want %s", tip, chain[4].Hash)
+		}
+		if err := VerifyStore(store); err != nil {
+			t.Errorf("VerifyStore() after truncate error = %v", err)
+		}
+	})
+
+	t.Run("redownload without bootstrap URL", func(t *testing.T) {
+		store, _ := newVerifyTestStore(t)
+		if _, err := NewChainManager("regtest", t.TempDir(), WithHeaderStore(store), WithVerify(VerifyRedownload)); err == nil {
+			t.Error("NewChainManager() succeeded without a bootstrap URL")
+		}
+	})
+}
+
+func TestParseVerifyMode(t *testing.T) {
+	for _, mode := range []VerifyMode{VerifyOff, VerifyFail, VerifyTruncate, VerifyRedownload} {
+		parsed, err := ParseVerifyMode(mode.String())
+		if err != nil || parsed != mode {
+			t.Errorf("ParseVerifyMode(%q) = %v, %v, want %v", mode.String(), parsed, err, mode)
+		}
+	}
+	if _, err := ParseVerifyMode("repair"); err == nil {
+		t.Error("ParseVerifyMode(\"repair\") succeeded")
+	}
+}
@@ -0,0 +1,264 @@
+package chaintracks
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/bsv-blockchain/go-sdk/block"
+	"github.com/bsv-blockchain/go-sdk/chainhash"
+)
+
+const (
+	// wireProtocolVersion is the protocol version sent in our version message (BSV 1.x)
+	wireProtocolVersion = 70016
+	// wireMinSendHeadersVersion is the first protocol version that understands sendheaders
+	wireMinSendHeadersVersion = 70012
+	// wireMinPeerVersion is the oldest peer protocol version that supports getheaders
+	wireMinPeerVersion = 31800
+	// wireUserAgent identifies us in the version message
+	wireUserAgent = "/go-chaintracks:1.0/"
+
+	wireHeaderSize        = 24                              // Magic, command, payload length and checksum
+	wireCommandSize       = 12                              // NUL-padded ASCII command
+	wireMaxPayload        = 32 * 1024 * 1024                // Nothing we request comes close
+	wireMaxHeadersPerMsg  = 2000                            // Most headers a peer returns for one getheaders
+	wireMaxInvPerMsg      = 50000                           // Most inventory vectors in one inv
+	wireMaxLocatorHashes  = 500                             // Most hashes accepted in a block locator
+	wireInvTypeBlock      = 2                               // Inventory type of a block
+	wireInvVectorSize     = 4 + chainhash.HashSize          // Inventory type and hash
+	wireHeadersEntrySize  = headerSize + 1                  // Each header is followed by a zero transaction count
+	wireVersionMinPayload = 4 + 8 + 8 + 26 + 26 + 8 + 1 + 4 // Version fields up to the start height with an empty user agent
+)
+
+// Wire protocol commands
+const (
+	wireCmdVersion     = "version"
+	wireCmdVerAck      = "verack"
+	wireCmdPing        = "ping"
+	wireCmdPong        = "pong"
+	wireCmdSendHeaders = "sendheaders"
+	wireCmdGetHeaders  = "getheaders"
+	wireCmdHeaders     = "headers"
+	wireCmdInv         = "inv"
+)
+
+// ErrWireProtocol is returned when a peer sends a malformed message
+var ErrWireProtocol = errors.New("wire protocol violation")
+
+// wireChecksum returns the first four bytes of the double SHA-256 of a payload
+func wireChecksum(payload []byte) [4]byte {
+	first := sha256.Sum256(payload)
+	second := sha256.Sum256(first[:])
+	var checksum [4]byte
+	copy(checksum[:], second[:4])
+	return checksum
+}
+
+// writeWireMessage frames and writes a single message
+func writeWireMessage(w io.Writer, magic uint32, command string, payload []byte) error {
+	if len(command) > wireCommandSize {
+		return fmt.Errorf("command %q is too long", command)
+	}
+
+	msg := make([]byte, wireHeaderSize+len(payload))
+	binary.LittleEndian.PutUint32(msg[0:4], magic)
+	copy(msg[4:4+wireCommandSize], command)
+	binary.LittleEndian.PutUint32(msg[16:20], uint32(len(payload)))
+	checksum := wireChecksum(payload)
+	copy(msg[20:24], checksum[:])
+	copy(msg[wireHeaderSize:], payload)
+
+	_, err := w.Write(msg)
+	return err
+}
+
+// readWireMessage reads a single message, checking its magic, size and checksum
+func readWireMessage(r io.Reader, magic uint32) (string, []byte, error) {
+	var header [wireHeaderSize]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return "", nil, err
+	}
+
+	if got := binary.LittleEndian.Uint32(header[0:4]); got != magic {
+		return "", nil, fmt.Errorf("%w: network magic %08x, expected %08x", ErrWireProtocol, got, magic)
+	}
+	command := string(bytes.TrimRight(header[4:4+wireCommandSize], "\x00"))
+	length := binary.LittleEndian.Uint32(header[16:20])
+	if length > wireMaxPayload {
+		return "", nil, fmt.Errorf("%w: %s payload of %d bytes", ErrWireProtocol, command, length)
+	}
+
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return "", nil, err
+	}
+	if checksum := wireChecksum(payload); !bytes.Equal(checksum[:], header[20:24]) {
+		return "", nil, fmt.Errorf("%w: %s checksum mismatch", ErrWireProtocol, command)
+	}
+
+	return command, payload, nil
+}
+
+// putVarInt appends a Bitcoin CompactSize integer
+func putVarInt(buf *bytes.Buffer, n uint64) {
+	var b [9]byte
+	switch {
+	case n < 0xfd:
+		buf.WriteByte(byte(n))
+	case n <= 0xffff:
+		b[0] = 0xfd
+		binary.LittleEndian.PutUint16(b[1:], uint16(n))
+		buf.Write(b[:3])
+	case n <= 0xffffffff:
+		b[0] = 0xfe
+		binary.LittleEndian.PutUint32(b[1:], uint32(n))
+		buf.Write(b[:5])
+	default:
+		b[0] = 0xff
+		binary.LittleEndian.PutUint64(b[1:], n)
+		buf.Write(b[:9])
+	}
+}
+
+// readVarInt reads a Bitcoin CompactSize integer
+func readVarInt(r *bytes.Reader) (uint64, error) {
+	prefix, err := r.ReadByte()
+	if err != nil {
+		return 0, err
+	}
+
+	var size int
+	switch prefix {
+	case 0xfd:
+		size = 2
+	case 0xfe:
+		size = 4
+	case 0xff:
+		size = 8
+	default:
+		return uint64(prefix), nil
+	}
+
+	var b [8]byte
+	if _, err := io.ReadFull(r, b[:size]); err != nil {
+		return 0, err
+	}
+	return binary.LittleEndian.Uint64(b[:]), nil
+}
+
+// wireVersion holds the fields of a version message we use
+type wireVersion struct {
+	ProtocolVersion int32
+	Services        uint64
+	Nonce           uint64
+	UserAgent       string
+	StartHeight     int32
+}
+
+// encodeVersion builds a version message payload. Addresses are left zero, which peers accept,
+// and relay is false so the peer does not announce transactions.
+func encodeVersion(v *wireVersion, now time.Time) []byte {
+	var buf bytes.Buffer
+	binary.Write(&buf, binary.LittleEndian, v.ProtocolVersion)
+	binary.Write(&buf, binary.LittleEndian, v.Services)
+	binary.Write(&buf, binary.LittleEndian, now.Unix())
+	buf.Write(make([]byte, 26)) // addr_recv: services, IPv6 address, port
+	buf.Write(make([]byte, 26)) // addr_from
+	binary.Write(&buf, binary.LittleEndian, v.Nonce)
+	putVarInt(&buf, uint64(len(v.UserAgent)))
+	buf.WriteString(v.UserAgent)
+	binary.Write(&buf, binary.LittleEndian, v.StartHeight)
+	buf.WriteByte(0) // relay
+	return buf.Bytes()
+}
+
+// decodeVersion parses the fields of a version message we use
+func decodeVersion(payload []byte) (*wireVersion, error) {
+	if len(payload) < wireVersionMinPayload {
+		return nil, fmt.Errorf("%w: version message of %d bytes", ErrWireProtocol, len(payload))
+	}
+
+	v := &wireVersion{
+		ProtocolVersion: int32(binary.LittleEndian.Uint32(payload[0:4])),
+		Services:        binary.LittleEndian.Uint64(payload[4:12]),
+		Nonce:           binary.LittleEndian.Uint64(payload[72:80]),
+	}
+
+	r := bytes.NewReader(payload[80:])
+	length, err := readVarInt(r)
+	if err != nil || length > uint64(r.Len()) {
+		return nil, fmt.Errorf("%w: version user agent", ErrWireProtocol)
+	}
+	userAgent := make([]byte, length)
+	r.Read(userAgent)
+	v.UserAgent = string(userAgent)
+
+	if err := binary.Read(r, binary.LittleEndian, &v.StartHeight); err != nil {
+		return nil, fmt.Errorf("%w: version start height", ErrWireProtocol)
+	}
+	return v, nil
+}
+
+// encodeGetHeaders builds a getheaders payload asking for the headers after the first locator hash the peer knows.
+// A zero stop hash asks for as many as the peer will send.
+func encodeGetHeaders(locator []chainhash.Hash, stop chainhash.Hash) []byte {
+	var buf bytes.Buffer
+	binary.Write(&buf, binary.LittleEndian, uint32(wireProtocolVersion))
+	putVarInt(&buf, uint64(len(locator)))
+	for _, hash := range locator {
+		buf.Write(hash[:])
+	}
+	buf.Write(stop[:])
+	return buf.Bytes()
+}
+
+// decodeHeaders parses a headers payload. Heights and chainwork are left for the caller to fill in.
+func decodeHeaders(payload []byte) ([]*BlockHeader, error) {
+	r := bytes.NewReader(payload)
+	count, err := readVarInt(r)
+	if err != nil || count > wireMaxHeadersPerMsg || uint64(r.Len()) != count*wireHeadersEntrySize {
+		return nil, fmt.Errorf("%w: headers message of %d bytes", ErrWireProtocol, len(payload))
+	}
+
+	headers := make([]*BlockHeader, count)
+	entry := make([]byte, wireHeadersEntrySize)
+	for i := range headers {
+		r.Read(entry)
+		if entry[headerSize] != 0 {
+			return nil, fmt.Errorf("%w: header %d has transactions", ErrWireProtocol, i)
+		}
+		header, err := block.NewHeaderFromBytes(entry[:headerSize])
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse header %d: %w", i, err)
+		}
+		headers[i] = &BlockHeader{Header: header, Hash: header.Hash()}
+	}
+	return headers, nil
+}
+
+// decodeBlockInv returns the block hashes announced in an inv payload, ignoring other inventory types
+func decodeBlockInv(payload []byte) ([]chainhash.Hash, error) {
+	r := bytes.NewReader(payload)
+	count, err := readVarInt(r)
+	if err != nil || count > wireMaxInvPerMsg || uint64(r.Len()) != count*wireInvVectorSize {
+		return nil, fmt.Errorf("%w: inv message of %d bytes", ErrWireProtocol, len(payload))
+	}
+
+	var hashes []chainhash.Hash
+	entry := make([]byte, wireInvVectorSize)
+	for i := uint64(0); i < count; i++ {
+		r.Read(entry)
+		if binary.LittleEndian.Uint32(entry[:4]) != wireInvTypeBlock {
+			continue
+		}
+		var hash chainhash.Hash
+		copy(hash[:], entry[4:])
+		hashes = append(hashes, hash)
+	}
+	return hashes, nil
+}
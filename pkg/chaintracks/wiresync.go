@@ -0,0 +1,297 @@
+package chaintracks
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/rand/v2"
+	"net"
+	"time"
+
+	"github.com/bsv-blockchain/go-sdk/chainhash"
+)
+
+const (
+	// nodeHandshakeTimeout bounds the version/verack exchange
+	nodeHandshakeTimeout = 30 * time.Second
+	// nodeWriteTimeout bounds sending a single message
+	nodeWriteTimeout = 30 * time.Second
+	// nodeIdleTimeout drops a peer that sends nothing, not even pings, for this long
+	nodeIdleTimeout = 20 * time.Minute
+	// nodeReconnectDelay is how long FollowNode waits before reconnecting to a peer
+	nodeReconnectDelay = 30 * time.Second
+)
+
+// nodePeer is a connection to an SV Node speaking the legacy Bitcoin P2P protocol
+type nodePeer struct {
+	cm      *ChainManager
+	conn    net.Conn
+	addr    string
+	magic   uint32
+	version *wireVersion
+	synced  bool // Set once the peer has no more headers for our locator
+}
+
+// SyncFromNode connects to an SV Node over the legacy Bitcoin P2P protocol and follows its chain.
+// After the version/verack handshake it requests headers with getheaders and a block locator until caught up,
+// then imports the headers the node announces with headers or inv messages. addr is host:port; the network's
+// default port is used if none is given. It returns when ctx is cancelled or the connection fails.
+func (cm *ChainManager) SyncFromNode(ctx context.Context, addr string) error {
+	if cm.params == nil || cm.params.WireMagic == 0 {
+		return fmt.Errorf("no SV Node wire protocol parameters for network %s", cm.network)
+	}
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		addr = net.JoinHostPort(addr, cm.params.DefaultPort)
+	}
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to connect to node %s: %w", addr, err)
+	}
+	defer conn.Close()
+
+	// Closing the connection unblocks any pending read when ctx is cancelled
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	peer := &nodePeer{cm: cm, conn: conn, addr: addr, magic: cm.params.WireMagic}
+	err = peer.handshake()
+	if err == nil {
+		err = peer.run()
+	}
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return ctxErr
+	}
+	return err
+}
+
+// FollowNode runs SyncFromNode until ctx is cancelled, reconnecting after nodeReconnectDelay when the connection fails
+func (cm *ChainManager) FollowNode(ctx context.Context, addr string) {
+	for {
+		err := cm.SyncFromNode(ctx, addr)
+		if ctx.Err() != nil {
+			return
+		}
+		log.Printf("Node %s disconnected: %v (reconnecting in %v)", addr, err, nodeReconnectDelay)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(nodeReconnectDelay):
+		}
+	}
+}
+
+// send writes a message to the peer
+func (p *nodePeer) send(command string, payload []byte) error {
+	p.conn.SetWriteDeadline(time.Now().Add(nodeWriteTimeout))
+	if err := writeWireMessage(p.conn, p.magic, command, payload); err != nil {
+		return fmt.Errorf("failed to send %s: %w", command, err)
+	}
+	return nil
+}
+
+// handshake exchanges version and verack messages
+func (p *nodePeer) handshake() error {
+	p.conn.SetDeadline(time.Now().Add(nodeHandshakeTimeout))
+	defer p.conn.SetDeadline(time.Time{})
+
+	nonce := rand.Uint64()
+	ours := &wireVersion{
+		ProtocolVersion: wireProtocolVersion,
+		Nonce:           nonce,
+		UserAgent:       wireUserAgent,
+		StartHeight:     int32(p.cm.GetHeight()),
+	}
+	if err := p.send(wireCmdVersion, encodeVersion(ours, time.Now())); err != nil {
+		return err
+	}
+
+	var gotVerAck bool
+	for p.version == nil || !gotVerAck {
+		command, payload, err := readWireMessage(p.conn, p.magic)
+		if err != nil {
+			return fmt.Errorf("handshake with %s failed: %w", p.addr, err)
+		}
+
+		switch command {
+		case wireCmdVersion:
+			version, err := decodeVersion(payload)
+			if err != nil {
+				return err
+			}
+			if version.Nonce == nonce {
+				return fmt.Errorf("connected to ourselves at %s", p.addr)
+			}
+			if version.ProtocolVersion < wireMinPeerVersion {
+				return fmt.Errorf("node %s speaks protocol %d, need at least %d", p.addr, version.ProtocolVersion, wireMinPeerVersion)
+			}
+			p.version = version
+			if version.StartHeight > 0 {
+				p.cm.noteUpstreamHeight(uint32(version.StartHeight))
+			}
+			if err := p.send(wireCmdVerAck, nil); err != nil {
+				return err
+			}
+		case wireCmdVerAck:
+			gotVerAck = true
+		}
+	}
+
+	log.Printf("Connected to node %s (%s, protocol %d, height %d)", p.addr, p.version.UserAgent, p.version.ProtocolVersion, p.version.StartHeight)
+	return nil
+}
+
+// run requests headers until caught up and then handles announcements until the connection fails
+func (p *nodePeer) run() error {
+	// Ask for new blocks to be announced with headers rather than inv
+	if p.version.ProtocolVersion >= wireMinSendHeadersVersion {
+		if err := p.send(wireCmdSendHeaders, nil); err != nil {
+			return err
+		}
+	}
+	if err := p.requestHeaders(); err != nil {
+		return err
+	}
+
+	for {
+		p.conn.SetReadDeadline(time.Now().Add(nodeIdleTimeout))
+		command, payload, err := readWireMessage(p.conn, p.magic)
+		if err != nil {
+			return err
+		}
+
+		switch command {
+		case wireCmdPing:
+			if err := p.send(wireCmdPong, payload); err != nil {
+				return err
+			}
+
+		case wireCmdHeaders:
+			headers, err := decodeHeaders(payload)
+			if err != nil {
+				return err
+			}
+			if err := p.handleHeaders(headers); err != nil {
+				return err
+			}
+
+		case wireCmdInv:
+			hashes, err := decodeBlockInv(payload)
+			if err != nil {
+				return err
+			}
+			for _, hash := range hashes {
+				if _, err := p.cm.GetHeaderByHash(&hash); err != nil {
+					if err := p.requestHeaders(); err != nil {
+						return err
+					}
+					break
+				}
+			}
+		}
+	}
+}
+
+// requestHeaders sends getheaders with a locator for the local main chain
+func (p *nodePeer) requestHeaders() error {
+	return p.send(wireCmdGetHeaders, encodeGetHeaders(p.cm.blockLocator(), chainhash.Hash{}))
+}
+
+// requestHeadersAfter sends getheaders with a locator that starts at last, a header the node sent
+func (p *nodePeer) requestHeadersAfter(last *BlockHeader) error {
+	locator := append([]chainhash.Hash{last.Hash}, p.cm.blockLocator()...)
+	return p.send(wireCmdGetHeaders, encodeGetHeaders(locator, chainhash.Hash{}))
+}
+
+// handleHeaders imports a headers message. Headers that do not connect to a known header are an announcement
+// of a block whose ancestors we are missing, so the gap is requested with a fresh locator.
+func (p *nodePeer) handleHeaders(headers []*BlockHeader) error {
+	if len(headers) == 0 {
+		p.markSynced()
+		return nil
+	}
+
+	parent, err := p.cm.GetHeaderByHash(&headers[0].PrevHash)
+	if err != nil {
+		return p.requestHeaders()
+	}
+	if err := linkBatch(parent, headers); err != nil {
+		return fmt.Errorf("%w: %v", ErrWireProtocol, err)
+	}
+
+	// A full message means the node has more to send
+	more := len(headers) == wireMaxHeadersPerMsg
+	last := headers[len(headers)-1]
+
+	// Overlap with what we already have is normal after a reorg or a stale locator
+	for len(headers) > 0 {
+		if _, err := p.cm.GetHeaderByHash(&headers[0].Hash); err != nil {
+			break
+		}
+		headers = headers[1:]
+	}
+	if len(headers) > 0 {
+		if err := p.cm.importBranch(headers, p.addr); err != nil {
+			return fmt.Errorf("node %s sent invalid headers: %w", p.addr, err)
+		}
+		if !p.synced {
+			log.Printf("Received headers from node %s up to height %d", p.addr, headers[len(headers)-1].Height)
+		}
+	}
+
+	// Continue after the last header sent, even if it did not move the tip: the headers may all be known or a
+	// side chain, and a main chain locator would be answered with the same message again
+	if more {
+		return p.requestHeadersAfter(last)
+	}
+	p.markSynced()
+	return nil
+}
+
+// markSynced logs the first time the peer has nothing more to send
+func (p *nodePeer) markSynced() {
+	if !p.synced {
+		p.synced = true
+		log.Printf("Caught up with node %s at height %d", p.addr, p.cm.GetHeight())
+	}
+}
+
+// blockLocator returns main chain hashes at blockLocatorHeights of the tip, newest first
+func (cm *ChainManager) blockLocator() []chainhash.Hash {
+	tip := cm.GetTip()
+	if tip == nil {
+		return nil
+	}
+
+	heights := blockLocatorHeights(tip.Height)
+	locator := make([]chainhash.Hash, 0, len(heights))
+	for _, height := range heights {
+		if header, err := cm.GetHeaderByHeight(height); err == nil {
+			locator = append(locator, header.Hash)
+		}
+	}
+	return locator
+}
+
+// importBranch validates headers that extend a known header and makes them the main chain if they have more
+// work than the current tip; otherwise they are kept as a side chain
+func (cm *ChainManager) importBranch(branch []*BlockHeader, source string) error {
+	if err := cm.validateHeaders(branch, source); err != nil {
+		return err
+	}
+
+	last := branch[len(branch)-1]
+	if tip := cm.GetTip(); tip == nil || last.ChainWork.Cmp(tip.ChainWork) > 0 {
+		return cm.setChainTip(branch)
+	}
+
+	for _, header := range branch {
+		if err := cm.AddHeader(header); err != nil {
+			return err
+		}
+	}
+	log.Printf("Headers from %s added as side chain up to height %d", source, last.Height)
+	return nil
+}
@@ -0,0 +1,271 @@
+package chaintracks
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/bsv-blockchain/go-sdk/chainhash"
+)
+
+// decodeGetHeaders parses a getheaders payload
+func decodeGetHeaders(payload []byte) ([]chainhash.Hash, chainhash.Hash, error) {
+	r := bytes.NewReader(payload)
+	var version uint32
+	if err := binary.Read(r, binary.LittleEndian, &version); err != nil {
+		return nil, chainhash.Hash{}, fmt.Errorf("%w: getheaders version", ErrWireProtocol)
+	}
+
+	count, err := readVarInt(r)
+	if err != nil || count > wireMaxLocatorHashes || uint64(r.Len()) != (count+1)*chainhash.HashSize {
+		return nil, chainhash.Hash{}, fmt.Errorf("%w: getheaders locator", ErrWireProtocol)
+	}
+
+	locator := make([]chainhash.Hash, count)
+	for i := range locator {
+		r.Read(locator[i][:])
+	}
+	var stop chainhash.Hash
+	r.Read(stop[:])
+	return locator, stop, nil
+}
+
+// encodeHeaders builds a headers payload
+func encodeHeaders(headers []*BlockHeader) []byte {
+	var buf bytes.Buffer
+	putVarInt(&buf, uint64(len(headers)))
+	for _, header := range headers {
+		buf.Write(header.Header.Bytes())
+		buf.WriteByte(0)
+	}
+	return buf.Bytes()
+}
+
+// encodeInv builds an inv payload announcing blocks
+func encodeInv(hashes []chainhash.Hash) []byte {
+	var buf bytes.Buffer
+	putVarInt(&buf, uint64(len(hashes)))
+	for _, hash := range hashes {
+		binary.Write(&buf, binary.LittleEndian, uint32(wireInvTypeBlock))
+		buf.Write(hash[:])
+	}
+	return buf.Bytes()
+}
+
+// startNodeSync runs SyncFromNode in the background until the test ends
+func startNodeSync(t *testing.T, cm *ChainManager, addr string) {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- cm.SyncFromNode(ctx, addr) }()
+
+	t.Cleanup(func() {
+		cancel()
+		if err := <-done; !errors.Is(err, context.Canceled) {
+			t.Errorf("SyncFromNode() error = %v, want context.Canceled", err)
+		}
+	})
+}
+
+// waitForTip polls until the main chain tip is want
+func waitForTip(t *testing.T, cm *ChainManager, want *BlockHeader) {
+	t.Helper()
+
+	deadline := time.Now().Add(10 * time.Second)
+	for time.Now().Before(deadline) {
+		if tip := cm.GetTip(); tip.Hash == want.Hash {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	tip := cm.GetTip()
+	t.Fatalf("Tip = height %d %s, want height %d %s", tip.Height, tip.Hash, want.Height, want.Hash)
+}
+
+func TestSyncFromNode(t *testing.T) {
+	cm := newTestChainManager(t)
+	genesis := cm.GetTip()
+	chain := append([]*BlockHeader{genesis}, extendChain(t, genesis, 4500)...)
+	node := newFakeUpstream(t, chain)
+
+	startNodeSync(t, cm, node.wireAddr())
+	waitForTip(t, cm, chain[4500])
+	if tip := cm.GetTip(); tip.ChainWork.Cmp(chain[4500].ChainWork) != 0 {
+		t.Errorf("Tip chainwork = %s, want %s", tip.ChainWork, chain[4500].ChainWork)
+	}
+
+	// A new block announced with its header
+	next := extendChain(t, chain[4500], 1)
+	node.extend(next, false)
+	waitForTip(t, cm, next[0])
+
+	// Several blocks announced with an inv for the last one
+	more := extendChain(t, next[0], 3)
+	node.extend(more, true)
+	waitForTip(t, cm, more[2])
+
+	// A header whose parents we have not seen fills the gap with getheaders
+	gap := extendChain(t, more[2], 5)
+	node.extend(gap, false)
+	waitForTip(t, cm, gap[4])
+}
+
+func TestSyncFromNodeReorgs(t *testing.T) {
+	cm := newTestChainManager(t)
+	genesis := cm.GetTip()
+	local := extendChain(t, genesis, 30)
+	if err := cm.SetChainTip(local); err != nil {
+		t.Fatalf("SetChainTip() error = %v", err)
+	}
+
+	// The node's chain forks after height 20 and has more work
+	fork := mineHeader(t, local[19], easyBits, local[19].Timestamp+300)
+	remote := append([]*BlockHeader{genesis}, local[:20]...)
+	remote = append(append(remote, fork), extendChain(t, fork, 15)...)
+	node := newFakeUpstream(t, remote)
+
+	startNodeSync(t, cm, node.wireAddr())
+	waitForTip(t, cm, remote[len(remote)-1])
+
+	if header, err := cm.GetHeaderByHeight(21); err != nil || header.Hash != fork.Hash {
+		t.Errorf("Header at height 21 = %v, want the fork header", header)
+	}
+}
+
+func TestSyncFromNodeKeepsChainWithMoreWork(t *testing.T) {
+	cm := newTestChainManager(t)
+	genesis := cm.GetTip()
+	local := extendChain(t, genesis, 30)
+	if err := cm.SetChainTip(local); err != nil {
+		t.Fatalf("SetChainTip() error = %v", err)
+	}
+
+	fork := mineHeader(t, local[19], easyBits, local[19].Timestamp+300)
+	remote := append([]*BlockHeader{genesis}, local[:20]...)
+	remote = append(append(remote, fork), extendChain(t, fork, 4)...)
+	node := newFakeUpstream(t, remote)
+
+	startNodeSync(t, cm, node.wireAddr())
+
+	// The shorter branch is kept as a side chain
+	deadline := time.Now().Add(10 * time.Second)
+	last := remote[len(remote)-1]
+	for {
+		if _, err := cm.GetHeaderByHash(&last.Hash); err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Side chain from the node was not stored")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if tip := cm.GetTip(); tip.Hash != local[29].Hash {
+		t.Errorf("Tip = height %d %s, want the local tip %s", tip.Height, tip.Hash, local[29].Hash)
+	}
+}
+
+func TestSyncFromNodeBehindByMoreThanOneMessage(t *testing.T) {
+	cm := newTestChainManager(t)
+	genesis := cm.GetTip()
+	local := append([]*BlockHeader{genesis}, extendChain(t, genesis, 4500)...)
+	if err := cm.SetChainTip(local[1:]); err != nil {
+		t.Fatalf("SetChainTip() error = %v", err)
+	}
+
+	// The node is at height 2440, so the deepest locator hash it knows is 397 and it answers with a full
+	// message of headers we already have
+	node := newFakeUpstream(t, local[:2441])
+	startNodeSync(t, cm, node.wireAddr())
+
+	deadline := time.Now().Add(10 * time.Second)
+	for node.getHeaders.Load() < 2 {
+		if time.Now().After(deadline) {
+			t.Fatalf("Node received %d getheaders, want 2", node.getHeaders.Load())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// The second request continues after the last header sent, reaching the node's tip
+	time.Sleep(200 * time.Millisecond)
+	if n := node.getHeaders.Load(); n != 2 {
+		t.Errorf("Node received %d getheaders, want 2", n)
+	}
+	if tip := cm.GetTip(); tip.Hash != local[4500].Hash {
+		t.Errorf("Tip = height %d %s, want the local tip %s", tip.Height, tip.Hash, local[4500].Hash)
+	}
+}
+
+func TestSyncFromNodeRequiresWireParams(t *testing.T) {
+	cm := newTestChainManager(t)
+	params := testParams
+	params.WireMagic = 0
+	cm.params = &params
+
+	if err := cm.SyncFromNode(context.Background(), "127.0.0.1:1"); err == nil {
+		t.Error("SyncFromNode() succeeded without wire protocol parameters")
+	}
+}
+
+func TestWireMessage(t *testing.T) {
+	var buf bytes.Buffer
+	if err := writeWireMessage(&buf, testParams.WireMagic, wireCmdPing, []byte{1, 2, 3, 4, 5, 6, 7, 8}); err != nil {
+		t.Fatalf("writeWireMessage() error = %v", err)
+	}
+	msg := buf.Bytes()
+
+	command, payload, err := readWireMessage(bytes.NewReader(msg), testParams.WireMagic)
+	if err != nil || command != wireCmdPing || !bytes.Equal(payload, []byte{1, 2, 3, 4, 5, 6, 7, 8}) {
+		t.Fatalf("readWireMessage() = %q, %x, %v", command, payload, err)
+	}
+
+	if _, _, err := readWireMessage(bytes.NewReader(msg), 0xe8f3e1e3); !errors.Is(err, ErrWireProtocol) {
+		t.Errorf("readWireMessage() with the wrong magic error = %v, want ErrWireProtocol", err)
+	}
+
+	corrupt := append([]byte(nil), msg...)
+	corrupt[len(corrupt)-1] ^= 0xff
+	if _, _, err := readWireMessage(bytes.NewReader(corrupt), testParams.WireMagic); !errors.Is(err, ErrWireProtocol) {
+		t.Errorf("readWireMessage() with a bad checksum error = %v, want ErrWireProtocol", err)
+	}
+}
+
+func TestWireVersion(t *testing.T) {
+	want := &wireVersion{ProtocolVersion: wireProtocolVersion, Services: 1, Nonce: 0x0123456789abcdef, UserAgent: wireUserAgent, StartHeight: 800000}
+
+	got, err := decodeVersion(encodeVersion(want, time.Now()))
+	if err != nil {
+		t.Fatalf("decodeVersion() error = %v", err)
+	}
+	if *got != *want {
+		t.Errorf("decodeVersion() = %+v, want %+v", got, want)
+	}
+
+	if _, err := decodeVersion(make([]byte, 20)); !errors.Is(err, ErrWireProtocol) {
+		t.Errorf("decodeVersion() of a short payload error = %v, want ErrWireProtocol", err)
+	}
+}
+
+func TestDecodeHeaders(t *testing.T) {
+	genesis := mineHeader(t, nil, easyBits, 1700000000)
+	chain := append([]*BlockHeader{genesis}, extendChain(t, genesis, 2)...)
+
+	headers, err := decodeHeaders(encodeHeaders(chain))
+	if err != nil {
+		t.Fatalf("decodeHeaders() error = %v", err)
+	}
+	if len(headers) != 3 || headers[2].Hash != chain[2].Hash {
+		t.Fatalf("decodeHeaders() returned %d headers", len(headers))
+	}
+
+	// Headers messages carry a zero transaction count after each header
+	payload := encodeHeaders(chain)
+	payload[1+headerSize] = 1
+	if _, err := decodeHeaders(payload); !errors.Is(err, ErrWireProtocol) {
+		t.Errorf("decodeHeaders() with transactions error = %v, want ErrWireProtocol", err)
+	}
+}